@@ -1,21 +1,137 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/yugo-ibuki/docrawl/internal/crawler"
-	"github.com/yugo-ibuki/docrawl/internal/pdf"
+	"github.com/yugo-ibuki/docrawl/internal/parser"
+	"github.com/yugo-ibuki/docrawl/output"
+	"golang.org/x/net/http/httpguts"
 )
 
 var (
-	baseURL      string
+	baseURLs     []string // クローリング開始URL（複数指定可）
 	outputPath   string
 	maxDepth     int
 	timeout      int
 	delaySeconds float64 // クローリング間の遅延（秒）
 	outputFormat string  // 出力形式（txtまたはpdf）
-	totalTime    int    // 総実行時間（秒）
+	totalTime    int     // 総実行時間（秒）
+
+	userAgent            string   // リクエストに設定するUser-Agent
+	acceptLanguage       string   // リクエストに設定するAccept-Language
+	mimicBrowser         bool     // ブラウザのUser-Agentを使用するか
+	headerFlags          []string // 追加のリクエストヘッダー（"Name: value"）
+	authUser             string   // Basic認証のユーザー名
+	authPass             string   // Basic認証のパスワード
+	token                string   // Bearerトークン
+	cookieFlags          []string // クロール開始前に設定するCookie（"name=value"）
+	loginURL             string   // フォームログインの送信先URL
+	sessionFile          string   // Cookieを保存・復元するセッションファイル
+	loginFields          []string // フォームログインで送信する値（"key=value"）
+	loginSuccessRegex    string   // ログイン成功を判定する正規表現
+	proxyFlag            string   // 経由するプロキシのURL
+	noEnvProxy           bool     // プロキシの環境変数を無視するか
+	preferSourceMarkdown bool     // 元のMarkdownソースを優先して使用するか
+	checkpointPath       string   // チェックポイントファイルのパス
+	resume               bool     // チェックポイントから再開するか
+	retryFailedPath      string   // 取得し直す前回の失敗したURLの一覧（--errors-out のファイル）
+	noContentDedup       bool     // 同じ内容のページの重複排除を無効にするか
+	showStatus           bool     // 出力にHTTPステータスコードを表示するか
+	useUTC               bool     // 出力の日時をUTCで表示するか
+	maxBodySizeMB        float64  // 1ページあたりのレスポンスボディの上限（MB）
+	skipOversized        bool     // 上限を超えたページをスキップするか
+	precheck             bool     // GETの前にHEADリクエストで事前確認するか
+	groupBySeed          bool     // 出力を開始URLごとにまとめるか
+	groupBySection       bool     // 出力をパンくずリスト（なければURLパス）の階層ごとにまとめるか
+	sitemapOnly          bool     // サイトマップに記載されたページのみを取得するか
+	cacheDir             string   // レスポンスをキャッシュするディレクトリ
+	noCache              bool     // キャッシュを読まずに取得し直すか
+	diffAgainst          string   // 比較する前回のクロールのインデックスファイル
+	changedOnly          bool     // 追加・変更されたページのみを出力するか
+	delayJitter          float64  // 待機時間を揺らす割合
+	adaptiveDelay        bool     // サーバーの応答に応じて待機時間を伸縮するか
+	maxURLs              int      // キューに追加できるURLの総数の上限
+	errorsOut            string   // 取得に失敗したURLを書き出すファイル
+	keepVariants         bool     // 印刷用やAMP版のページもたどるか
+	verbose              bool     // デバッグ用のログも表示するか
+	quiet                bool     // 警告とエラーのみを表示するか
+	dryRun               bool     // 出力を生成せずに取得対象のURLを一覧表示するか
+	checkMode            bool     // 出力を生成せずにリンク切れを確認するか
+	checkExternal        bool     // 開始URLと別のホストへのリンクも確認するか
+	checkConcurrency     int      // リンク切れの確認で同時に送るリクエストの数
+	saveHTMLDir          string   // 元のHTMLを保存するディレクトリ
+	skipExts             []string // リンクをたどらない拡張子の追加分
+	allowExts            []string // リンクをたどらない拡張子から除くもの
+	stripQuery           bool     // クエリ文字列をすべて取り除くか
+	keepParams           []string // 取り除かずに残すクエリパラメータ
+	stripParams          []string // デフォルトに加えて取り除くクエリパラメータ
+	urlListPath          string   // 取得するURLを1行に1つずつ記載したファイル
+	maxPagination        int      // rel="next" で連続してたどるページ数の上限
+	failFast             bool     // いずれかのページの取得に失敗した時点で中止するか
+	depthModeName        string   // 深度の数え方（hops または path）
+	linksFrom            string   // リンクを収集する要素のセレクタ
+	contentSelector      string   // 本文を抽出する要素のセレクタ
+	autoContent          bool     // 本文の要素を推定できない場合に本文らしさの点数で選ぶか
+	excludeSelectors     []string // 本文の抽出の前に取り除く要素のセレクタ
+	noDefaultExcludes    bool     // デフォルトで取り除く要素を取り除かないか
+	noIframes            bool     // iframe・frameで埋め込まれたページを取得しないか
+	langFilter           string   // 収集する言語
+	exactVisited         bool     // 訪問済みのURLをURL全体で判定するか
+	localDir             string   // ディスク上に保存したサイトのディレクトリ
+	localBase            string   // ディスク上のサイトのページのURLとして扱うベースURL
+	pageOrder            string   // 出力するページの順序（crawl または nav）
+	navSelector          string   // ナビゲーションの順序を取得する要素のセレクタ
+	navEveryPage         bool     // すべてのページのナビゲーションの順序を使うか
+	soft404Patterns      []string // ソフト404と判定するパターン
+	priorityFlags        []string // URLのパターンごとのクロールの優先度（"pattern=weight"）
+	skipTitleRegexes     []string // 収集しないページのタイトルのパターン
+	skipTitleFollow      bool     // タイトルで収集しなかったページのリンクをたどるか
+	preferOGTitle        bool     // タイトルに og:title を優先するか
+	discoverRoot         bool     // 開始ページからドキュメントのルートを探してクロール範囲とするか
+	scopeRoot            string   // クロール範囲のルートのURL
+	soft404MaxChars      int      // ソフト404と判定する本文の最大文字数
+	authAbortRatio       float64  // 401・403の割合がこれを超えたら中止する
+	nearDupThreshold     int      // ほぼ同じ内容とみなすsimhashのハミング距離
+	nearDupAction        string   // ほぼ同じ内容のページの扱い（alias または skip）
+	breakerWindow        int      // 失敗率を計算する直近のリクエスト数
+	breakerThreshold     float64  // リクエストを一時停止する失敗率
+	breakerCooldown      float64  // リクエストを一時停止する時間（秒）
+	retries              int      // 一時的なネットワークエラーで再試行する回数
+	maxBandwidth         string   // 受信速度の上限（例: 500KB/s）
+	trapMaxRepeats       int      // 1つのURLで同じパスの要素が繰り返してよい回数
+	trapMaxVariants      int      // 数字やクエリの値だけが異なるURLが伸び続けてよい回数の上限
+	render               bool     // ヘッドレスブラウザで描画してから抽出するか
+	latestOnly           bool     // 開始URLのバージョンのページのみを取得するか
+	docVersion           string   // 取得するドキュメントのバージョン
+	includePDFs          bool     // リンク先のPDFも収集するか
+	downloadImages       bool     // ページの画像を保存するか
+	stripBoilerplate     bool     // 複数のページに共通する定型文を取り除くか
+	boilerplateRatio     float64  // 定型文とみなすブロックを含むページの割合
+	boilerplateMaxChars  int      // 定型文とみなすブロックの最大文字数
+	linkStyleName        string   // 本文のリンクの書き出し方（inline または footnote）
+	keepFragmentLinks    bool     // 同じページ内へのリンクも残すか
+	tabsModeName         string   // タブで切り替える内容の書き出し方（all または first）
+	dedentCode           bool     // コードブロックの共通の字下げを取り除くか
+	allowImageCDNs       bool     // よく使われるCDNの画像も保存するか
+	renderTimeout        float64  // 描画を待つ時間の上限（秒）
+	waitFor              string   // 描画後に現れるまで待つ要素のセレクタ
+	waitMS               int      // 描画後にDOMを取得する前に待つ時間（ミリ秒）
 )
 
 var rootCmd = &cobra.Command{
@@ -24,17 +140,392 @@ var rootCmd = &cobra.Command{
 	Long: `docrawlはドキュメントサイト全体をクローリングし、
 内容をテキストファイルまたはPDFとして保存するCLIツールです。技術のライブラリのような
 ドキュメントサイトを対象としています。`,
+	Version: crawler.Version,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if baseURL == "" {
+		// URLリストを読み込む（--url を省略した場合はリストのURLのホストをクロール範囲とする）
+		var urlList []crawler.ListedURL
+		// ディスク上のサイトはディレクトリ（または --local-base）を開始URLとする
+		if localDir != "" {
+			seed, err := crawler.LocalSeedURL(localDir, localBase)
+			if err != nil {
+				return err
+			}
+			baseURLs = []string{seed}
+		} else if localBase != "" {
+			return fmt.Errorf("--local-base を使用するには --local でディレクトリを指定してください")
+		}
+		if urlListPath != "" {
+			list, err := readURLList(urlListPath)
+			if err != nil {
+				return err
+			}
+			urlList = list
+			if len(baseURLs) == 0 {
+				baseURLs = listRoots(urlList)
+			}
+		}
+
+		if len(baseURLs) == 0 {
 			return fmt.Errorf("ベースURLを指定してください")
 		}
 
-		// クローラーを初期化
-		crawler := crawler.New(baseURL, maxDepth, timeout, delaySeconds, totalTime)
-		pages, err := crawler.Crawl()
+		if resume && checkpointPath == "" {
+			return fmt.Errorf("--resume を使用するには --checkpoint でチェックポイントファイルを指定してください")
+		}
+
+		// 前回の失敗したURLを読み込み、チェックポイントの前回のページに取得できたページを追加する
+		var retryFailed []crawler.FailedURL
+		if retryFailedPath != "" {
+			if checkpointPath == "" {
+				return fmt.Errorf("--retry-failed を使用するには --checkpoint で前回のクロールのチェックポイントファイルを指定してください")
+			}
+			failed, err := readFailedURLs(retryFailedPath)
+			if err != nil {
+				return err
+			}
+			if len(failed) == 0 {
+				fmt.Printf("%s に取得に失敗したURLはありません\n", retryFailedPath)
+				return nil
+			}
+			retryFailed = failed
+		}
+
+		if scopeRoot != "" {
+			if err := validateScopeRoot(scopeRoot, baseURLs); err != nil {
+				return err
+			}
+		}
+
+		if checkExternal && !checkMode {
+			return fmt.Errorf("--check-external を使用するには --check を指定してください")
+		}
+
+		if totalTime < 0 {
+			return fmt.Errorf("--total-time には0以上の値を指定してください（0で無制限）")
+		}
+
+		if authAbortRatio < 0 || authAbortRatio > 1 {
+			return fmt.Errorf("--auth-abort-ratio には0から1の値を指定してください")
+		}
+		nearDup, err := crawler.ParseNearDupAction(nearDupAction)
 		if err != nil {
 			return err
 		}
+		if nearDupThreshold < 0 || nearDupThreshold > 64 {
+			return fmt.Errorf("--near-dup-threshold には0から64の値を指定してください")
+		}
+
+		if breakerThreshold <= 0 || breakerThreshold > 1 {
+			return fmt.Errorf("--breaker-threshold には0より大きく1以下の値を指定してください")
+		}
+		if breakerWindow < 0 || breakerCooldown < 0 {
+			return fmt.Errorf("--breaker-window と --breaker-cooldown には0以上の値を指定してください")
+		}
+		if delayJitter < 0 || delayJitter > 1 {
+			return fmt.Errorf("--delay-jitter には0から1の値を指定してください")
+		}
+		depthMode, ok := crawler.ParseDepthMode(depthModeName)
+		if !ok {
+			return fmt.Errorf("--depth-mode には hops または path を指定してください: %s", depthModeName)
+		}
+
+		if linksFrom != "" {
+			if err := parser.ValidateSelector("--links-from", linksFrom); err != nil {
+				return err
+			}
+		}
+
+		if contentSelector != "" {
+			if err := parser.ValidateSelector("--selector", contentSelector); err != nil {
+				return err
+			}
+		}
+		for _, selector := range excludeSelectors {
+			if err := parser.ValidateSelector("--exclude-selector", selector); err != nil {
+				return err
+			}
+		}
+
+		if waitFor != "" {
+			if err := parser.ValidateSelector("--wait-for", waitFor); err != nil {
+				return err
+			}
+		}
+		if boilerplateRatio <= 0 || boilerplateRatio > 1 {
+			return fmt.Errorf("--boilerplate-ratio には0より大きく1以下の値を指定してください")
+		}
+		if boilerplateMaxChars <= 0 {
+			return fmt.Errorf("--boilerplate-max-chars には1以上の値を指定してください")
+		}
+		stripRatio := boilerplateRatio
+		if !stripBoilerplate {
+			stripRatio = 0
+		}
+		linkStyle, err := crawler.ParseLinkStyle(linkStyleName)
+		if err != nil {
+			return err
+		}
+		tabsMode, err := crawler.ParseTabsMode(tabsModeName)
+		if err != nil {
+			return err
+		}
+		if allowImageCDNs && !downloadImages {
+			return fmt.Errorf("--allow-image-cdns は --download-images と併せて指定してください")
+		}
+		if !render && (waitFor != "" || waitMS > 0 || cmd.Flags().Changed("render-timeout")) {
+			return fmt.Errorf("--wait-for、--wait-ms、--render-timeout は --render と併せて指定してください")
+		}
+
+		if pageOrder != "crawl" && pageOrder != "nav" {
+			return fmt.Errorf("--order には crawl または nav を指定してください: %s", pageOrder)
+		}
+		if pageOrder == "nav" {
+			if err := parser.ValidateSelector("--nav-selector", navSelector); err != nil {
+				return err
+			}
+		} else {
+			navSelector = ""
+		}
+
+		var bandwidth int64
+		if maxBandwidth != "" {
+			rate, err := crawler.ParseBandwidth(maxBandwidth)
+			if err != nil {
+				return fmt.Errorf("--max-bandwidth: %w", err)
+			}
+			bandwidth = rate
+		}
+
+		soft404Res, err := compileSoft404Patterns(soft404Patterns)
+		if err != nil {
+			return err
+		}
+
+		priorities, err := parsePriorities(priorityFlags)
+		if err != nil {
+			return err
+		}
+
+		skipTitleRes, err := compileSkipTitlePatterns(skipTitleRegexes)
+		if err != nil {
+			return err
+		}
+		if groupBySeed && groupBySection {
+			return fmt.Errorf("--group-by-seed と --group-by-section は同時に指定できません")
+		}
+		if skipTitleFollow && len(skipTitleRes) == 0 {
+			return fmt.Errorf("--skip-title-follow を使用するには --skip-title-regex を指定してください")
+		}
+
+		if changedOnly && diffAgainst == "" {
+			return fmt.Errorf("--changed-only を使用するには --diff-against で前回のインデックスファイルを指定してください")
+		}
+
+		if mimicBrowser {
+			userAgent = crawler.BrowserUserAgent
+		}
+
+		// 前回のクロールのインデックスを読み込む（クローリング前に読み込めないことを検出する）
+		var previousIndex *crawler.Index
+		if diffAgainst != "" {
+			idx, err := crawler.LoadIndex(diffAgainst)
+			if err != nil {
+				return err
+			}
+			previousIndex = idx
+		}
+
+		headers, err := parseHeaders(headerFlags)
+		if err != nil {
+			return err
+		}
+
+		cookies, err := parseCookies(cookieFlags)
+		if err != nil {
+			return err
+		}
+
+		login, err := buildLoginConfig(loginURL, loginFields, loginSuccessRegex)
+		if err != nil {
+			return err
+		}
+
+		proxyURL, err := parseProxy(proxyFlag)
+		if err != nil {
+			return err
+		}
+
+		if cmd.Flags().Changed("auth-pass") && authUser == "" {
+			return fmt.Errorf("--auth-pass を使用するには --auth-user を指定してください")
+		}
+		// パスワードはシェルの履歴に残らないよう環境変数からも読み込む（Basic認証を使う場合のみ）
+		if authPass == "" && authUser != "" {
+			authPass = os.Getenv("DOCRAWL_AUTH_PASS")
+		}
+		// 環境変数のトークンも --token と同じくBasic認証とは併用できない
+		if envToken := os.Getenv("DOCRAWL_TOKEN"); token == "" && envToken != "" {
+			if authUser != "" {
+				return fmt.Errorf("環境変数 DOCRAWL_TOKEN と --auth-user は同時に指定できません")
+			}
+			token = envToken
+		}
+
+		// HTMLの保存先を作成（クローリング前に作成できないことを検出する）
+		if saveHTMLDir != "" {
+			if err := os.MkdirAll(saveHTMLDir, 0o755); err != nil {
+				return fmt.Errorf("HTMLの保存先ディレクトリの作成に失敗しました: %w", err)
+			}
+		}
+
+		// 出力形式を確認（クローリング前に未対応の形式を検出する）
+		factory, err := output.Lookup(outputFormat)
+		if err != nil {
+			return err
+		}
+
+		// リンク先のPDFとページの画像は出力ファイルの横のディレクトリに保存する
+		assetsDir := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "_assets"
+		var pdfDir string
+		if includePDFs {
+			pdfDir = assetsDir
+		}
+		var imageDir, imageRefPrefix string
+		var imageHosts []string
+		if downloadImages {
+			imageDir = filepath.Join(assetsDir, "images")
+			imageRefPrefix = filepath.ToSlash(filepath.Join(filepath.Base(assetsDir), "images")) + "/"
+			if allowImageCDNs {
+				imageHosts = crawler.DefaultImageCDNHosts
+			}
+		}
+
+		// クローラーを初期化
+		startedAt := time.Now()
+		crawler := crawler.New(baseURLs[0], maxDepth, timeout, delaySeconds, totalTime,
+			crawler.WithUserAgent(userAgent),
+			crawler.WithAcceptLanguage(acceptLanguage),
+			crawler.WithHeaders(headers),
+			crawler.WithBasicAuth(authUser, authPass),
+			crawler.WithBearerToken(token),
+			crawler.WithCookies(cookies),
+			crawler.WithLogin(login),
+			crawler.WithSessionFile(sessionFile),
+			crawler.WithProxy(proxyURL, noEnvProxy),
+			crawler.WithPreferSourceMarkdown(preferSourceMarkdown),
+			crawler.WithCheckpoint(checkpointPath, resume),
+			crawler.WithRetryFailed(retryFailed),
+			crawler.WithContentDedup(!noContentDedup),
+			crawler.WithMaxBodySize(int64(maxBodySizeMB*(1<<20)), skipOversized),
+			crawler.WithPrecheck(precheck),
+			crawler.WithSeeds(baseURLs[1:]),
+			crawler.WithSitemapOnly(sitemapOnly),
+			crawler.WithCache(cacheDir, noCache),
+			crawler.WithDelayJitter(delayJitter, adaptiveDelay),
+			crawler.WithMaxURLs(maxURLs),
+			crawler.WithKeepVariants(keepVariants),
+			crawler.WithLogger(newLogger(verbose, quiet)),
+			crawler.WithDryRun(dryRun),
+			crawler.WithSaveHTML(saveHTMLDir),
+			crawler.WithExtensionFilter(skipExts, allowExts),
+			crawler.WithQueryNormalization(stripQuery, keepParams, stripParams),
+			crawler.WithURLList(urlList),
+			crawler.WithMaxPagination(maxPagination),
+			crawler.WithFailFast(failFast),
+			crawler.WithDepthMode(depthMode),
+			crawler.WithLinksFrom(linksFrom),
+			crawler.WithContentSelector(contentSelector),
+			crawler.WithAutoContent(autoContent),
+			crawler.WithExcludeSelectors(excludeSelectors, !noDefaultExcludes),
+			crawler.WithFollowFrames(!noIframes),
+			crawler.WithLangFilter(langFilter),
+			crawler.WithExactVisited(exactVisited),
+			crawler.WithLocalDir(localDir, localBase),
+			crawler.WithNavOrder(navSelector, navEveryPage),
+			crawler.WithSoft404(soft404Res, soft404MaxChars),
+			crawler.WithPriorities(priorities),
+			crawler.WithSkipTitles(skipTitleRes, skipTitleFollow),
+			crawler.WithPreferOGTitle(preferOGTitle),
+			crawler.WithAuthAbortRatio(authAbortRatio),
+			crawler.WithNearDuplicates(nearDupThreshold, nearDup),
+			crawler.WithCircuitBreaker(breakerWindow, breakerThreshold, time.Duration(breakerCooldown*float64(time.Second))),
+			crawler.WithRetries(retries),
+			crawler.WithMaxBandwidth(bandwidth),
+			crawler.WithTrapDetection(trapMaxRepeats, trapMaxVariants),
+			crawler.WithScopeRoot(scopeRoot, discoverRoot),
+			crawler.WithLinkCheck(checkMode, checkExternal, checkConcurrency),
+			crawler.WithRender(render),
+			crawler.WithVersion(docVersion, latestOnly),
+			crawler.WithIncludePDFs(pdfDir),
+			crawler.WithDownloadImages(imageDir, imageRefPrefix, imageHosts),
+			crawler.WithLinkStyle(linkStyle, keepFragmentLinks),
+			crawler.WithTabsMode(tabsMode),
+			crawler.WithCodeDedent(dedentCode),
+			crawler.WithBoilerplateStripping(stripRatio, boilerplateMaxChars),
+			crawler.WithRenderWait(time.Duration(renderTimeout*float64(time.Second)), waitFor, time.Duration(waitMS)*time.Millisecond),
+		)
+		ctx, stop := notifyInterrupt()
+		defer stop()
+		// 取得に失敗しても取得済みのページは出力し、最後にエラーを返して終了コードを0以外にする
+		crawlResult, crawlErr := crawler.Crawl(ctx)
+		if crawlResult == nil || len(crawlResult.Pages) == 0 && crawlErr != nil {
+			return crawlErr
+		}
+		if crawlErr != nil {
+			fmt.Fprintf(os.Stderr, "警告: %v\n取得済みの %d ページを出力します\n", crawlErr, len(crawlResult.Pages))
+		} else if denied := crawlResult.Stats.AuthDenied; denied > 0 {
+			fmt.Fprintf(os.Stderr, "警告: %d 件のページは認証が必要なため取得できませんでした (401/403)\n", denied)
+			exitCode = ExitAuthGaps
+		}
+		pages := crawlResult.Pages
+		if ctx.Err() != nil {
+			fmt.Printf("中断前に %d ページを取得しました\n", len(pages))
+		}
+
+		// 取得に失敗したURLを表示し、指定があればファイルに書き出す
+		// 失敗したURLの再取得では、まだ失敗しているURLで一覧を更新する
+		printCrawlErrors(crawlResult.Errors)
+		failuresOut := errorsOut
+		if retryFailed != nil {
+			printRecovered(retryFailed, crawlResult.Errors, crawler.RetrySkipped(), crawler.NormalizeURL)
+			if failuresOut == "" {
+				failuresOut = retryFailedPath
+			}
+		}
+		if failuresOut != "" {
+			if err := writeCrawlErrors(failuresOut, crawlResult.Errors); err != nil {
+				return err
+			}
+			fmt.Printf("取得に失敗したURLを書き出しました: %s\n", failuresOut)
+		}
+
+		if crawler.URLCapReached() {
+			fmt.Printf("警告: URLの上限 (%d 件) に達したため、一部のページを取得していません\n", maxURLs)
+		}
+		printCrawlStats(crawlResult.Stats, cacheDir != "", precheck)
+
+		// ユーザー指定セレクタの有効性を表示
+		crawler.SelectorStats().Report(os.Stdout)
+
+		// リンク切れの確認では出力を生成せず、リンク切れのリンクを表示して終了する
+		if checkMode {
+			printBrokenLinks(crawlResult.BrokenLinks)
+			if len(crawlResult.BrokenLinks) > 0 && crawlErr == nil {
+				exitCode = ExitBrokenLinks
+			}
+			return crawlErr
+		}
+
+		// ドライランでは取得対象のURLを一覧表示して終了する
+		if dryRun {
+			for _, page := range pages {
+				fmt.Printf("%d\t%s\n", page.Depth, page.URL)
+			}
+			fmt.Printf("取得対象のURL: %d 件\n", len(pages))
+			if len(pages) == 0 {
+				fmt.Fprintln(os.Stderr, "警告: 条件に一致するURLがありませんでした")
+			}
+			return crawlErr
+		}
 
 		// 出力パスの調整
 		if outputFormat == "txt" && !containsExtension(outputPath, ".txt") {
@@ -47,27 +538,605 @@ var rootCmd = &cobra.Command{
 			}
 		}
 
-		// テキスト形式で出力する場合
-		if outputFormat == "txt" {
-			// テキストファイルを直接生成
-			err = crawler.GenerateTXT(pages, outputPath)
-			if err != nil {
+		// ナビゲーションの順に並べ替える
+		if pageOrder == "nav" {
+			sortPagesByNav(pages, crawler.NavOrder())
+		}
+
+		// 開始URLごとにまとめる場合は、開始URLの指定順に並べ替える（同じ開始URL内はクロール順）
+		if groupBySeed {
+			groupPagesBySeed(pages, crawler.Seeds())
+		}
+
+		// 次回の比較のために今回のクロールのインデックスを保存する
+		// 中断などで一部のページしか取得していない場合は、次回の比較で取得しなかったページが削除とみなされるため保存しない
+		complete := crawlResult.Complete && retryFailed == nil
+		indexPath := outputPath + ".index.json"
+		if complete {
+			if err := saveIndex(indexPath, pages); err != nil {
 				return err
 			}
-			fmt.Printf("成功: %s にテキストファイルが生成されました\n", outputPath)
-			return nil
+			fmt.Printf("インデックスを保存しました: %s\n", indexPath)
+		} else {
+			fmt.Printf("クロールが完了しなかったため、インデックスを保存しません（%s は前回のままです）\n", indexPath)
+		}
+
+		// 前回のクロールと比較し、必要であれば変更のあったページのみに絞り込む
+		// クロールが完了しなかった場合は、取得しなかったページを削除されたページとして表示しない
+		var removedURLs []string
+		if previousIndex != nil {
+			removedURLs = previousIndex.ApplyChanges(pages)
+			if !complete {
+				removedURLs = nil
+			}
+			printChangeSummary(pages, removedURLs)
+			if changedOnly {
+				pages = filterChangedPages(pages)
+				if len(pages) == 0 {
+					fmt.Println("追加・変更されたページはありません")
+					return crawlErr
+				}
+			}
 		}
 
-		// PDF（またはテキスト）ジェネレーターを使用
-		generator := pdf.NewGenerator(outputPath)
-		if err := generator.GeneratePDF(pages); err != nil {
+		// セクションごとにまとめる場合は、パンくずリスト（なければURLパス）の階層の順に並べ替える
+		outPages := outputPages(pages)
+		if groupBySection {
+			outPages = output.BuildSectionTree(outPages).AllPages()
+		}
+
+		// 出力形式に対応するWriterで出力
+		writer, err := factory(output.Options{OutputPath: outputPath, ShowStatus: showStatus, UTC: useUTC})
+		if err != nil {
 			return err
 		}
+		result := &output.CrawlResult{
+			Meta: output.CrawlMeta{
+				BaseURL:    baseURLs[0],
+				SeedURLs:   crawler.Seeds(),
+				StartedAt:  startedAt,
+				FinishedAt: time.Now(),
+				PageCount:  len(outPages),
+				DocVersion: crawler.DocVersion(),
+				AcceptLang: crawler.AcceptLanguage(),
 
-		return nil
+				RemovedURLs: removedURLs,
+			},
+			Pages: outPages,
+		}
+		if err := output.Write(writer, result); err != nil {
+			return err
+		}
+		if outputFormat == "txt" {
+			fmt.Printf("成功: %s にテキストファイルが生成されました\n", outputPath)
+		}
+		return crawlErr
 	},
 }
 
+// readURLList はURLリストファイルを読み込む
+// 空行と#で始まる行は無視し、形式の正しくない行は行番号とともに警告してスキップする
+func readURLList(path string) ([]crawler.ListedURL, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("URLリストの読み込みに失敗しました: %w", err)
+	}
+
+	list := make([]crawler.ListedURL, 0)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fmt.Fprintf(os.Stderr, "警告: %s:%d: URLの形式が正しくないためスキップします: %s\n", path, i+1, line)
+			continue
+		}
+		list = append(list, crawler.ListedURL{URL: line, Line: i + 1})
+	}
+	return list, nil
+}
+
+// listRoots はURLリストに含まれるホストのルートURLを出現順に返す
+func listRoots(list []crawler.ListedURL) []string {
+	var roots []string
+	seen := make(map[string]bool)
+	for _, entry := range list {
+		u, err := url.Parse(entry.URL)
+		if err != nil {
+			continue
+		}
+		root := u.Scheme + "://" + u.Host + "/"
+		if !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// newLogger は -v/-q の指定に応じたレベルで標準エラー出力に書き出すロガーを作成する
+func newLogger(verbose, quiet bool) *slog.Logger {
+	level := slog.LevelInfo
+	switch {
+	case verbose:
+		level = slog.LevelDebug
+	case quiet:
+		level = slog.LevelWarn
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: level,
+		// 端末での読みやすさのため時刻は出力しない
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+// printBrokenLinks はリンク切れのリンクをリンクを含むページごとに表示する
+func printBrokenLinks(links []crawler.BrokenLink) {
+	if len(links) == 0 {
+		fmt.Println("\nリンク切れは見つかりませんでした")
+		return
+	}
+	fmt.Printf("\nリンク切れ: %d 件\n", len(links))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	page := ""
+	for _, link := range links {
+		if link.Page != page {
+			page = link.Page
+			fmt.Fprintf(w, "%s\n", page)
+		}
+		status := "-"
+		if link.StatusCode > 0 {
+			status = fmt.Sprint(link.StatusCode)
+		}
+		scope := "内部"
+		if link.External {
+			scope = "外部"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", status, scope, link.URL, link.Err)
+	}
+	w.Flush()
+}
+
+// printCrawlErrors は取得に失敗したURLを表形式で表示する
+func printCrawlErrors(errs []crawler.CrawlError) {
+	if len(errs) == 0 {
+		return
+	}
+	fmt.Printf("\n取得に失敗したURL: %d 件\n", len(errs))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "種類\t深度\t試行\tURL\tエラー")
+	for _, e := range errs {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%v\n", e.Kind, e.Depth, e.Attempts, e.URL, e.Err)
+	}
+	w.Flush()
+}
+
+// printCrawlStats はクロールの統計情報を表形式で表示する
+func printCrawlStats(stats crawler.StatsSnapshot, showCache, showHead bool) {
+	fmt.Println("\nクロールの統計:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  取得したページ\t%d 件\n", stats.PagesFetched)
+	fmt.Fprintf(w, "  スキップしたページ\t%d 件\n", stats.SkippedTotal())
+	for _, reason := range crawler.SkipReasons() {
+		if n := stats.Skipped[reason]; n > 0 {
+			fmt.Fprintf(w, "    %s\t%d 件\n", reason, n)
+		}
+	}
+	if stats.Retries > 0 {
+		fmt.Fprintf(w, "  再試行\t%d 回\n", stats.Retries)
+	}
+	if stats.WaitForMissing > 0 {
+		fmt.Fprintf(w, "  --wait-for の要素が現れなかったページ\t%d 件\n", stats.WaitForMissing)
+	}
+	fmt.Fprintf(w, "  ダウンロード量（展開後）\t%s\n", formatBytes(stats.BytesDownloaded))
+	fmt.Fprintf(w, "  転送量\t%s\n", formatBytes(stats.BytesReceived))
+	fmt.Fprintf(w, "  経過時間\t%s\n", stats.Elapsed.Round(time.Millisecond))
+	// --max-bandwidth と比べられるよう、展開前の転送量を経過時間で割る
+	if seconds := stats.Elapsed.Seconds(); seconds > 0 {
+		fmt.Fprintf(w, "  平均転送速度\t%s/s\n", formatBytes(int64(float64(stats.BytesReceived)/seconds)))
+	}
+	fmt.Fprintf(w, "  平均応答時間\t%s\n", stats.AverageResponse.Round(time.Millisecond))
+	fmt.Fprintf(w, "  取得時間 (p50 / p95)\t%s / %s\n", stats.FetchP50.Round(time.Millisecond), stats.FetchP95.Round(time.Millisecond))
+	if showCache {
+		fmt.Fprintf(w, "  キャッシュから再利用したページ\t%d 件\n", stats.CacheHits)
+	}
+	if showHead {
+		fmt.Fprintf(w, "  事前確認のHEADリクエスト\t%d 件\n", stats.HeadRequests)
+	}
+	w.Flush()
+
+	if len(stats.Slowest) == 0 {
+		return
+	}
+	fmt.Println("\n応答の遅いURL:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, t := range stats.Slowest {
+		fmt.Fprintf(w, "  %s\t%s\n", t.Duration.Round(time.Millisecond), t.URL)
+	}
+	w.Flush()
+}
+
+// formatBytes はバイト数を読みやすい単位で表す
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// writeCrawlErrors は取得に失敗したURLをタブ区切りでファイルに書き出す
+func writeCrawlErrors(path string, errs []crawler.CrawlError) error {
+	var sb strings.Builder
+	sb.WriteString("kind\tdepth\tattempts\turl\terror\n")
+	for _, e := range errs {
+		msg := strings.NewReplacer("\t", " ", "\n", " ").Replace(e.Err.Error())
+		fmt.Fprintf(&sb, "%s\t%d\t%d\t%s\t%s\n", e.Kind, e.Depth, e.Attempts, e.URL, msg)
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		return fmt.Errorf("エラー一覧の書き出しに失敗しました: %w", err)
+	}
+	return nil
+}
+
+// readFailedURLs は --errors-out で書き出した取得に失敗したURLの一覧を読み込む
+func readFailedURLs(path string) ([]crawler.FailedURL, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("取得に失敗したURLの一覧の読み込みに失敗しました: %w", err)
+	}
+	var failed []crawler.FailedURL
+	for i, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if i == 0 || line == "" {
+			continue // ヘッダー行
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("%s:%d: 取得に失敗したURLの一覧の形式が不正です", path, i+1)
+		}
+		depth, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: 深度が不正です: %s", path, i+1, fields[1])
+		}
+		failed = append(failed, crawler.FailedURL{URL: fields[3], Depth: depth})
+	}
+	return failed, nil
+}
+
+// printRecovered は取得し直したURLのうち取得できたものと、範囲外のためスキップしたものを表示する
+// 失敗したURL（CrawlError.URL）は正規化されているため、前回の一覧のURLも normalize で正規化して比較する
+func printRecovered(failed []crawler.FailedURL, errs []crawler.CrawlError, skipped []string, normalize func(string) string) {
+	stillFailed := make(map[string]bool, len(errs))
+	for _, e := range errs {
+		stillFailed[normalize(e.URL)] = true
+	}
+	isSkipped := make(map[string]bool, len(skipped))
+	for _, u := range skipped {
+		isSkipped[u] = true
+	}
+	var recovered []string
+	for _, f := range failed {
+		if !isSkipped[f.URL] && !stillFailed[normalize(f.URL)] {
+			recovered = append(recovered, f.URL)
+		}
+	}
+	retried := len(failed) - len(skipped)
+	fmt.Printf("\n取得し直したURL: %d 件（取得できた %d 件、失敗 %d 件、範囲外のためスキップ %d 件）\n", retried, len(recovered), retried-len(recovered), len(skipped))
+	for _, u := range recovered {
+		fmt.Printf("  取得できた: %s\n", u)
+	}
+	for _, u := range skipped {
+		fmt.Printf("  スキップ: %s\n", u)
+	}
+}
+
+// saveIndex は次回のクロールで変化を検出するためのインデックスを保存する
+func saveIndex(path string, pages []crawler.Page) error {
+	return crawler.BuildIndex(pages).Save(path)
+}
+
+// outputPages はクロールしたページをWriterに渡す形式に変換する
+func outputPages(pages []crawler.Page) []output.Page {
+	converted := make([]output.Page, len(pages))
+	for i, page := range pages {
+		converted[i] = output.Page{
+			URL:           page.URL,
+			RequestedURL:  page.RequestedURL,
+			Title:         page.Title,
+			Content:       page.Content,
+			Depth:         page.Depth,
+			Seed:          page.Seed,
+			StatusCode:    page.StatusCode,
+			FetchedAt:     page.FetchedAt,
+			LastModified:  page.LastModified,
+			CanonicalURL:  page.CanonicalURL,
+			SourceEditURL: page.SourceEditURL,
+			FromSource:    page.FromSource,
+			AliasURLs:     page.AliasURLs,
+			ChangeStatus:  page.ChangeStatus,
+			FromPDF:       page.FromPDF,
+			AssetPath:     page.AssetPath,
+			Headings:      outputHeadings(page.Headings),
+			Description:   page.Description,
+			OGTitle:       page.OGTitle,
+			ModifiedAt:    page.ModifiedAt,
+			Breadcrumbs:   page.Breadcrumbs,
+			Outline:       outputOutline(page.Outline),
+		}
+	}
+	return converted
+}
+
+// outputHeadings は本文の見出しをWriterに渡す形式に変換する
+func outputHeadings(headings []crawler.Heading) []output.Heading {
+	if headings == nil {
+		return nil
+	}
+	converted := make([]output.Heading, len(headings))
+	for i, h := range headings {
+		converted[i] = output.Heading{Level: h.Level, Text: h.Text, Anchor: h.Anchor}
+	}
+	return converted
+}
+
+// outputOutline は見出しの階層をWriterに渡す形式に変換する
+func outputOutline(nodes []*crawler.HeadingNode) []*output.HeadingNode {
+	if nodes == nil {
+		return nil
+	}
+	converted := make([]*output.HeadingNode, len(nodes))
+	for i, node := range nodes {
+		converted[i] = &output.HeadingNode{
+			Heading:  output.Heading{Level: node.Level, Text: node.Text, Anchor: node.Anchor},
+			Depth:    node.Depth,
+			Children: outputOutline(node.Children),
+		}
+	}
+	return converted
+}
+
+// printChangeSummary は前回のクロールからの変化の件数を表示する
+func printChangeSummary(pages []crawler.Page, removedURLs []string) {
+	counts := make(map[string]int)
+	for _, page := range pages {
+		counts[page.ChangeStatus]++
+	}
+	fmt.Printf("前回のクロールからの変化: 追加 %d 件, 変更 %d 件, 削除 %d 件 (変更なし %d 件)\n",
+		counts[crawler.ChangeAdded], counts[crawler.ChangeChanged], len(removedURLs), counts[crawler.ChangeUnchanged])
+	for _, u := range removedURLs {
+		fmt.Printf("  削除: %s\n", u)
+	}
+}
+
+// filterChangedPages は追加または変更されたページのみを返す
+func filterChangedPages(pages []crawler.Page) []crawler.Page {
+	var changed []crawler.Page
+	for _, page := range pages {
+		if page.ChangeStatus == crawler.ChangeAdded || page.ChangeStatus == crawler.ChangeChanged {
+			changed = append(changed, page)
+		}
+	}
+	return changed
+}
+
+// groupPagesBySeed はページを開始URLの指定順に安定ソートする
+func groupPagesBySeed(pages []crawler.Page, seeds []string) {
+	order := make(map[string]int, len(seeds))
+	for i, seed := range seeds {
+		order[seed] = i
+	}
+	sort.SliceStable(pages, func(i, j int) bool {
+		return order[pages[i].Seed] < order[pages[j].Seed]
+	})
+}
+
+// compileSoft404Patterns はソフト404と判定するパターンをコンパイルする
+func compileSoft404Patterns(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("--soft-404-pattern の正規表現が不正です: %w", err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// validateScopeRoot はクロール範囲のルートがいずれかの開始URLと同じホストのURLかを確認する
+func validateScopeRoot(root string, baseURLs []string) error {
+	u, err := url.Parse(root)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("--scope-root のURLが不正です: %s", root)
+	}
+	for _, base := range baseURLs {
+		if b, err := url.Parse(base); err == nil && strings.EqualFold(b.Host, u.Host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("--scope-root には開始URLと同じホストのURLを指定してください: %s", root)
+}
+
+// compileSkipTitlePatterns は収集しないページのタイトルのパターンをコンパイルする
+// 大文字と小文字を区別しない（区別する場合はパターンの先頭に (?-i) を付ける）
+func compileSkipTitlePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return nil, fmt.Errorf("--skip-title-regex の正規表現が不正です: %w", err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// parsePriorities は "pattern=weight" 形式の優先度の指定を解析する
+// パターンに = を含められるよう、最後の = で区切る
+func parsePriorities(specs []string) ([]crawler.PriorityRule, error) {
+	var rules []crawler.PriorityRule
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("--priority の形式が不正です（\"pattern=weight\" の形式で指定してください）: %s", spec)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(spec[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("--priority の重みは整数で指定してください: %s", spec)
+		}
+		re, err := regexp.Compile(spec[:i])
+		if err != nil {
+			return nil, fmt.Errorf("--priority の正規表現が不正です: %w", err)
+		}
+		rules = append(rules, crawler.PriorityRule{Pattern: re, Weight: weight})
+	}
+	return rules, nil
+}
+
+// sortPagesByNav はページをナビゲーションの順に並べ替える
+func sortPagesByNav(pages []crawler.Page, nav []string) {
+	crawler.SortByNav(pages, nav)
+}
+
+// notifyInterrupt はSIGINT/SIGTERMでキャンセルされるコンテキストを返す
+// 1回目のシグナルでクローリングを中断して取得済みのページを出力し、2回目で即座に終了する
+func notifyInterrupt() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-sigCh:
+		case <-ctx.Done():
+			return
+		}
+		fmt.Fprintln(os.Stderr, "\n中断を受け付けました。取得済みのページを出力します（もう一度押すと強制終了します）")
+		cancel()
+		<-sigCh
+		fmt.Fprintln(os.Stderr, "強制終了します")
+		os.Exit(130)
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigCh)
+		cancel()
+	}
+}
+
+// reservedHeaders はクローラーの動作に必要なため上書きできないヘッダー
+var reservedHeaders = map[string]bool{
+	"Host":           true,
+	"Content-Length": true,
+}
+
+// parseHeaders は "Name: value" 形式のヘッダー指定を解析する
+// 同じ名前のヘッダーは後に指定した値で上書きする
+func parseHeaders(specs []string) (http.Header, error) {
+	headers := make(http.Header)
+	for _, spec := range specs {
+		name, value, found := strings.Cut(spec, ":")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if !found || !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("--header の形式が不正です（\"Name: value\" の形式で指定してください）: %q", spec)
+		}
+		if reservedHeaders[http.CanonicalHeaderKey(name)] {
+			return nil, fmt.Errorf("--header で %s ヘッダーは指定できません", http.CanonicalHeaderKey(name))
+		}
+		headers.Set(name, value)
+	}
+	return headers, nil
+}
+
+// parseCookies は "name=value" 形式のCookie指定を解析する
+// エラーメッセージにはCookieの値を含めない
+func parseCookies(specs []string) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	for _, spec := range specs {
+		parsed, err := http.ParseCookie(spec)
+		if err != nil || len(parsed) != 1 || parsed[0].Name == "" {
+			name, _, _ := strings.Cut(spec, "=")
+			return nil, fmt.Errorf("--cookie の形式が不正です（\"name=value\" の形式で指定してください）: %s", strings.TrimSpace(name))
+		}
+		cookies = append(cookies, parsed[0])
+	}
+	return cookies, nil
+}
+
+// parseProxy はプロキシのURLを解析する（http://、https://、socks5:// に対応）
+func parseProxy(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("--proxy のURLが不正です: %s", raw)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return u, nil
+	default:
+		return nil, fmt.Errorf("--proxy は http://、https://、socks5:// のいずれかで指定してください: %s", u.Redacted())
+	}
+}
+
+// envPattern はフォームの値に埋め込む環境変数の参照（${NAME}）
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// buildLoginConfig はフォームログインのフラグからログイン設定を作成する
+// フィールドの値に含まれる ${NAME} は環境変数の値に置き換える
+func buildLoginConfig(loginURL string, fields []string, successRegex string) (*crawler.LoginConfig, error) {
+	if loginURL == "" {
+		if len(fields) > 0 || successRegex != "" {
+			return nil, fmt.Errorf("--login-field と --login-success-regex を使用するには --login-url を指定してください")
+		}
+		return nil, nil
+	}
+
+	login := &crawler.LoginConfig{URL: loginURL, Fields: url.Values{}}
+	for _, field := range fields {
+		key, value, found := strings.Cut(field, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("--login-field の形式が不正です（\"key=value\" の形式で指定してください）: %s", key)
+		}
+		var missing string
+		value = envPattern.ReplaceAllStringFunc(value, func(ref string) string {
+			name := envPattern.FindStringSubmatch(ref)[1]
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				missing = name
+			}
+			return v
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("--login-field %s が参照する環境変数 %s が設定されていません", key, missing)
+		}
+		login.Fields.Add(strings.TrimSpace(key), value)
+	}
+
+	if successRegex != "" {
+		re, err := regexp.Compile(successRegex)
+		if err != nil {
+			return nil, fmt.Errorf("--login-success-regex が不正です: %w", err)
+		}
+		login.SuccessRegex = re
+	}
+	return login, nil
+}
+
 // containsExtension はパスに特定の拡張子が含まれているかを確認
 func containsExtension(path, ext string) bool {
 	return len(path) >= len(ext) && path[len(path)-len(ext):] == ext
@@ -77,14 +1146,140 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// 終了コード（エラーで終了した場合は1）
+const (
+	ExitOK          = 0 // すべてのページを取得できた
+	ExitAuthGaps    = 2 // 完了したが401・403で取得できなかったページがある
+	ExitBrokenLinks = 3 // --check でリンク切れが見つかった
+)
+
+// exitCode はエラーなしで終了した場合の終了コード
+var exitCode = ExitOK
+
+// ExitCode はエラーなしで終了した場合の終了コードを返す
+func ExitCode() int {
+	return exitCode
+}
+
 func init() {
-	rootCmd.Flags().StringVarP(&baseURL, "url", "u", "", "クローリング開始URLを指定 (必須)")
+	rootCmd.Flags().StringSliceVarP(&baseURLs, "url", "u", nil, "クローリング開始URLを指定 (--url-list か --local を指定しない場合は必須、複数指定またはカンマ区切りで複数の開始URLを指定可)")
 	rootCmd.Flags().StringVarP(&outputPath, "output", "o", "output.pdf", "出力ファイルパス")
 	rootCmd.Flags().IntVarP(&maxDepth, "depth", "d", 3, "クローリングの最大深度")
 	rootCmd.Flags().IntVarP(&timeout, "timeout", "t", 30, "リクエストタイムアウト（秒）")
-	rootCmd.Flags().Float64VarP(&delaySeconds, "delay", "w", 2.0, "リクエスト間の待機時間（秒）")
+	rootCmd.Flags().Float64VarP(&delaySeconds, "delay", "w", 2.0, "同じホストへのリクエスト間の待機時間（秒）")
+	rootCmd.Flags().Float64Var(&delayJitter, "delay-jitter", 0, "待機時間をランダムに揺らす割合（0.5で±50%）")
+	rootCmd.Flags().BoolVar(&exactVisited, "exact-visited", false, "訪問済みのURLを64ビットのハッシュではなくURL全体で判定する（メモリ使用量が増える）")
+	rootCmd.Flags().IntVar(&maxURLs, "max-urls", crawler.DefaultMaxURLs, "キューに追加できるURLの総数の上限（0で無制限）")
+	rootCmd.Flags().StringArrayVar(&priorityFlags, "priority", nil, "URLが正規表現に一致するページを先にクロールする重み（\"pattern=weight\"、複数指定可、例: \"/guide/=10\"）。同じ深度では重みの大きい順に取得する")
+	rootCmd.Flags().BoolVar(&adaptiveDelay, "adaptive-delay", false, "応答が遅いときやエラー時に待機時間を伸ばし、快調なときは --delay まで縮める")
 	rootCmd.Flags().StringVarP(&outputFormat, "format", "f", "txt", "出力形式 (txt または pdf)")
-	rootCmd.Flags().IntVarP(&totalTime, "total-time", "T", 300, "総実行時間（秒）")
+	rootCmd.Flags().IntVarP(&totalTime, "total-time", "T", 300, "総実行時間（秒、0で無制限）。経過した時点で取得済みのページを出力する")
+	rootCmd.Flags().StringVar(&acceptLanguage, "accept-language", crawler.DefaultAcceptLanguage, "リクエストに設定するAccept-Language（例: ja,en;q=0.8）。出力のヘッダーにも記録する")
+	rootCmd.Flags().StringVar(&userAgent, "user-agent", "", "リクエストに設定するUser-Agent (デフォルト: "+crawler.DefaultUserAgent()+")")
+	rootCmd.Flags().BoolVar(&mimicBrowser, "mimic-browser", false, "ブラウザ（Chrome）のUser-Agentを使用する")
+	rootCmd.MarkFlagsMutuallyExclusive("user-agent", "mimic-browser")
+	rootCmd.Flags().StringArrayVarP(&headerFlags, "header", "H", nil, "すべてのリクエストに追加するヘッダー（\"Name: value\"、複数指定可）")
+	rootCmd.Flags().BoolVar(&preferSourceMarkdown, "prefer-source-markdown", false, "元のMarkdownソース（編集リンクや.mdのURL）が取得できる場合はHTMLの代わりに使用")
+
+	rootCmd.Flags().StringVar(&authUser, "auth-user", "", "Basic認証のユーザー名")
+	rootCmd.Flags().StringVar(&authPass, "auth-pass", "", "Basic認証のパスワード（環境変数 DOCRAWL_AUTH_PASS でも指定可）")
+	rootCmd.Flags().StringVar(&token, "token", "", "Bearerトークン（環境変数 DOCRAWL_TOKEN でも指定可）")
+	rootCmd.MarkFlagsMutuallyExclusive("token", "auth-user")
+	rootCmd.MarkFlagsMutuallyExclusive("token", "auth-pass")
+	rootCmd.Flags().StringArrayVar(&cookieFlags, "cookie", nil, "クロール開始前に設定するCookie（\"name=value\"、複数指定可）")
+	rootCmd.Flags().BoolVar(&render, "render", false, "ヘッドレスブラウザ（ChromeまたはChromium）でJavaScriptを実行して描画したDOMからコンテンツを抽出する（SPAのドキュメント向け、CHROME_PATH で実行ファイルを指定可）")
+	rootCmd.Flags().Float64Var(&renderTimeout, "render-timeout", crawler.DefaultRenderTimeout.Seconds(), "--render でネットワークが落ち着くのと --wait-for の要素が現れるのを待つ時間の上限（秒）。超えた場合はその時点のDOMを使う")
+	rootCmd.Flags().StringVar(&waitFor, "wait-for", "", "--render で描画後にこのCSSセレクタに一致する要素が現れるまで待つ（現れなかったページは警告を表示し、集計に含める）")
+	rootCmd.Flags().IntVar(&waitMS, "wait-ms", 0, "--render で描画後にDOMを取得する前に一律に待つ時間（ミリ秒）")
+	rootCmd.Flags().BoolVar(&latestOnly, "latest-only", false, "バージョン付きのドキュメント（/en/v2.3/、/en/latest/ など）で開始URLのバージョンのページのみを取得する（開始URLにバージョンがない場合は正規URLやバージョンの切り替えのlatest・stableから判定する）")
+	rootCmd.Flags().StringVar(&docVersion, "doc-version", "", "取得するドキュメントのバージョン（例: v2.3、latest）。開始URLのバージョンも置き換え、別のバージョンへのリンクはたどらない")
+	rootCmd.Flags().BoolVar(&stripBoilerplate, "strip-boilerplate", false, "クロール後に多くのページに同じ内容で現れる短いブロック（「このページは役に立ちましたか？」やフッターなど）を本文から取り除く")
+	rootCmd.Flags().Float64Var(&boilerplateRatio, "boilerplate-ratio", crawler.DefaultBoilerplateRatio, "--strip-boilerplate で定型文とみなすブロックを含むページの割合（0より大きく1以下）")
+	rootCmd.Flags().IntVar(&boilerplateMaxChars, "boilerplate-max-chars", crawler.DefaultBoilerplateMaxChars, "--strip-boilerplate で定型文とみなすブロックの最大文字数")
+	rootCmd.Flags().StringVar(&linkStyleName, "link-style", string(crawler.LinkInline), "本文のリンクの書き出し方（inline: [テキスト](URL)、footnote: テキスト [1] とページの末尾のリンクの一覧。テキストやPDFでの出力向け）")
+	rootCmd.Flags().BoolVar(&keepFragmentLinks, "keep-fragment-links", false, "同じページ内の見出しなどへのリンク（#section）もリンクとして残す（デフォルトではテキストのみにする）")
+	rootCmd.Flags().BoolVar(&dedentCode, "dedent-code", false, "コードブロックのすべての行に共通する行頭の字下げを取り除く（デフォルトではpre要素の内容をそのまま残す）")
+	rootCmd.Flags().StringVar(&tabsModeName, "tabs", string(crawler.TabsAll), "タブで切り替える内容（npm / yarn のコード例など）の書き出し方（all: すべてのタブをタブ名に続けて書き出す、first: 選択されているタブのみ）")
+	rootCmd.Flags().BoolVar(&downloadImages, "download-images", false, "ページの本文が参照する開始URLと同じホストの画像を <出力ファイル名>_assets/images に保存し、本文の画像の参照を保存したファイルに書き換える")
+	rootCmd.Flags().BoolVar(&allowImageCDNs, "allow-image-cdns", false, "--download-images でよく使われるCDN（jsdelivr.net、cloudfront.net、githubusercontent.com など）の画像も保存する")
+	rootCmd.Flags().BoolVar(&includePDFs, "include-pdfs", false, "リンク先のPDFを出力ファイルの横の <出力ファイル名>_assets ディレクトリに保存し、本文のテキストを抽出してページとして含める")
+	rootCmd.Flags().BoolVar(&discoverRoot, "discover-root", false, "開始ページのサイドバーやパンくずリストからドキュメントのルートを探し、その配下をクロール範囲とする（クロールは指定したページから始める）")
+	rootCmd.Flags().StringVar(&scopeRoot, "scope-root", "", "クロール範囲とするルートのURL（例: https://example.com/docs/）。指定した場合は --discover-root で探さない")
+	rootCmd.Flags().IntVar(&trapMaxRepeats, "trap-max-repeats", crawler.DefaultTrapMaxRepeats, "1つのURLで同じパスの要素（/tag/a/tag/b/... の tag など）が繰り返してよい回数。超えるURLはたどらない（0で判定しない）")
+	rootCmd.Flags().IntVar(&trapMaxVariants, "trap-max-variants", crawler.DefaultTrapMaxVariants, "数字の要素やクエリの値だけが異なるURL（ファセット検索など）が伸び続けてよい回数の上限（0で判定しない）")
+	rootCmd.Flags().StringVar(&maxBandwidth, "max-bandwidth", "", "すべてのリクエストを合わせた受信速度の上限（例: 500KB/s、2MB/s、10Mbit/s）")
+	rootCmd.Flags().IntVar(&breakerWindow, "breaker-window", crawler.DefaultBreakerWindow, "5xxやタイムアウトの割合を計算する直近のリクエスト数（0でリクエストを一時停止しない）")
+	rootCmd.Flags().Float64Var(&breakerThreshold, "breaker-threshold", crawler.DefaultBreakerThreshold, "直近のリクエストのうち5xxやタイムアウトの割合がこれ以上になったらすべてのリクエストを一時停止する")
+	rootCmd.Flags().Float64Var(&breakerCooldown, "breaker-cooldown", crawler.DefaultBreakerCooldown.Seconds(), "リクエストを一時停止する時間（秒）。その後1件のリクエストで回復を確認してから再開する")
+	rootCmd.Flags().IntVar(&retries, "retries", crawler.DefaultRetries, "タイムアウトや接続の切断など一時的なネットワークエラーで再試行する回数（HTTPエラーや証明書のエラーは再試行しない）")
+	rootCmd.Flags().Float64Var(&authAbortRatio, "auth-abort-ratio", crawler.DefaultAuthAbortRatio, "401・403が返されたリクエストの割合がこれを超えたら認証が必要と判断してクロールを中止する（0で中止しない）")
+	rootCmd.Flags().StringVar(&loginURL, "login-url", "", "クロール開始前にフォームログインするURL")
+	rootCmd.Flags().StringVar(&sessionFile, "session-file", "", "Cookieを保存・復元するファイル。開始時に読み込み、セッションが有効なら --login-url のログインを省略し、終了時に保存する（秘密情報を含むため権限は0600）")
+	rootCmd.Flags().StringArrayVar(&loginFields, "login-field", nil, "ログインフォームで送信する値（\"key=value\"、複数指定可、${NAME}で環境変数を参照）")
+	rootCmd.Flags().StringVar(&loginSuccessRegex, "login-success-regex", "", "ログイン成功時のレスポンス本文に一致する正規表現（省略時はステータスコードで判定）")
+	rootCmd.Flags().StringVar(&proxyFlag, "proxy", "", "経由するプロキシのURL（http://、https://、socks5://）")
+	rootCmd.Flags().BoolVar(&noEnvProxy, "no-env-proxy", false, "HTTP_PROXY / HTTPS_PROXY / NO_PROXY 環境変数を無視する")
+	rootCmd.Flags().StringVar(&checkpointPath, "checkpoint", "", "クロール状態を定期的に保存するチェックポイントファイルのパス")
+	rootCmd.Flags().BoolVar(&resume, "resume", false, "--checkpoint のファイルから中断したクロールを再開")
+	rootCmd.Flags().StringVar(&retryFailedPath, "retry-failed", "", "--errors-out で書き出した一覧のURLのみを取得し直し、--checkpoint の前回のページと合わせて出力する（一覧はまだ失敗しているURLで更新する）")
+	rootCmd.MarkFlagsMutuallyExclusive("retry-failed", "resume")
+
+	rootCmd.Flags().BoolVar(&noContentDedup, "no-content-dedup", false, "同じ内容のページの重複排除を無効にする")
+	rootCmd.Flags().IntVar(&nearDupThreshold, "near-dup-threshold", crawler.DefaultNearDupThreshold, "本文のsimhashのハミング距離がこれ未満のページをほぼ同じ内容とみなす（0で判定しない）")
+	rootCmd.Flags().StringVar(&nearDupAction, "near-dup-action", string(crawler.NearDupAlias), "ほぼ同じ内容のページの扱い（alias: 既存のページの別URLとして記録、skip: 収集しない）")
+
+	rootCmd.Flags().BoolVar(&showStatus, "show-status", false, "各ページのヘッダーにHTTPステータスコードを表示する")
+	rootCmd.Flags().Float64Var(&maxBodySizeMB, "max-body-size", float64(crawler.DefaultMaxBodySize)/(1<<20), "1ページあたりのレスポンスボディの上限（MB、0で無制限）")
+	rootCmd.Flags().BoolVar(&skipOversized, "skip-oversized", false, "上限を超えたページを切り詰めずにスキップする")
+	rootCmd.Flags().BoolVar(&precheck, "precheck", false, "GETの前にHEADリクエストを送り、HTMLではないページや上限を超えるページをスキップする")
+	rootCmd.Flags().BoolVar(&sitemapOnly, "sitemap-only", false, "リンクをたどらず、サイトマップに記載されたページのみを取得する（深度は無視される）")
+	rootCmd.Flags().StringVar(&cacheDir, "cache-dir", "", "レスポンスをキャッシュするディレクトリ（次回以降は変更のないページを再取得しない）")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "キャッシュを読まずにすべて取得し直す（キャッシュの更新は行う）")
+	rootCmd.Flags().StringVar(&diffAgainst, "diff-against", "", "前回のクロールのインデックスファイル（<出力ファイル>.index.json）と比較して変化を表示する")
+	rootCmd.Flags().BoolVar(&changedOnly, "changed-only", false, "--diff-against と比較して追加・変更されたページと削除されたURLの一覧のみを出力する")
+	rootCmd.Flags().StringVar(&errorsOut, "errors-out", "", "取得に失敗したURLの一覧を書き出すファイル（タブ区切り）")
+	rootCmd.Flags().BoolVar(&keepVariants, "keep-variants", false, "印刷用・AMP・モバイル版のページ（?print=1 や /amp/ など）もたどる")
+	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "デバッグ用のログ（ページごとのサイズなど）も標準エラー出力に表示する")
+	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "警告とエラーのみを標準エラー出力に表示する")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "出力を生成せず、取得対象のURLと深度を一覧表示する")
+	rootCmd.Flags().BoolVar(&checkMode, "check", false, "出力を生成せず、クロールしたページのリンクのうち4xx・5xxを返すものや接続できないものをページごとに表示する（見つかった場合は終了コード3）")
+	rootCmd.Flags().BoolVar(&checkExternal, "check-external", false, "--check で開始URLと別のホストへのリンクも確認する")
+	rootCmd.Flags().IntVar(&checkConcurrency, "check-concurrency", crawler.DefaultCheckConcurrency, "--check でクロールしなかったリンクの確認に同時に送るリクエストの数")
+	rootCmd.MarkFlagsMutuallyExclusive("check", "dry-run")
+	rootCmd.Flags().StringVar(&saveHTMLDir, "save-html", "", "取得したページの元のHTMLをURLのパスに対応するファイルとして保存するディレクトリ")
+	rootCmd.Flags().StringSliceVar(&skipExts, "skip-ext", nil, "リンクをたどらない拡張子を追加する（例: .csv、複数指定またはカンマ区切り）")
+	rootCmd.Flags().StringSliceVar(&allowExts, "allow-ext", nil, "デフォルトでたどらない拡張子（画像、アーカイブ、動画、フォントなど）のうちたどるものを指定する")
+	rootCmd.Flags().BoolVar(&stripQuery, "strip-query", false, "リンクのクエリ文字列をすべて取り除く（--keep-param で指定したものは残す）")
+	rootCmd.Flags().StringSliceVar(&keepParams, "keep-param", nil, "取り除かずに残すクエリパラメータ（例: page、複数指定またはカンマ区切り）")
+	rootCmd.Flags().StringSliceVar(&stripParams, "strip-param", nil, "utm_* などのデフォルトに加えて取り除くクエリパラメータ（例: sessionid、複数指定またはカンマ区切り、--keep-param が優先）")
+	rootCmd.Flags().StringVar(&urlListPath, "url-list", "", "リンクをたどらず、ファイルに1行ずつ記載したURLのみを記載順に取得する（#で始まる行は無視）")
+	rootCmd.Flags().StringVar(&localDir, "local", "", "ネットワークの代わりにディスク上に保存したサイト（wgetなどのミラー）のディレクトリから取得する")
+	rootCmd.Flags().StringVar(&localBase, "local-base", "", "--local のページのURLとして扱うベースURL（省略時は file:// のURL）")
+	rootCmd.Flags().StringVar(&langFilter, "lang-filter", "", "収集する言語（例: en、ja、pt-br）。htmlのlang属性が一致しないページと /ja/ などの言語のパスが一致しないリンクをスキップする")
+	rootCmd.Flags().BoolVar(&noIframes, "no-iframes", false, "iframe・frameで埋め込まれたページを取得しない（デフォルトでは埋め込み元と同じ深度で取得する）")
+	rootCmd.Flags().StringVar(&linksFrom, "links-from", "", "リンクを収集する要素のCSSセレクタ（例: \"nav, .sidebar, .toc\"）。一致する要素がないページではページ全体から収集する")
+	rootCmd.Flags().StringVar(&contentSelector, "selector", "", "本文を抽出する要素のCSSセレクタ（例: \"article.markdown-body\"）。一致する最初の要素のみから抽出し、一致しないページでは本文の要素を推定する")
+	rootCmd.Flags().BoolVar(&autoContent, "auto-content", false, "本文の要素を推定できないページでbody全体の代わりに、段落の文字数やリンクの割合、class・idの名前から本文らしい要素を選んで抽出する")
+	rootCmd.Flags().StringArrayVar(&excludeSelectors, "exclude-selector", nil, "本文の抽出の前に取り除く要素のCSSセレクタ（複数指定可、例: \".edit-link, .feedback\"）。リンクの収集には影響しない")
+	rootCmd.Flags().BoolVar(&noDefaultExcludes, "no-default-excludes", false, "デフォルトで取り除くナビゲーション、ヘッダー、フッター、サイドバーなどの要素を本文に残す")
+	rootCmd.Flags().StringVar(&depthModeName, "depth-mode", string(crawler.DepthHops), "--depth の深度の数え方（hops: 開始URLからたどったリンクの数、path: 開始URLのディレクトリからのURLのパスの階層。path ではクロール順によらず対象のページが決まる）")
+	rootCmd.Flags().BoolVar(&failFast, "fail-fast", false, "いずれかのページの取得に失敗した時点でクロールを中止する（取得済みのページは出力し、エラーで終了する）")
+	rootCmd.Flags().IntVar(&maxPagination, "max-pagination", crawler.DefaultMaxPagination, "rel=\"next\" のページ送りを深度を増やさずに連続してたどるページ数の上限（0で無制限）")
+	rootCmd.Flags().StringArrayVar(&skipTitleRegexes, "skip-title-regex", nil, "タイトルが正規表現に一致するページを収集しない（複数指定可、大文字と小文字を区別しない）")
+	rootCmd.Flags().BoolVar(&skipTitleFollow, "skip-title-follow", false, "--skip-title-regex で収集しなかったページのリンクもたどる")
+	rootCmd.Flags().BoolVar(&preferOGTitle, "prefer-og-title", false, "ページのタイトルに og:title があれば <title>（「| サイト名」が付くことが多い）より優先して使う")
+	rootCmd.Flags().StringArrayVar(&soft404Patterns, "soft-404-pattern", nil, "ステータスコード200の「ページが見つかりません」のページと判定するタイトルや本文の正規表現（複数指定可、省略時は 404、page not found、not found など）")
+	rootCmd.Flags().IntVar(&soft404MaxChars, "soft-404-max-chars", crawler.DefaultSoft404MaxChars, "パターンに一致したページをソフト404と判定する本文の最大文字数（0で判定しない）")
+	rootCmd.Flags().StringVar(&pageOrder, "order", "crawl", "出力するページの順序（crawl: クロール順、nav: 開始ページのナビゲーションの順で、ナビゲーションにないページはその後にパスごとにまとめる）")
+	rootCmd.Flags().StringVar(&navSelector, "nav-selector", crawler.DefaultNavSelector, "--order nav でナビゲーションの順序を取得する要素のCSSセレクタ（例: \".sidebar\"）")
+	rootCmd.Flags().BoolVar(&navEveryPage, "nav-every-page", false, "--order nav で開始ページ以外のページのナビゲーションの順序も使う")
+	rootCmd.Flags().BoolVar(&groupBySeed, "group-by-seed", false, "出力を開始URLごとにまとめる（デフォルトはクロール順）")
+	rootCmd.Flags().BoolVar(&groupBySection, "group-by-section", false, "出力をパンくずリストの階層（Guides > Deployment など。パンくずリストのないページはURLパスの階層）ごとにまとめ、セクションの見出しを書き出す。--order nav と併せて指定した場合はナビゲーションの順に最初に現れた順にセクションを並べる。--group-by-seed とは同時に指定できない")
+	rootCmd.Flags().BoolVar(&useUTC, "utc", false, "出力の日時をローカルタイムゾーンではなくUTCで表示する")
 
-	rootCmd.MarkFlagRequired("url")
+	rootCmd.MarkFlagsOneRequired("url", "url-list", "local")
+	rootCmd.MarkFlagsMutuallyExclusive("url", "local")
+	rootCmd.MarkFlagsMutuallyExclusive("url-list", "local")
+	rootCmd.MarkFlagsMutuallyExclusive("retry-failed", "url-list")
 }
@@ -0,0 +1,348 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/yugo-ibuki/docrawl/output"
+)
+
+// fixtureSite はリンクでつながったドキュメントサイトを返すテスト用のサーバーを起動する
+func fixtureSite(t *testing.T, pages map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// runRoot はコマンドライン引数を指定して docrawl を実行する
+// 前の実行で指定したフラグの値が残らないよう、すべてのフラグをデフォルト値に戻してから実行する
+func runRoot(t *testing.T, args ...string) error {
+	t.Helper()
+	rootCmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else if err := f.Value.Set(f.DefValue); err != nil {
+			t.Fatalf("--%s をデフォルト値に戻せません: %v", f.Name, err)
+		}
+		f.Changed = false
+	})
+	rootCmd.SetArgs(args)
+	return rootCmd.Execute()
+}
+
+// mockWriter は受け取ったクロール結果を記録するStreamWriter
+type mockWriter struct {
+	mu    sync.Mutex
+	opts  output.Options
+	meta  output.CrawlMeta
+	pages []output.Page
+	calls []string
+}
+
+func (w *mockWriter) Write(result *output.CrawlResult) error {
+	return output.Write(w, result)
+}
+
+func (w *mockWriter) Begin(meta output.CrawlMeta) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.meta = meta
+	w.calls = append(w.calls, "begin")
+	return nil
+}
+
+func (w *mockWriter) WritePage(page output.Page) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pages = append(w.pages, page)
+	w.calls = append(w.calls, "page")
+	return nil
+}
+
+func (w *mockWriter) End() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.calls = append(w.calls, "end")
+	return nil
+}
+
+func TestCustomWriterEndToEnd(t *testing.T) {
+	server := fixtureSite(t, map[string]string{
+		"/docs/": `<html><head><title>Docs</title></head><body><main>
+			<h1 id="top">Docs</h1><p>トップページ</p>
+			<a href="/docs/install">Install</a> <a href="/docs/usage">Usage</a>
+		</main></body></html>`,
+		"/docs/install": `<html><head><title>Install</title><meta name="description" content="インストール方法"></head><body><main>
+			<h1 id="install">Install</h1><h3 id="npm">npm</h3><pre><code>npm install docrawl</code></pre>
+		</main></body></html>`,
+		"/docs/usage": `<html><head><title>Usage</title></head><body><main>
+			<h1>Usage</h1><p>使い方</p>
+		</main></body></html>`,
+	})
+
+	writer := &mockWriter{}
+	err := output.RegisterFormat("mock", func(opts output.Options) (output.Writer, error) {
+		writer.opts = opts
+		return writer, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := output.RegisterFormat("txt", func(output.Options) (output.Writer, error) { return writer, nil }); err == nil {
+		t.Error("組み込みの形式と同じ名前を登録できました")
+	}
+
+	outPath := filepath.Join(t.TempDir(), "docs")
+	if err := runRoot(t, "-u", server.URL+"/docs/", "-f", "mock", "-o", outPath, "-w", "0", "-q"); err != nil {
+		t.Fatalf("クロールに失敗しました: %v", err)
+	}
+
+	if writer.opts.OutputPath != outPath {
+		t.Errorf("Writerに渡された出力パス = %q, want %q", writer.opts.OutputPath, outPath)
+	}
+	wantCalls := "begin page page page end"
+	if got := strings.Join(writer.calls, " "); got != wantCalls {
+		t.Errorf("StreamWriterの呼び出し = %q, want %q", got, wantCalls)
+	}
+	if writer.meta.BaseURL != server.URL+"/docs/" || writer.meta.PageCount != 3 {
+		t.Errorf("メタ情報 = %+v", writer.meta)
+	}
+
+	byURL := make(map[string]output.Page)
+	for _, page := range writer.pages {
+		byURL[strings.TrimPrefix(page.URL, server.URL)] = page
+	}
+	install, ok := byURL["/docs/install"]
+	if !ok {
+		t.Fatalf("/docs/install が出力されていません: %v", byURL)
+	}
+	if install.Title != "Install" || install.Depth != 1 || install.StatusCode != http.StatusOK || install.Description != "インストール方法" {
+		t.Errorf("ページの情報 = %+v", install)
+	}
+	if !strings.Contains(install.Content, "npm install docrawl") {
+		t.Errorf("本文にコードが含まれていません:\n%s", install.Content)
+	}
+	if len(install.Outline) != 1 || install.Outline[0].Anchor != "install" ||
+		len(install.Outline[0].Children) != 1 || install.Outline[0].Children[0].Text != "npm" {
+		t.Errorf("見出しの階層 = %+v", install.Outline)
+	}
+}
+
+func TestNotifyInterruptCancelsContext(t *testing.T) {
+	ctx, stop := notifyInterrupt()
+	defer stop()
+
+	// 自身へのシグナルの送信に対応していない環境では確認できない
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := process.Signal(os.Interrupt); err != nil {
+		t.Skipf("SIGINTを送信できません: %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGINTでコンテキストがキャンセルされませんでした")
+	}
+}
+
+// codeIndentPage はPythonとYAMLのコード例を含むページ（YAMLはすべての行が4文字の字下げで始まる）
+const codeIndentPage = `<html><head><title>Config</title></head><body><main>
+<h1>Config</h1>
+<p>ハンドラーの例:</p>
+<div class="highlight"><pre><code class="language-python">def handler(event):
+    if event.get("retry"):
+        for attempt in range(3):
+            print(f"attempt {attempt}")    # 3回まで
+    return {"ok":  True}
+</code></pre></div>
+<p>設定ファイルの例:</p>
+<pre><code class="language-yaml">    server:
+      port: 8080        # 待ち受けるポート
+      hosts:
+        - a.example.com
+        - b.example.com
+</code></pre>
+</main></body></html>`
+
+const (
+	pythonSample = "```python\n" +
+		"def handler(event):\n" +
+		"    if event.get(\"retry\"):\n" +
+		"        for attempt in range(3):\n" +
+		"            print(f\"attempt {attempt}\")    # 3回まで\n" +
+		"    return {\"ok\":  True}\n" +
+		"```"
+	yamlSample = "```yaml\n" +
+		"    server:\n" +
+		"      port: 8080        # 待ち受けるポート\n" +
+		"      hosts:\n" +
+		"        - a.example.com\n" +
+		"        - b.example.com\n" +
+		"```"
+	yamlDedented = "```yaml\n" +
+		"server:\n" +
+		"  port: 8080        # 待ち受けるポート\n" +
+		"  hosts:\n" +
+		"    - a.example.com\n" +
+		"    - b.example.com\n" +
+		"```"
+)
+
+// TestCodeIndentationEndToEnd はコードブロックの字下げと連続する空白が出力ファイルまでそのまま残ることを確認する
+func TestCodeIndentationEndToEnd(t *testing.T) {
+	server := fixtureSite(t, map[string]string{"/docs/config": codeIndentPage})
+	dir := t.TempDir()
+
+	for _, tt := range []struct {
+		name   string
+		args   []string
+		output string
+		want   []string
+	}{
+		{"txt", []string{"-f", "txt", "-o", filepath.Join(dir, "docs.txt")}, "docs.txt", []string{pythonSample, yamlSample}},
+		{"pdf", []string{"-f", "pdf", "-o", filepath.Join(dir, "docs.pdf")}, "docs.txt", []string{pythonSample, yamlSample}},
+		{"dedent", []string{"-f", "txt", "-o", filepath.Join(dir, "dedent.txt"), "--dedent-code"}, "dedent.txt", []string{pythonSample, yamlDedented}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-u", server.URL + "/docs/config", "-w", "0", "-q"}, tt.args...)
+			if err := runRoot(t, args...); err != nil {
+				t.Fatalf("クロールに失敗しました: %v", err)
+			}
+			data, err := os.ReadFile(filepath.Join(dir, tt.output))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("コードブロックが元の字下げのまま出力されていません\nwant:\n%s\ngot:\n%s", want, data)
+				}
+			}
+		})
+	}
+}
+
+// quotedCodePage は引用と注記の中にPythonとYAMLのコード例を含むページ
+const quotedCodePage = `<html><head><title>Quoted</title></head><body><main>
+<h1>Quoted</h1>
+<blockquote><p>ハンドラーの例:</p>
+<pre><code class="language-python">def handler(event):
+    if event.get("retry"):
+        for attempt in range(3):
+            print(f"attempt {attempt}")    # 3回まで
+    return {"ok":  True}
+</code></pre></blockquote>
+<div class="admonition note"><p class="admonition-title">Note</p>
+<pre><code class="language-yaml">    server:
+      port: 8080        # 待ち受けるポート
+      hosts:
+        - a.example.com
+        - b.example.com
+</code></pre></div>
+</main></body></html>`
+
+// quoteLines は各行の先頭に引用の記号を付ける
+func quoteLines(s string) string {
+	return "> " + strings.ReplaceAll(s, "\n", "\n> ")
+}
+
+// TestQuotedCodeIndentationEndToEnd は引用や注記の中のコードブロックの字下げも出力ファイルまでそのまま残ることを確認する
+func TestQuotedCodeIndentationEndToEnd(t *testing.T) {
+	server := fixtureSite(t, map[string]string{"/docs/quoted": quotedCodePage})
+	dir := t.TempDir()
+
+	for _, tt := range []struct {
+		name   string
+		args   []string
+		output string
+	}{
+		{"txt", []string{"-f", "txt", "-o", filepath.Join(dir, "quoted.txt")}, "quoted.txt"},
+		{"pdf", []string{"-f", "pdf", "-o", filepath.Join(dir, "quoted-pdf.pdf")}, "quoted-pdf.txt"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-u", server.URL + "/docs/quoted", "-w", "0", "-q"}, tt.args...)
+			if err := runRoot(t, args...); err != nil {
+				t.Fatalf("クロールに失敗しました: %v", err)
+			}
+			data, err := os.ReadFile(filepath.Join(dir, tt.output))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{quoteLines(pythonSample), quoteLines(yamlSample)} {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("引用の中のコードブロックが元の字下げのまま出力されていません\nwant:\n%s\ngot:\n%s", want, data)
+				}
+			}
+		})
+	}
+}
+
+// TestAuthPassEnv は環境変数 DOCRAWL_AUTH_PASS が --auth-user を指定した場合のみ使われることを確認する
+func TestAuthPassEnv(t *testing.T) {
+	var mu sync.Mutex
+	var gotUser, gotPass string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gotUser, gotPass, _ = r.BasicAuth()
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Docs</title></head><body><main><h1>Docs</h1><p>本文</p></main></body></html>`))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("DOCRAWL_AUTH_PASS", "secret")
+	outPath := filepath.Join(t.TempDir(), "docs.txt")
+
+	// --auth-user がない場合は環境変数を無視して認証なしでクロールする
+	if err := runRoot(t, "-u", server.URL+"/docs/", "-f", "txt", "-o", outPath, "-w", "0", "-q"); err != nil {
+		t.Fatalf("--auth-user なしのクロールに失敗しました: %v", err)
+	}
+	if gotUser != "" || gotPass != "" {
+		t.Errorf("認証情報が送信されました: %q / %q", gotUser, gotPass)
+	}
+
+	if err := runRoot(t, "-u", server.URL+"/docs/", "-f", "txt", "-o", outPath, "-w", "0", "-q", "--auth-user", "alice"); err != nil {
+		t.Fatalf("--auth-user ありのクロールに失敗しました: %v", err)
+	}
+	if gotUser != "alice" || gotPass != "secret" {
+		t.Errorf("Basic認証 = %q / %q, want \"alice\" / \"secret\"", gotUser, gotPass)
+	}
+
+	if err := runRoot(t, "-u", server.URL+"/docs/", "-f", "txt", "-o", outPath, "-w", "0", "-q", "--auth-pass", "x"); err == nil {
+		t.Error("--auth-user なしの --auth-pass がエラーになりませんでした")
+	}
+}
+
+// TestTokenEnvWithBasicAuth は環境変数 DOCRAWL_TOKEN と --auth-user の併用がエラーになることを確認する
+func TestTokenEnvWithBasicAuth(t *testing.T) {
+	server := fixtureSite(t, map[string]string{
+		"/docs/": `<html><head><title>Docs</title></head><body><main><h1>Docs</h1><p>本文</p></main></body></html>`,
+	})
+	t.Setenv("DOCRAWL_TOKEN", "abc")
+	outPath := filepath.Join(t.TempDir(), "docs.txt")
+
+	err := runRoot(t, "-u", server.URL+"/docs/", "-f", "txt", "-o", outPath, "-w", "0", "-q", "--auth-user", "alice")
+	if err == nil || !strings.Contains(err.Error(), "DOCRAWL_TOKEN") {
+		t.Errorf("DOCRAWL_TOKEN と --auth-user の併用のエラー = %v", err)
+	}
+	// Basic認証を指定しない場合は環境変数のトークンを使う
+	if err := runRoot(t, "-u", server.URL+"/docs/", "-f", "txt", "-o", outPath, "-w", "0", "-q"); err != nil {
+		t.Errorf("DOCRAWL_TOKEN のみのクロールに失敗しました: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package crawler
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// admonitionContainers は注記（note・warningなど）の囲みを表すクラス
+// MkDocs・Sphinxの admonition、Docusaurusの theme-admonition、GitHubの markdown-alert、Bootstrapの alert など
+var admonitionContainers = map[string]bool{
+	"admonition": true, "theme-admonition": true, "markdown-alert": true, "callout": true, "alert": true,
+}
+
+// admonitionTitleClasses は注記の見出しの要素を表すクラスの接頭辞
+var admonitionTitleClasses = []string{
+	"admonition-title", "admonition-heading", "admonitionheading", "markdown-alert-title", "callout-title", "alert-heading",
+}
+
+// admonitionPrefixes は注記の種類を表すクラスの接頭辞
+var admonitionPrefixes = []string{"theme-admonition-", "admonition-", "markdown-alert-", "callout-", "alert--", "alert-"}
+
+// admonitionLabels は注記の種類と書き出すラベル
+var admonitionLabels = map[string]string{
+	"note": "Note", "info": "Info", "tip": "Tip", "hint": "Hint", "important": "Important",
+	"warning": "Warning", "warn": "Warning", "caution": "Caution", "danger": "Danger", "error": "Error",
+	"attention": "Attention", "success": "Success", "example": "Example", "question": "Question",
+	"abstract": "Abstract", "bug": "Bug", "failure": "Failure", "seealso": "See also", "todo": "Todo",
+}
+
+// asideKinds はクラスの一部に含まれていればaside要素を注記とみなす種類
+var asideKinds = []string{"note", "tip", "info", "warning", "caution", "danger", "important", "hint"}
+
+// defaultAdmonitionLabel は種類が分からない注記のラベル
+const defaultAdmonitionLabel = "Note"
+
+// admonition は要素が注記の囲みであれば、種類のラベルと見出しの要素を返す
+// 種類はクラス（warning、admonition-warning、alert--warning など）、なければ見出しのテキストから判定する
+func admonition(n *html.Node) (label string, title *html.Node, ok bool) {
+	classes := strings.Fields(strings.ToLower(attr(n, "class")))
+	switch n.Data {
+	case "div", "section":
+		for _, class := range classes {
+			ok = ok || admonitionContainers[class]
+		}
+	case "details":
+		// MkDocs-materialの折りたたみ（details.note など）はクラスの種類のみで判定する
+		ok = admonitionKind(classes) != ""
+	case "aside":
+		// aside.note-box のようにクラスの一部に種類を含むものも注記とする
+		ok = asideKind(classes) != ""
+	}
+	if !ok {
+		return "", nil, false
+	}
+
+	title = admonitionTitle(n)
+	label = admonitionLabels[admonitionKind(classes)]
+	if label == "" && n.Data == "aside" {
+		label = admonitionLabels[asideKind(classes)]
+	}
+	if label == "" && title != nil {
+		label = admonitionLabels[strings.ToLower(strings.TrimSpace(textContent(title)))]
+	}
+	if label == "" {
+		label = defaultAdmonitionLabel
+	}
+	return label, title, true
+}
+
+// admonitionKind はクラスから注記の種類を返す（分からない場合は空）
+func admonitionKind(classes []string) string {
+	for _, class := range classes {
+		if _, ok := admonitionLabels[class]; ok {
+			return class
+		}
+		for _, prefix := range admonitionPrefixes {
+			if kind, ok := strings.CutPrefix(class, prefix); ok {
+				if _, known := admonitionLabels[kind]; known {
+					return kind
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// asideKind はクラスの一部に含まれる注記の種類を返す（ない場合は空）
+func asideKind(classes []string) string {
+	for _, kind := range asideKinds {
+		for _, class := range classes {
+			if strings.Contains(class, kind) {
+				return kind
+			}
+		}
+	}
+	return ""
+}
+
+// admonitionTitle は注記の見出しの要素（admonition-title のクラスの要素や details の summary）を返す
+func admonitionTitle(n *html.Node) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if c.Data == "summary" {
+			return c
+		}
+		for _, class := range strings.Fields(strings.ToLower(attr(c, "class"))) {
+			for _, prefix := range admonitionTitleClasses {
+				if strings.HasPrefix(class, prefix) {
+					return c
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// admonitionBlock は注記を「> **Warning:** 本文」の形の引用に変換する
+// 見出しが種類の名前と異なる場合（独自の見出し）はラベルに続けて見出しも書き出す
+func (m *markdownConverter) admonitionBlock(n *html.Node, label string, title *html.Node) string {
+	heading := "**" + label + ":**"
+	if title != nil {
+		if text := strings.ReplaceAll(m.inlineText(title), "\\\n", " "); text != "" && !strings.EqualFold(strings.TrimSpace(textContent(title)), label) {
+			heading = "**" + label + ": " + text + "**"
+		}
+	}
+
+	outer := m.skip
+	m.skip = title
+	blocks := m.blocks(n)
+	m.skip = outer
+
+	// 本文の最初のブロックが段落であればラベルを同じ行に続ける
+	if len(blocks) > 0 && isParagraph(blocks[0].text) {
+		blocks[0].text = heading + " " + blocks[0].text
+	} else {
+		blocks = append([]mdBlock{{text: heading}}, blocks...)
+	}
+	return m.quoteBlocks(blocks)
+}
+
+// isParagraph はMarkdownのブロックが見出し・リスト・コードブロック・引用・表ではない段落かを判定する
+func isParagraph(block string) bool {
+	for _, prefix := range []string{"#", "* ", "```", "~~~", ">", "|", "**"} {
+		if strings.HasPrefix(block, prefix) {
+			return false
+		}
+	}
+	first, _, _ := strings.Cut(block, " ")
+	return !strings.HasSuffix(first, ".") || strings.Trim(first, "0123456789.") != ""
+}
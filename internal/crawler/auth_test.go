@@ -0,0 +1,116 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCrawlBearerToken(t *testing.T) {
+	const token = "s3cret-token"
+	pages := map[string]string{
+		"/":      htmlPage("Top", "top page", "/guide"),
+		"/guide": htmlPage("Guide", "guide page"),
+	}
+	site := newTestSite(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(body))
+	})
+
+	t.Run("トークンあり", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		c := New(site.URL+"/", 3, 10, 0, 0, WithLogger(logger), WithBearerToken(token))
+		result, err := c.Crawl(context.Background())
+		if err != nil {
+			t.Fatalf("Crawl: %v", err)
+		}
+		if got := strings.Join(pagePaths(site, result.Pages), ","); got != "/,/guide" {
+			t.Errorf("取得したページ = %s, want /,/guide", got)
+		}
+		if strings.Contains(logs.String(), token) {
+			t.Error("ログにトークンが出力されています")
+		}
+	})
+
+	t.Run("トークンなし", func(t *testing.T) {
+		c := New(site.URL+"/", 3, 10, 0, 0, WithLogger(discardLogger()))
+		result, err := c.Crawl(context.Background())
+		if err == nil {
+			t.Fatal("認証が必要なサイトの取得がエラーになりませんでした")
+		}
+		if result != nil && len(result.Pages) != 0 {
+			t.Errorf("取得したページ = %v", pagePaths(site, result.Pages))
+		}
+	})
+}
+
+// consentSite は consent=yes のCookieがないリクエストを同意ページへリダイレクトするサイトを起動する
+// setOnTop が true の場合は開始ページの応答でCookieを設定する
+func consentSite(t *testing.T, setOnTop bool) *testSite {
+	t.Helper()
+	pages := map[string]string{
+		"/":      htmlPage("Top", "top page", "/guide"),
+		"/guide": htmlPage("Guide", "guide page"),
+	}
+	return newTestSite(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/consent" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(htmlPage("Consent", "accept cookies")))
+			return
+		}
+		if setOnTop && r.URL.Path == "/" {
+			http.SetCookie(w, &http.Cookie{Name: "consent", Value: "yes", Path: "/"})
+		} else if cookie, err := r.Cookie("consent"); err != nil || cookie.Value != "yes" {
+			http.Redirect(w, r, "/consent", http.StatusFound)
+			return
+		}
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(body))
+	})
+}
+
+func TestCrawlCookies(t *testing.T) {
+	tests := []struct {
+		name     string
+		setOnTop bool
+		opts     []Option
+	}{
+		{name: "指定したCookie", opts: []Option{WithCookies([]*http.Cookie{{Name: "consent", Value: "yes"}})}},
+		{name: "Set-Cookie", setOnTop: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := consentSite(t, tt.setOnTop)
+
+			c := New(site.URL+"/", 3, 10, 0, 0, append([]Option{WithLogger(discardLogger())}, tt.opts...)...)
+			result, err := c.Crawl(context.Background())
+			if err != nil {
+				t.Fatalf("Crawl: %v", err)
+			}
+			if got := strings.Join(pagePaths(site, result.Pages), ","); got != "/,/guide" {
+				t.Errorf("取得したページ = %s, want /,/guide", got)
+			}
+			if n := site.count("/consent"); n != 0 {
+				t.Errorf("同意ページへ %d 回リダイレクトされました", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// bandwidthUnits は帯域の単位と1秒あたりのバイト数への換算（小文字で、長いものから照合する）
+var bandwidthUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"gbit", 1e9 / 8}, {"mbit", 1e6 / 8}, {"kbit", 1e3 / 8},
+	{"gbps", 1e9 / 8}, {"mbps", 1e6 / 8}, {"kbps", 1e3 / 8},
+	{"gib", 1 << 30}, {"mib", 1 << 20}, {"kib", 1 << 10},
+	{"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10},
+	{"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10},
+	{"b", 1},
+}
+
+// ParseBandwidth は "500KB/s" や "2Mbit/s" のような帯域の指定を1秒あたりのバイト数に変換する
+// KB・MB は1024倍、kbit・Mbit（kbps・Mbps）は1000倍のビットとして扱い、単位のない数値はバイトとみなす
+// 大文字と小文字は区別しないため、"Mb" は MB と同じメガバイトになる（メガビットは Mbit か Mbps で指定する）
+func ParseBandwidth(s string) (int64, error) {
+	spec := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	spec = strings.TrimSuffix(strings.TrimSuffix(spec, "/s"), "/sec")
+
+	number, factor := spec, 1.0
+	for _, unit := range bandwidthUnits {
+		if strings.HasSuffix(spec, unit.suffix) {
+			number, factor = strings.TrimSuffix(spec, unit.suffix), unit.bytes
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("帯域の指定が正しくありません（例: 500KB/s、2MB/s、10Mbit/s）: %s", s)
+	}
+	rate := int64(value * factor)
+	if rate < 1 {
+		return 0, fmt.Errorf("帯域が小さすぎます: %s", s)
+	}
+	return rate, nil
+}
+
+// bandwidthLimiter はすべてのリクエストで共有する受信速度の制限
+// 読み込んだバイト数に応じた時間を共通の時間軸に予約するため、並行して読み込んでも合計の速度が上限を超えない
+type bandwidthLimiter struct {
+	rate int64 // 1秒あたりのバイト数
+	mu   sync.Mutex
+	next time.Time // 次に読み込みを再開できる時刻
+}
+
+// Wait は n バイトを読み込んだ分だけ待つ（ctx がキャンセルされた場合はすぐに戻る）
+func (l *bandwidthLimiter) Wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.rate) * float64(time.Second)))
+	at := l.next
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// chunkSize は1回に読み込むバイト数（待機が細かくなるよう約0.1秒分に制限する）
+func (l *bandwidthLimiter) chunkSize() int {
+	size := int(l.rate / 10)
+	if size < 512 {
+		size = 512
+	}
+	return size
+}
+
+// throttledBody は受信したバイト数（展開前）を数え、指定があれば受信速度を制限するレスポンスボディ
+type throttledBody struct {
+	ctx      context.Context
+	body     io.ReadCloser
+	limiter  *bandwidthLimiter // nilの場合は制限しない
+	received *atomic.Int64     // 受信したバイト数の合計
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if b.limiter != nil {
+		if size := b.limiter.chunkSize(); len(p) > size {
+			p = p[:size]
+		}
+	}
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.received.Add(int64(n))
+		if b.limiter != nil {
+			if werr := b.limiter.Wait(b.ctx, n); werr != nil {
+				return n, werr
+			}
+		}
+	}
+	return n, err
+}
+
+func (b *throttledBody) Close() error {
+	return b.body.Close()
+}
+
+// throttledTransport はレスポンスボディの受信したバイト数を数え、指定があれば受信速度を制限するTransport
+// Content-Encodingの展開より前で数えるため、圧縮されたレスポンスは転送されたサイズになる
+type throttledTransport struct {
+	limiter  *bandwidthLimiter // nilの場合は制限しない
+	received *atomic.Int64
+	next     http.RoundTripper
+}
+
+// RoundTrip はリクエストを送信し、レスポンスボディを受信したバイト数を数えるものに置き換える
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &throttledBody{ctx: req.Context(), body: resp.Body, limiter: t.limiter, received: t.received}
+	return resp, nil
+}
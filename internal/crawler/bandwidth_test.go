@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"500KB/s", 500 << 10},
+		{"500kb", 500 << 10},
+		{"2MB/s", 2 << 20},
+		{"1.5 MiB/s", 3 << 19},
+		{"10Mbit/s", 1250000},
+		{"10Mbps", 1250000},
+		{"800kbit/s", 100000},
+		{"1Gbit", 125000000},
+		// 大文字と小文字を区別しないため、"Mb" はメガビットではなくメガバイトになる
+		{"2Mb/s", 2 << 20},
+		{"4096", 4096},
+		{"4096B/s", 4096},
+	}
+	for _, tt := range tests {
+		got, err := ParseBandwidth(tt.in)
+		if err != nil {
+			t.Errorf("ParseBandwidth(%q) がエラーを返しました: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseBandwidth(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseBandwidthInvalid(t *testing.T) {
+	for _, in := range []string{"", "fast", "0KB/s", "-1MB/s", "KB/s", "0.1bit"} {
+		if _, err := ParseBandwidth(in); err == nil {
+			t.Errorf("ParseBandwidth(%q) がエラーを返しませんでした", in)
+		}
+	}
+}
+
+func TestThrottledTransportCountsWireBytes(t *testing.T) {
+	text := bytes.Repeat([]byte("docrawl "), 4096)
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(text)
+	gz.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	var received atomic.Int64
+	client := &http.Client{Transport: &throttledTransport{received: &received, next: &http.Transport{DisableCompression: true}}}
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if got, want := received.Load(), int64(compressed.Len()); got != want {
+		t.Errorf("受信したバイト数 = %d, want %d（展開前のサイズ）", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// 複数のページに共通する定型文（「このページは役に立ちましたか？」やフッターなど）を取り除く設定のデフォルト値
+const (
+	DefaultBoilerplateRatio    = 0.8 // 定型文とみなすブロックを含むページの割合
+	DefaultBoilerplateMaxChars = 200 // 定型文とみなすブロックの最大文字数
+)
+
+// boilerplateMinPages は定型文を判定するのに必要なページ数
+const boilerplateMinPages = 3
+
+// stripBoilerplate は割合 boilerplateRatio 以上のページに同じ内容で現れる短いブロックを各ページの本文から取り除く
+// 空白の違いは無視して比較し、見出しとコードブロックは取り除かない
+// 取り除くと見出し以外のブロックがなくなるページ（トップページなど）はそのままにする
+func (c *Crawler) stripBoilerplate(pages []Page) {
+	if len(pages) < boilerplateMinPages {
+		return
+	}
+
+	counts := make(map[string]int)
+	var order []string
+	for _, page := range pages {
+		seen := make(map[string]bool)
+		for _, block := range splitBlocks(page.Content) {
+			key := boilerplateKey(block)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if counts[key] == 0 {
+				order = append(order, key)
+			}
+			counts[key]++
+		}
+	}
+	boilerplate := make(map[string]bool)
+	for _, key := range order {
+		if float64(counts[key]) >= c.boilerplateRatio*float64(len(pages)) && utf8.RuneCountInString(key) <= c.boilerplateMaxChars {
+			boilerplate[key] = true
+		}
+	}
+	if len(boilerplate) == 0 {
+		return
+	}
+
+	stripped := make(map[string]int)
+	for i, page := range pages {
+		var kept, removed []string
+		body := false
+		for _, block := range splitBlocks(page.Content) {
+			if key := boilerplateKey(block); boilerplate[key] {
+				removed = append(removed, key)
+				continue
+			}
+			kept = append(kept, block)
+			body = body || !strings.HasPrefix(block, "#")
+		}
+		if len(removed) == 0 || !body {
+			continue
+		}
+		for _, key := range removed {
+			stripped[key]++
+		}
+		content := strings.Join(kept, "\n\n")
+		if strings.HasSuffix(page.Content, "\n") {
+			content += "\n"
+		}
+		pages[i].Content = content
+	}
+
+	for _, key := range order {
+		if stripped[key] > 0 {
+			c.logger.Info("複数のページに共通する定型文を取り除きました", "pages", stripped[key], "text", truncateText(key, 80))
+		}
+	}
+}
+
+// boilerplateKey は定型文の判定に使うブロックの内容を返す（見出しとコードブロックは判定しないため空）
+func boilerplateKey(block string) string {
+	if strings.HasPrefix(block, "#") || strings.HasPrefix(block, "```") || strings.HasPrefix(block, "~~~") {
+		return ""
+	}
+	return strings.Join(strings.Fields(block), " ")
+}
+
+// splitBlocks は本文を空行で区切られたブロックに分割する（コードブロックの中の空行では区切らない）
+func splitBlocks(content string) []string {
+	var blocks []string
+	var current []string
+	flush := func() {
+		if block := strings.TrimSpace(strings.Join(current, "\n")); block != "" {
+			blocks = append(blocks, strings.TrimRight(strings.Join(current, "\n"), " \t\n"))
+		}
+		current = current[:0]
+	}
+	lines := strings.Split(content, "\n")
+	code := CodeFenceLines(lines)
+	for i, line := range lines {
+		if !code[i] && strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+	return blocks
+}
+
+// truncateText はログに表示するために文字列を最大 n 文字に切り詰める
+func truncateText(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -0,0 +1,165 @@
+package crawler
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// breadcrumbSelectors はパンくずリストの要素のセレクタ（先に一致したものを使う）
+var breadcrumbSelectors = []string{
+	`nav[aria-label*="breadcrumb"], nav[aria-label*="Breadcrumb"]`,
+	`[itemtype*="BreadcrumbList"]`,
+	`.breadcrumbs`,
+	`.breadcrumb`,
+	`.wy-breadcrumbs`,
+	`.md-path`,
+}
+
+// breadcrumbSeparators はパンくずリストの項目の間の区切り記号
+const breadcrumbSeparators = "›»>/|·•→〉"
+
+// extractBreadcrumbs はページのパンくずリストの項目名を上位の階層から順に返す
+// JSON-LDの BreadcrumbList を優先し、なければパンくずリストの要素の項目から区切り記号を取り除いて使う
+func extractBreadcrumbs(doc *goquery.Document) []string {
+	if trail := jsonLDBreadcrumbs(doc); len(trail) > 0 {
+		return trail
+	}
+	for _, sel := range breadcrumbSelectors {
+		if trail := domBreadcrumbs(doc.Find(sel).First()); len(trail) > 0 {
+			return trail
+		}
+	}
+	return nil
+}
+
+// domBreadcrumbs はパンくずリストの要素の項目名を返す（li 要素、なければリンクを項目とする）
+func domBreadcrumbs(s *goquery.Selection) []string {
+	if s.Length() == 0 {
+		return nil
+	}
+	items := s.Find("li")
+	if items.Length() == 0 {
+		items = s.Find("a")
+	}
+	var trail []string
+	items.Each(func(i int, item *goquery.Selection) {
+		// Read the Docs の「Edit on GitHub」など項目ではない要素は除く
+		if strings.Contains(item.AttrOr("class", ""), "aside") {
+			return
+		}
+		if name := breadcrumbName(item.Text()); name != "" {
+			trail = append(trail, name)
+		}
+	})
+	if len(trail) == 0 {
+		for _, part := range strings.FieldsFunc(s.Text(), func(r rune) bool { return strings.ContainsRune(breadcrumbSeparators, r) }) {
+			if name := breadcrumbName(part); name != "" {
+				trail = append(trail, name)
+			}
+		}
+	}
+	return trail
+}
+
+// breadcrumbName は項目のテキストの空白をまとめ、前後の区切り記号を取り除く
+func breadcrumbName(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.TrimSpace(strings.Trim(text, breadcrumbSeparators+" "))
+}
+
+// jsonLDBreadcrumbs はJSON-LD（script[type="application/ld+json"]）の BreadcrumbList の項目名を position の順に返す
+func jsonLDBreadcrumbs(doc *goquery.Document) []string {
+	var trail []string
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		var data any
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			return true
+		}
+		if list := findBreadcrumbList(data); list != nil {
+			trail = breadcrumbListNames(list)
+		}
+		return len(trail) == 0
+	})
+	return trail
+}
+
+// findBreadcrumbList はJSON-LDのデータ（配列や @graph を含む）から @type が BreadcrumbList のオブジェクトを探す
+func findBreadcrumbList(data any) map[string]any {
+	switch v := data.(type) {
+	case []any:
+		for _, item := range v {
+			if list := findBreadcrumbList(item); list != nil {
+				return list
+			}
+		}
+	case map[string]any:
+		if hasJSONLDType(v, "BreadcrumbList") {
+			return v
+		}
+		if graph, ok := v["@graph"]; ok {
+			return findBreadcrumbList(graph)
+		}
+	}
+	return nil
+}
+
+// hasJSONLDType はJSON-LDのオブジェクトの @type（文字列または配列）に typ が含まれるかを判定する
+func hasJSONLDType(obj map[string]any, typ string) bool {
+	switch t := obj["@type"].(type) {
+	case string:
+		return t == typ
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok && s == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// breadcrumbListNames は BreadcrumbList の itemListElement の項目名を position の順に返す
+// 項目名は ListItem の name、なければ item の name を使う
+func breadcrumbListNames(list map[string]any) []string {
+	elements, _ := list["itemListElement"].([]any)
+	type entry struct {
+		position float64
+		name     string
+	}
+	var entries []entry
+	for i, element := range elements {
+		item, ok := element.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := item["name"].(string)
+		if name == "" {
+			if target, ok := item["item"].(map[string]any); ok {
+				name, _ = target["name"].(string)
+			}
+		}
+		if name = breadcrumbName(name); name == "" {
+			continue
+		}
+		position := float64(i + 1)
+		switch p := item["position"].(type) {
+		case float64:
+			position = p
+		case string:
+			if n, err := strconv.ParseFloat(p, 64); err == nil {
+				position = n
+			}
+		}
+		entries = append(entries, entry{position: position, name: name})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].position < entries[j].position })
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.name
+	}
+	return names
+}
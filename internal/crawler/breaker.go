@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// 直近のリクエストの失敗率でリクエストを一時停止するサーキットブレーカーのデフォルト値
+const (
+	DefaultBreakerWindow    = 20               // 失敗率を計算する直近のリクエスト数
+	DefaultBreakerThreshold = 0.5              // 一時停止する失敗率
+	DefaultBreakerCooldown  = 30 * time.Second // 一時停止する時間
+)
+
+// breakerState はサーキットブレーカーの状態
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // 通常どおりリクエストを送る
+	breakerOpen                         // 一時停止中
+	breakerHalfOpen                     // 1件のリクエストで回復を確認中
+)
+
+// circuitBreaker はサーバーの5xxやタイムアウトが続いたときにすべてのリクエストを一時停止する
+// 一時停止の後は1件のリクエストだけを送り、成功すれば再開、失敗すれば再び一時停止する
+type circuitBreaker struct {
+	window    int
+	threshold float64
+	cooldown  time.Duration
+	logger    *slog.Logger
+	now       func() time.Time                                 // 現在時刻（テストで差し替える）
+	sleep     func(ctx context.Context, d time.Duration) error // 待機（テストで差し替える）
+
+	mu        sync.Mutex
+	state     breakerState
+	results   []bool // 直近のリクエストが失敗したか（リングバッファ）
+	next      int    // results に次に書き込む位置
+	failures  int    // results のうち失敗の数
+	openedAt  time.Time
+	probeDone chan struct{} // 回復の確認のリクエストが終わると閉じる
+}
+
+// newCircuitBreaker はサーキットブレーカーを作成する（window が0以下の場合は nil を返し、一時停止しない）
+func newCircuitBreaker(window int, threshold float64, cooldown time.Duration, logger *slog.Logger) *circuitBreaker {
+	if window <= 0 || threshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{
+		window:    window,
+		threshold: threshold,
+		cooldown:  cooldown,
+		logger:    logger,
+		now:       time.Now,
+		sleep:     sleepContext,
+		results:   make([]bool, 0, window),
+	}
+}
+
+// wait はリクエストを送ってよい状態になるまで待つ（ctx がキャンセルされた場合は中断する）
+// 一時停止の明けに呼び出したリクエストが回復の確認のリクエストになり、probe が true になる
+func (b *circuitBreaker) wait(ctx context.Context) (probe bool, err error) {
+	if b == nil {
+		return false, nil
+	}
+	for {
+		b.mu.Lock()
+		switch b.state {
+		case breakerClosed:
+			b.mu.Unlock()
+			return false, nil
+		case breakerOpen:
+			remaining := b.openedAt.Add(b.cooldown).Sub(b.now())
+			if remaining <= 0 {
+				b.state = breakerHalfOpen
+				b.probeDone = make(chan struct{})
+				b.mu.Unlock()
+				b.logger.Info("一時停止が終わったため、1件のリクエストでサーバーの回復を確認します")
+				return true, nil
+			}
+			b.mu.Unlock()
+			if err := b.sleep(ctx, remaining); err != nil {
+				return false, err
+			}
+		case breakerHalfOpen:
+			done := b.probeDone
+			b.mu.Unlock()
+			select {
+			case <-done:
+			case <-ctx.Done():
+				return false, ctx.Err()
+			}
+		}
+	}
+}
+
+// record はリクエストの結果を記録し、失敗率に応じて状態を切り替える
+// probe は wait で回復の確認のリクエストとされたかどうか
+func (b *circuitBreaker) record(probe, failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe && b.state == breakerHalfOpen {
+		close(b.probeDone)
+		if failed {
+			b.state, b.openedAt = breakerOpen, b.now()
+			b.logger.Warn("サーバーが回復していないため、リクエストを再び一時停止します", "cooldown", b.cooldown)
+			return
+		}
+		b.state = breakerClosed
+		b.results, b.next, b.failures = b.results[:0], 0, 0
+		b.logger.Info("サーバーが回復したため、リクエストを再開します")
+		return
+	}
+	if b.state != breakerClosed {
+		return
+	}
+
+	if len(b.results) < b.window {
+		b.results = append(b.results, failed)
+	} else {
+		if b.results[b.next] {
+			b.failures--
+		}
+		b.results[b.next] = failed
+	}
+	b.next = (b.next + 1) % b.window
+	if failed {
+		b.failures++
+	}
+
+	if len(b.results) == b.window && float64(b.failures) >= b.threshold*float64(b.window) {
+		b.state, b.openedAt = breakerOpen, b.now()
+		b.logger.Warn("サーバーエラーやタイムアウトが続いているため、すべてのリクエストを一時停止します",
+			"failures", b.failures, "window", b.window, "cooldown", b.cooldown)
+	}
+}
+
+// release は結果を記録せずに終わったリクエスト（クロールの中断など）が回復の確認中だった場合、次のリクエストで確認し直せるようにする
+func (b *circuitBreaker) release(probe bool) {
+	if b == nil || !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		close(b.probeDone)
+		b.state, b.openedAt = breakerOpen, b.now().Add(-b.cooldown)
+	}
+}
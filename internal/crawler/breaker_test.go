@@ -0,0 +1,208 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock はサーキットブレーカーの now と sleep を差し替える時計
+// sleep は待たずに時刻を進める
+type fakeClock struct {
+	t      time.Time
+	sleeps []time.Duration
+}
+
+func (f *fakeClock) now() time.Time { return f.t }
+
+func (f *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.sleeps = append(f.sleeps, d)
+	f.t = f.t.Add(d)
+	return nil
+}
+
+// newTestBreaker は偽の時計を使うサーキットブレーカーを作成する
+func newTestBreaker(window int, threshold float64, cooldown time.Duration) (*circuitBreaker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := newCircuitBreaker(window, threshold, cooldown, discardLogger())
+	b.now, b.sleep = clock.now, clock.sleep
+	return b, clock
+}
+
+// mustWait は wait を呼び出し、エラーの場合はテストを失敗させる
+func mustWait(t *testing.T, b *circuitBreaker) bool {
+	t.Helper()
+	probe, err := b.wait(context.Background())
+	if err != nil {
+		t.Fatalf("wait がエラーを返しました: %v", err)
+	}
+	return probe
+}
+
+func TestCircuitBreakerOpensAtThreshold(t *testing.T) {
+	b, _ := newTestBreaker(4, 0.5, 30*time.Second)
+
+	// 直近のリクエスト数が window に満たないうちは失敗が続いても一時停止しない
+	b.record(false, true)
+	if b.state != breakerClosed {
+		t.Fatalf("window に満たないうちに状態が %v になりました", b.state)
+	}
+	b.record(false, false)
+	b.record(false, false)
+	b.record(false, false)
+	if b.state != breakerClosed {
+		t.Fatalf("失敗率が閾値未満で状態が %v になりました", b.state)
+	}
+
+	// 最も古い失敗が押し出され、直近4件のうち2件が失敗になったところで一時停止する
+	b.record(false, true)
+	if b.state != breakerClosed {
+		t.Fatalf("直近4件のうち失敗1件で状態が %v になりました", b.state)
+	}
+	b.record(false, true)
+	if b.state != breakerOpen {
+		t.Fatalf("直近4件のうち失敗2件で一時停止しませんでした: %v", b.state)
+	}
+}
+
+func TestCircuitBreakerHalfOpenRecovers(t *testing.T) {
+	b, clock := newTestBreaker(2, 0.5, 30*time.Second)
+	b.record(false, true)
+	b.record(false, true)
+	opened := clock.t
+
+	// 一時停止中に時刻が進んだ分だけ待つ時間が短くなる
+	clock.t = opened.Add(10 * time.Second)
+	if !mustWait(t, b) {
+		t.Fatal("一時停止明けのリクエストが回復の確認になりませんでした")
+	}
+	if len(clock.sleeps) != 1 || clock.sleeps[0] != 20*time.Second {
+		t.Errorf("待機時間 = %v, want [20s]", clock.sleeps)
+	}
+	if b.state != breakerHalfOpen {
+		t.Fatalf("状態 = %v, want half-open", b.state)
+	}
+
+	// 回復の確認中は他のリクエストが確認の結果を待つ
+	waited := make(chan bool)
+	go func() {
+		probe, _ := b.wait(context.Background())
+		waited <- probe
+	}()
+	select {
+	case <-waited:
+		t.Fatal("回復の確認中に他のリクエストが待たずに戻りました")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	b.record(true, false)
+	if probe := <-waited; probe {
+		t.Error("回復後のリクエストが回復の確認とされました")
+	}
+	if b.state != breakerClosed {
+		t.Fatalf("回復の確認の成功後の状態 = %v, want closed", b.state)
+	}
+	if len(b.results) != 0 || b.failures != 0 {
+		t.Errorf("回復後に直近の結果が初期化されていません: results=%v failures=%d", b.results, b.failures)
+	}
+
+	// 回復前の失敗は数えず、再び window 分の結果がそろってから判定する
+	b.record(false, true)
+	if b.state != breakerClosed {
+		t.Errorf("回復直後の1件の失敗で状態が %v になりました", b.state)
+	}
+}
+
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	b, clock := newTestBreaker(1, 1, time.Minute)
+	b.record(false, true)
+	if !mustWait(t, b) {
+		t.Fatal("一時停止明けのリクエストが回復の確認になりませんでした")
+	}
+
+	b.record(true, true)
+	if b.state != breakerOpen {
+		t.Fatalf("回復の確認の失敗後の状態 = %v, want open", b.state)
+	}
+	if !b.openedAt.Equal(clock.t) {
+		t.Errorf("再び一時停止した時刻 = %v, want %v", b.openedAt, clock.t)
+	}
+
+	// 再び cooldown の全体を待ってから確認する
+	clock.sleeps = nil
+	if !mustWait(t, b) {
+		t.Fatal("再度の一時停止明けのリクエストが回復の確認になりませんでした")
+	}
+	if len(clock.sleeps) != 1 || clock.sleeps[0] != time.Minute {
+		t.Errorf("待機時間 = %v, want [1m0s]", clock.sleeps)
+	}
+}
+
+func TestCircuitBreakerReleaseOnCancel(t *testing.T) {
+	b, clock := newTestBreaker(1, 1, time.Minute)
+	b.record(false, true)
+	if !mustWait(t, b) {
+		t.Fatal("一時停止明けのリクエストが回復の確認になりませんでした")
+	}
+
+	// 回復の確認を待っているリクエストは確認が終わると再び wait をやり直す
+	waited := make(chan bool)
+	go func() {
+		probe, _ := b.wait(context.Background())
+		waited <- probe
+	}()
+
+	// 確認のリクエストが結果を記録せずに終わった場合は、待たずに次のリクエストで確認し直す
+	clock.sleeps = nil
+	b.release(true)
+	if probe := <-waited; !probe {
+		t.Error("release の後のリクエストが回復の確認になりませんでした")
+	}
+	if len(clock.sleeps) != 0 {
+		t.Errorf("release の後に一時停止の時間を待ちました: %v", clock.sleeps)
+	}
+	if b.state != breakerHalfOpen {
+		t.Errorf("状態 = %v, want half-open", b.state)
+	}
+
+	// 回復の確認ではないリクエストの release は状態を変えない
+	b.release(false)
+	if b.state != breakerHalfOpen {
+		t.Errorf("確認ではないリクエストの release で状態が %v になりました", b.state)
+	}
+}
+
+func TestCircuitBreakerWaitCanceled(t *testing.T) {
+	b, _ := newTestBreaker(1, 1, time.Minute)
+	b.record(false, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("一時停止中にキャンセルした wait のエラー = %v, want context.Canceled", err)
+	}
+
+	// 回復の確認の結果を待っている間のキャンセル
+	if !mustWait(t, b) {
+		t.Fatal("一時停止明けのリクエストが回復の確認になりませんでした")
+	}
+	if _, err := b.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("回復の確認中にキャンセルした wait のエラー = %v, want context.Canceled", err)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	if b := newCircuitBreaker(0, 0.5, time.Second, discardLogger()); b != nil {
+		t.Fatal("window が0のときに nil を返しませんでした")
+	}
+	var b *circuitBreaker
+	if probe, err := b.wait(context.Background()); probe || err != nil {
+		t.Errorf("nil の wait = (%v, %v), want (false, nil)", probe, err)
+	}
+	b.record(true, true)
+	b.release(true)
+}
@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheEntry はキャッシュに保存する1ページ分のレスポンス
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	StatusCode   int       `json:"status_code"`
+	StoredAt     time.Time `json:"stored_at"`
+	BodyHash     string    `json:"body_sha256"` // 破損検出用のボディのハッシュ
+	Body         []byte    `json:"body"`
+}
+
+// cachePath はURLに対応するキャッシュファイルのパスを返す
+func (c *Crawler) cachePath(url string) string {
+	sum := sha256.Sum256([]byte(stripFragment(url)))
+	return filepath.Join(c.cacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// loadCache はURLのキャッシュを読み込む（キャッシュがない、読み込みが無効、または破損している場合は nil）
+func (c *Crawler) loadCache(url string) *cacheEntry {
+	if c.cacheDir == "" || c.noCacheRead {
+		return nil
+	}
+	data, err := os.ReadFile(c.cachePath(url))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		c.logger.Warn("キャッシュが破損しているため無視します", "url", url)
+		return nil
+	}
+	sum := sha256.Sum256(entry.Body)
+	if entry.URL != stripFragment(url) || hex.EncodeToString(sum[:]) != entry.BodyHash {
+		c.logger.Warn("キャッシュが破損しているため無視します", "url", url)
+		return nil
+	}
+	return &entry
+}
+
+// setConditionalHeaders はキャッシュの検証用ヘッダーをリクエストに設定する
+func (entry *cacheEntry) setConditionalHeaders(req *http.Request) {
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
+
+// storeCache はレスポンスのボディをキャッシュに保存する
+// ETagもLast-Modifiedもないレスポンスは再検証できないため保存しない
+func (c *Crawler) storeCache(url string, resp *http.Response, body []byte) {
+	if c.cacheDir == "" {
+		return
+	}
+	entry := cacheEntry{
+		URL:          stripFragment(url),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentType:  resp.Header.Get("Content-Type"),
+		StatusCode:   resp.StatusCode,
+		StoredAt:     time.Now(),
+		Body:         body,
+	}
+	if entry.ETag == "" && entry.LastModified == "" {
+		return
+	}
+	sum := sha256.Sum256(body)
+	entry.BodyHash = hex.EncodeToString(sum[:])
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		c.logger.Warn("キャッシュの作成に失敗しました", "error", err)
+		return
+	}
+	if err := os.MkdirAll(c.cacheDir, 0o755); err != nil {
+		c.logger.Warn("キャッシュの保存に失敗しました", "error", err)
+		return
+	}
+
+	// 書き込み途中のファイルを読まないよう一時ファイルからリネームする
+	path := c.cachePath(url)
+	tmp, err := os.CreateTemp(c.cacheDir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		c.logger.Warn("キャッシュの保存に失敗しました", "error", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		c.logger.Warn("キャッシュの保存に失敗しました", "error", err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		c.logger.Warn("キャッシュの保存に失敗しました", "error", err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		c.logger.Warn("キャッシュの保存に失敗しました", "error", err)
+	}
+}
@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// toUTF8 はレスポンスボディをUTF-8に変換する
+// Content-Typeのcharsetパラメータ、<meta charset>の順に文字コードを判定し、
+// どちらもない場合はUTF-8、Shift_JIS、EUC-JPを推定する
+func toUTF8(body []byte, contentType string) []byte {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+
+	// 宣言がなくUTF-8でもない場合、判定の既定値（windows-1252）の代わりに日本語の文字コードを推定する
+	if !certain && name == "windows-1252" && !hasMetaCharset(body) {
+		if utf8.Valid(body) {
+			return body
+		}
+		if sniffed := sniffJapanese(body); sniffed != nil {
+			enc = sniffed
+		}
+	}
+
+	if enc == encoding.Nop || name == "utf-8" {
+		return bytes.ToValidUTF8(body, []byte("\uFFFD"))
+	}
+	decoded, _, err := transform.Bytes(enc.NewDecoder(), body)
+	if err != nil {
+		return bytes.ToValidUTF8(body, []byte("\uFFFD"))
+	}
+	return bytes.ToValidUTF8(decoded, []byte("\uFFFD"))
+}
+
+// hasMetaCharset はHTMLの先頭付近に文字コードの宣言があるかを返す
+func hasMetaCharset(body []byte) bool {
+	head := body
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	return bytes.Contains(bytes.ToLower(head), []byte("charset"))
+}
+
+// sniffJapanese はShift_JISとEUC-JPのうち、より自然に変換できる方の文字コードを返す
+// どちらでも正しく変換できない場合は nil を返す
+func sniffJapanese(body []byte) encoding.Encoding {
+	candidates := []encoding.Encoding{japanese.ShiftJIS, japanese.EUCJP}
+
+	var best encoding.Encoding
+	bestScore, bestErrors := -1, 0
+	for _, enc := range candidates {
+		decoded, _, err := transform.Bytes(enc.NewDecoder(), body)
+		if err != nil {
+			continue
+		}
+		errors, score := japaneseDecodeScore(string(decoded))
+		if bestScore == -1 || score < bestScore {
+			best, bestScore, bestErrors = enc, score, errors
+		}
+	}
+
+	// 変換エラーが多すぎる場合は日本語の文字コードではないとみなす
+	if best == nil || bestErrors > len(body)/100 {
+		return nil
+	}
+	return best
+}
+
+// japaneseDecodeScore は変換結果の不自然さを数値化する（小さいほど自然）
+// EUC-JPのバイト列はShift_JISとして読むと半角カナとしてエラーなく変換できてしまうため、半角カナも減点する
+func japaneseDecodeScore(decoded string) (errors, score int) {
+	for _, r := range decoded {
+		switch {
+		case r == utf8.RuneError:
+			errors++
+			score += 10
+		case r >= 0xFF61 && r <= 0xFF9F:
+			score++
+		}
+	}
+	return errors, score
+}
@@ -0,0 +1,60 @@
+package crawler
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// charsetFixture は文字コードの変換の確認に使う日本語の本文
+const charsetFixture = "<p>インストール手順：ドキュメントを参照してください。漢字・ひらがな・カタカナを含む文章です。</p>"
+
+// encodeJapanese は s を enc で符号化する
+func encodeJapanese(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name        string
+		enc         encoding.Encoding
+		contentType string
+		head        string
+	}{
+		{name: "Content-TypeのShift_JIS", enc: japanese.ShiftJIS, contentType: "text/html; charset=Shift_JIS"},
+		{name: "Content-TypeのEUC-JP", enc: japanese.EUCJP, contentType: "text/html; charset=euc-jp"},
+		{name: "meta charsetのShift_JIS", enc: japanese.ShiftJIS, contentType: "text/html", head: `<meta charset="shift_jis">`},
+		{name: "meta http-equivのEUC-JP", enc: japanese.EUCJP, contentType: "text/html", head: `<meta http-equiv="Content-Type" content="text/html; charset=EUC-JP">`},
+		{name: "宣言なしのShift_JIS", enc: japanese.ShiftJIS, contentType: "text/html"},
+		{name: "宣言なしのEUC-JP", enc: japanese.EUCJP, contentType: "text/html"},
+		{name: "宣言なしのUTF-8", contentType: "text/html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := "<html><head>" + tt.head + "<title>文字コード</title></head><body>" + charsetFixture + "</body></html>"
+			body := []byte(html)
+			if tt.enc != nil {
+				body = encodeJapanese(t, tt.enc, html)
+			}
+			got := string(toUTF8(body, tt.contentType))
+			if !strings.Contains(got, charsetFixture) || !strings.Contains(got, "<title>文字コード</title>") {
+				t.Errorf("UTF-8に変換した本文 = %q", got)
+			}
+		})
+	}
+}
+
+func TestToUTF8InvalidBytes(t *testing.T) {
+	// UTF-8と宣言された不正なバイト列は置換文字に置き換える
+	got := toUTF8([]byte("<p>ok\xff\xfe</p>"), "text/html; charset=utf-8")
+	if want := "<p>ok�</p>"; string(got) != want {
+		t.Errorf("toUTF8 = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// checkpointInterval はチェックポイントを保存するページ数の間隔
+const checkpointInterval = 10
+
+// checkpoint は中断したクロールを再開するための状態
+type checkpoint struct {
+	BaseURL       string           `json:"base_url"`
+	Seeds         []string         `json:"seeds,omitempty"`
+	SavedAt       time.Time        `json:"saved_at"`
+	Visited       []string         `json:"visited"`
+	VisitedHashes []uint64         `json:"visited_hashes,omitempty"` // URL全体を保持しない場合の訪問済みURLのハッシュ
+	Collected     []string         `json:"collected"`
+	Pending       []checkpointItem `json:"pending"`
+	Pages         []Page           `json:"pages"`
+}
+
+// checkpointItem はクロール待ちのURLの保存形式
+type checkpointItem struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+	Seed  string `json:"seed,omitempty"`
+}
+
+// loadCheckpoint はチェックポイントファイルを読み込んでクローラーの状態を復元し、保存済みのページを返す
+func (c *Crawler) loadCheckpoint() ([]Page, error) {
+	data, err := os.ReadFile(c.checkpointPath)
+	if err != nil {
+		return nil, fmt.Errorf("チェックポイントの読み込みに失敗しました: %w", err)
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("チェックポイントの解析に失敗しました: %w", err)
+	}
+	seeds := cp.Seeds
+	if len(seeds) == 0 {
+		seeds = []string{cp.BaseURL}
+	}
+	if strings.Join(seeds, ", ") != strings.Join(c.seeds, ", ") {
+		return nil, fmt.Errorf("チェックポイントの開始URL (%s) が指定されたURL (%s) と一致しません",
+			strings.Join(seeds, ", "), strings.Join(c.seeds, ", "))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, u := range cp.Visited {
+		c.visited.add(u)
+	}
+	for _, h := range cp.VisitedHashes {
+		c.visited.addHash(h)
+	}
+	c.enqueued = len(cp.Visited) + len(cp.VisitedHashes)
+	for _, u := range cp.Collected {
+		c.collected[u] = true
+	}
+	c.queue = c.queue[:0]
+	for _, item := range cp.Pending {
+		c.queue = append(c.queue, crawlItem{url: item.URL, depth: item.Depth, seed: item.Seed, weight: c.urlWeight(item.URL)})
+	}
+
+	c.logger.Info("チェックポイントから再開します",
+		"pages", len(cp.Pages), "pending", len(cp.Pending), "saved_at", cp.SavedAt.Format("2006-01-02 15:04:05"))
+	return cp.Pages, nil
+}
+
+// saveCheckpoint は現在のクロール状態をチェックポイントファイルに保存する
+// 一時ファイルに書き込んでからリネームするため、保存中にクラッシュしても既存のファイルは壊れない
+func (c *Crawler) saveCheckpoint(pages []Page) error {
+	c.mu.Lock()
+	cp := checkpoint{
+		BaseURL: c.baseURL,
+		Seeds:   c.seeds,
+		SavedAt: time.Now(),
+		Pages:   pages,
+	}
+	cp.Visited, cp.VisitedHashes = c.visited.entries()
+	for u := range c.collected {
+		cp.Collected = append(cp.Collected, u)
+	}
+	for _, item := range c.queue {
+		cp.Pending = append(cp.Pending, checkpointItem{URL: item.url, Depth: item.depth, Seed: item.seed})
+	}
+	c.mu.Unlock()
+	sort.Strings(cp.Visited)
+	sort.Slice(cp.VisitedHashes, func(i, j int) bool { return cp.VisitedHashes[i] < cp.VisitedHashes[j] })
+	sort.Strings(cp.Collected)
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("チェックポイントの作成に失敗しました: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.checkpointPath), filepath.Base(c.checkpointPath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("チェックポイントの保存に失敗しました: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("チェックポイントの保存に失敗しました: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("チェックポイントの保存に失敗しました: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("チェックポイントの保存に失敗しました: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.checkpointPath); err != nil {
+		return fmt.Errorf("チェックポイントの保存に失敗しました: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package crawler
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/yugo-ibuki/docrawl/internal/parser"
+)
+
+// contentSelectorSource は本文の要素のセレクタの指定元
+const contentSelectorSource = "--selector"
+
+// newConverter はページの本文をMarkdownに変換するコンバーターを作成する
+func (c *Crawler) newConverter(pageURL string) *markdownConverter {
+	return &markdownConverter{
+		base:          pageURL,
+		linkStyle:     c.linkStyle,
+		keepFragments: c.keepFragmentLinks,
+		tabsMode:      c.tabsMode,
+		dedentCode:    c.dedentCode,
+	}
+}
+
+// contentRoot は本文を抽出する要素を返す
+// --selector に一致する最初の要素を使い、指定がないか一致しないページではメインコンテンツの要素を推定する
+// --auto-content の指定があれば、推定できないページでは本文らしさの点数で要素を選ぶ
+func (c *Crawler) contentRoot(doc *goquery.Document, pageURL string) *goquery.Selection {
+	if c.contentSelector != "" {
+		matched := doc.Find(c.contentSelector)
+		c.selectorStats.Record(contentSelectorSource, c.contentSelector, matched.Length())
+		if matched.Length() > 0 {
+			return matched.First()
+		}
+		c.logger.Warn("本文の要素のセレクタに一致する要素がないため、本文の要素を推定します", "url", pageURL, "selector", c.contentSelector)
+	}
+	return parser.MainContent(doc, c.autoContent)
+}
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// contentFixture は本文の要素の選び方の確認に使うHTML（main の中に目次と評価欄がある）
+var contentFixture = filepath.Join("testdata", "content", "markdown-body.html")
+
+func TestContentSelectorMatch(t *testing.T) {
+	var logs bytes.Buffer
+	c := New("https://docs.example.com/", 3, 10, 0, 0,
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))), WithContentSelector("article.markdown-body"))
+	got := convertFixture(t, c, contentFixture)
+
+	// 指定した要素の中だけを抽出する
+	for _, want := range []string{"Webhooks notify your server", "## Events", "exponential backoff"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("出力に %q が含まれていません:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"On this page", "Was this page helpful?", "Copyright"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("出力に %q が含まれています:\n%s", unwanted, got)
+		}
+	}
+	if strings.Contains(logs.String(), "WARN") {
+		t.Errorf("一致したセレクタで警告が出力されました:\n%s", logs.String())
+	}
+}
+
+func TestContentSelectorFallback(t *testing.T) {
+	var logs bytes.Buffer
+	c := New("https://docs.example.com/", 3, 10, 0, 0,
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))), WithContentSelector("div.rst-content"))
+	got := convertFixture(t, c, contentFixture)
+
+	// 一致しないページでは本文の要素を推定し（main）、URLを含めて警告する
+	for _, want := range []string{"Webhooks notify your server", "Was this page helpful?"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("出力に %q が含まれていません:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Copyright") {
+		t.Errorf("main の外のフッターが出力されています:\n%s", got)
+	}
+	if log := logs.String(); !strings.Contains(log, "WARN") || !strings.Contains(log, fixtureURL) || !strings.Contains(log, "div.rst-content") {
+		t.Errorf("一致しないセレクタの警告にURLとセレクタが含まれていません:\n%s", log)
+	}
+}
@@ -2,210 +2,1189 @@ package crawler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/yugo-ibuki/docrawl/internal/parser"
 )
 
+// Version はUser-Agentなどに表示するdocrawlのバージョン（ビルド時に -ldflags で上書きできる）
+var Version = "dev"
+
+// BrowserUserAgent はブラウザを装う場合のUser-Agent
+const BrowserUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36"
+
+// DefaultUserAgent はdocrawlであることを明示するデフォルトのUser-Agentを返す
+func DefaultUserAgent() string {
+	return "docrawl/" + Version + " (+https://github.com/yugo-ibuki/docrawl)"
+}
+
+// DefaultAcceptLanguage はリクエストに設定するデフォルトのAccept-Language
+const DefaultAcceptLanguage = "en-US,en;q=0.9"
+
+// DefaultMaxURLs はキューに追加できるURLの総数の上限のデフォルト値
+const DefaultMaxURLs = 50000
+
 // Page はクロールされたページの情報を格納する構造体
 type Page struct {
-	URL     string
-	Title   string
-	Content string
-	Depth   int
+	URL           string    `json:"url"`
+	RequestedURL  string    `json:"requested_url,omitempty"` // リダイレクトされた場合のリクエスト時のURL
+	Title         string    `json:"title"`
+	Content       string    `json:"content"`
+	Depth         int       `json:"depth"`
+	Seed          string    `json:"seed,omitempty"` // このページに到達した開始URL
+	StatusCode    int       `json:"status_code"`
+	FetchedAt     time.Time `json:"fetched_at"`                // レスポンスを受信した日時
+	LastModified  time.Time `json:"last_modified,omitempty"`   // Last-Modifiedヘッダーの日時（ない場合はゼロ値）
+	DurationMS    int64     `json:"duration_ms"`               // リクエストの送信からボディの読み込み完了までの時間（ミリ秒）
+	Bytes         int64     `json:"bytes"`                     // 受信したボディのバイト数（キャッシュを使った場合は0）
+	CanonicalURL  string    `json:"canonical_url,omitempty"`   // <link rel="canonical">で宣言された正規URL（自身と同じ場合は空）
+	SourceEditURL string    `json:"source_edit_url,omitempty"` // 「このページを編集」リンクのURL
+	FromSource    bool      `json:"from_source,omitempty"`     // 元のMarkdownソースをそのままコンテンツとして使用したか
+	AliasURLs     []string  `json:"alias_urls,omitempty"`      // 同じ内容で提供されている別のURL
+	ChangeStatus  string    `json:"change_status,omitempty"`   // 前回のクロールからの変化（ChangeAdded などの値、比較しない場合は空）
+	WaitForMissed bool      `json:"wait_for_missed,omitempty"` // 描画の待機中に --wait-for の要素が現れなかったか
+	FromPDF       bool      `json:"from_pdf,omitempty"`        // リンク先のPDFから抽出したページか
+	AssetPath     string    `json:"asset_path,omitempty"`      // 保存したPDFのパス
+	Headings      []Heading `json:"headings,omitempty"`        // 本文の見出しとアンカー（HTMLから抽出した場合のみ、文書の順）
+	Description   string    `json:"description,omitempty"`     // meta description（なければ og:description）
+	OGTitle       string    `json:"og_title,omitempty"`        // og:title
+	ModifiedAt    time.Time `json:"modified_at,omitempty"`     // article:modified_time の日時（ない場合はゼロ値）
+	Breadcrumbs   []string  `json:"breadcrumbs,omitempty"`     // パンくずリストの項目名（上位の階層から順）
+
+	Outline []*HeadingNode `json:"outline,omitempty"` // Headings を階層にした目次
 }
 
 // Crawler はウェブサイトをクロールする構造体
 type Crawler struct {
-	baseURL     string
-	maxDepth    int
-	timeout     int
-	delay       time.Duration
-	totalTime   time.Duration // 総実行時間
-	visitedURLs map[string]bool
-	mu          sync.Mutex // 並行アクセスのための排他制御
+	baseURL      string
+	seeds        []string // 開始URLの一覧（先頭は baseURL）
+	maxDepth     int
+	timeout      int
+	delay        time.Duration
+	limiter      *hostLimiter   // ホストごとのリクエスト間隔の制限
+	delayPolicy  *delayPolicy   // リクエスト間の待機時間の決め方
+	logger       *slog.Logger   // 進捗や警告の出力先
+	totalTime    time.Duration  // 総実行時間（0以下は無制限）
+	userAgent    string         // リクエストに設定するUser-Agent
+	acceptLang   string         // リクエストに設定するAccept-Language
+	headers      http.Header    // すべてのリクエストに追加するヘッダー
+	authUser     string         // Basic認証のユーザー名
+	authPass     string         // Basic認証のパスワード
+	token        string         // Bearerトークン（ログには出力しない）
+	client       *http.Client   // すべてのリクエストで共有するHTTPクライアント
+	jar          http.CookieJar // Set-Cookieをクロール全体で引き継ぐCookieジャー
+	cookies      []*http.Cookie // クロール開始前に設定するCookie（ログには出力しない）
+	login        *LoginConfig   // クロール開始前に行うフォームログインの設定
+	sessionFile  string         // Cookieを保存・復元するセッションファイルのパス（空の場合は保存しない）
+	proxyURL     *url.URL       // 経由するプロキシ（nilの場合は環境変数に従う）
+	noEnvProxy   bool           // HTTP_PROXYなどの環境変数を無視するか
+	visited      *visitedSet    // 訪問済みのURL（個別のロックで保護）
+	exactVisited bool           // 訪問済みのURLをハッシュではなくURL全体で判定するか
+	local        *localSite     // ディスク上に保存したサイト（nilの場合はネットワークから取得する）
+	navSelector  string         // ナビゲーションの順序を記録する要素のセレクタ（空の場合は記録しない）
+	navEveryPage bool           // 開始ページ以外のナビゲーションの順序も記録するか
+	navOrder     []string       // ナビゲーションに記載されていた順のURL
+	navIndex     map[string]int // navOrderでの位置
+
+	soft404Patterns  []*regexp.Regexp  // ソフト404と判定するパターン（nilの場合はデフォルト）
+	soft404MaxChars  int               // ソフト404と判定する本文の最大文字数（0以下は判定しない）
+	authAbortRatio   float64           // 401・403の割合がこれを超えたらクロールを中止する（0以下は中止しない）
+	retries          int               // 一時的なネットワークエラーで再試行する回数
+	retryBackoff     time.Duration     // 最初の再試行までの待機時間
+	breakerWindow    int               // 失敗率を計算する直近のリクエスト数（0以下は一時停止しない）
+	breakerThreshold float64           // 一時停止する失敗率
+	breakerCooldown  time.Duration     // 一時停止する時間
+	bandwidth        *bandwidthLimiter // 受信速度の制限（nilの場合は制限しない）
+	traps            *trapDetector     // クローラートラップの検出
+	breaker          *circuitBreaker   // サーバーの応答が悪化したときの一時停止（nilの場合は停止しない）
+	collected        map[string]bool   // 収集済みページの正規URL（canonicalによる重複排除用）
+	queue            []crawlItem       // クロール待ちのURL
+	enqueued         int               // これまでにキューに追加したURLの総数
+	priorities       []PriorityRule    // キューから取り出す順序を決めるURLの重み（空の場合は追加した順）
+	maxURLs          int               // キューに追加できるURLの総数の上限（0以下は無制限）
+	urlCapReached    bool              // キューに追加するURLが上限に達したか
+	errors           []CrawlError      // 取得に失敗したURL
+	mu               sync.Mutex        // 並行アクセスのための排他制御
+
+	contentHashes map[string]int // 収集済みページの内容のハッシュとpagesでの位置（pagesのミューテックスで保護）
+	contentDedup  bool           // 同じ内容のページを重複排除するか
+
+	skipTitlePatterns []*regexp.Regexp // 収集しないページのタイトルのパターン
+	skipTitleFollow   bool             // タイトルで収集しなかったページのリンクをたどるか
+
+	preferOGTitle bool // 表示するタイトルに og:title を <title> より優先するか
+
+	scopeRoot      string // クロール範囲のルートのURL（空の場合は開始URLのホスト全体）
+	discoverRoot   bool   // 開始ページからドキュメントのルートを探してクロール範囲とするか
+	rootDiscovered bool   // ドキュメントのルートを探し終えたか
+
+	contentSelector  string   // 本文を抽出する要素のセレクタ（空の場合は推定する）
+	autoContent      bool     // 本文の要素を推定できない場合に本文らしさの点数で選ぶか
+	excludeSelectors []string // 本文の抽出の前に取り除く要素のセレクタ
+	defaultExcludes  bool     // DefaultExcludeSelectors の要素も取り除くか
+
+	checkLinks       bool                // クロール後にページのリンクのリンク切れを確認するか
+	checkExternal    bool                // 開始URLと別のホストへのリンクも確認するか
+	checkConcurrency int                 // リンク切れの確認で同時に送るリクエストの数
+	checkLimiter     *hostLimiter        // 開始URLと別のホストへの確認のリクエスト間隔の制限
+	linkRefs         map[string][]string // ページに含まれていたリンクとそれを含むページ
+	linkOrder        []string            // linkRefs のリンクを見つけた順
+	brokenLinks      []BrokenLink        // リンク切れのリンク
+
+	imageDir       string   // ページの画像を保存するディレクトリ（空の場合は保存しない）
+	imageRefPrefix string   // 本文の画像の参照を書き換える保存したファイルのパスの前に付ける文字列
+	imageHosts     []string // 開始URLのホストのほかに画像を取得するドメイン
+
+	boilerplateRatio    float64 // 定型文とみなすブロックを含むページの割合（0以下は取り除かない）
+	boilerplateMaxChars int     // 定型文とみなすブロックの最大文字数
+
+	linkStyle         LinkStyle // 本文のリンクの書き出し方
+	keepFragmentLinks bool      // 同じページ内へのリンクも残すか
+	tabsMode          TabsMode  // タブで切り替える内容の書き出し方
+	dedentCode        bool      // コードブロックの共通の字下げを取り除くか
+
+	fingerprints     []pageFingerprint // 収集済みページのsimhash（pagesのミューテックスで保護）
+	nearDupThreshold int               // ほぼ同じ内容とみなすsimhashのハミング距離（これ未満、0以下は判定しない）
+	nearDupAction    NearDupAction     // ほぼ同じ内容のページの扱い
+
+	preferSourceMarkdown bool                  // 元のMarkdownソースが取得できる場合はHTML抽出より優先する
+	selectorStats        *parser.SelectorStats // ユーザー指定セレクタの一致数の集計
+	checkpointPath       string                // チェックポイントファイルのパス（空の場合は保存しない）
+	resume               bool                  // チェックポイントから再開するか
+	retryFailed          []FailedURL           // 取得し直す前回のクロールで失敗したURL（nilの場合は通常のクロール）
+	retrySkipped         []string              // 取得し直す対象のうち開始URLの範囲外のためスキップしたURL
+	maxBodySize          int64                 // 1ページあたりのレスポンスボディの上限（バイト、0以下は無制限）
+	skipOversized        bool                  // 上限を超えたページを切り詰めずにスキップするか
+	precheckEnabled      bool                  // GETの前にHEADリクエストで事前確認するか
+	sitemapOnly          bool                  // リンクをたどらずサイトマップに記載されたページのみを取得するか
+	cacheDir             string                // レスポンスをキャッシュするディレクトリ（空の場合はキャッシュしない）
+	noCacheRead          bool                  // キャッシュを読まずに取得し直すか（書き込みは行う）
+	stats                *CrawlStats           // クロールの統計情報
+	delayJitter          float64               // 待機時間を揺らす割合
+	adaptiveDelay        bool                  // サーバーの応答に応じて待機時間を伸縮するか
+	keepVariants         bool                  // 印刷用やAMP版のページもたどるか
+	dryRun               bool                  // コンテンツを抽出せずURLの探索のみを行うか
+	htmlDir              string                // 元のHTMLを保存するディレクトリ（空の場合は保存しない）
+	snapshots            []snapshotEntry       // 保存したHTMLの一覧
+	skipExts             map[string]bool       // リンクをたどらない拡張子
+	normalizer           queryNormalizer       // クエリパラメータの正規化
+	urlList              []ListedURL           // 指定された場合はリンクをたどらずこのURLのみを順に取得する
+	maxPagination        int                   // rel="next" で連続してたどるページ数の上限（0以下は無制限）
+	failFast             bool                  // いずれかのページの取得に失敗した時点でクロールを中止するか
+	depthMode            DepthMode             // 深度の数え方
+	linksFrom            string                // リンクを収集する要素のセレクタ（空の場合はページ全体）
+	followFrames         bool                  // iframe・frameで埋め込まれたページも取得するか
+	langFilter           string                // 収集する言語（空の場合はすべての言語）
+	render               bool                  // ヘッドレスブラウザで描画したDOMからコンテンツを抽出するか
+	renderTimeout        time.Duration         // 描画でネットワークが落ち着くのと waitFor の要素が現れるのを待つ時間の上限
+	waitFor              string                // 描画後に現れるまで待つ要素のセレクタ（空の場合は待たない）
+	waitDelay            time.Duration         // 描画後にDOMを取得する前に一律に待つ時間
+	browser              *browser              // 描画に使うヘッドレスブラウザ（クロール中のみ）
+	latestOnly           bool                  // 開始ページのバージョンのページのみを取得するか
+	docVersion           string                // 対象のドキュメントのバージョン（空の場合は絞り込まない、mu で保護）
+	versionDecided       bool                  // 対象のバージョンを決定済みか（mu で保護）
+	pdfDir               string                // リンク先のPDFを保存するディレクトリ（空の場合はPDFを収集しない）
 }
 
 // New は新しいCrawlerインスタンスを作成する
-func New(baseURL string, maxDepth, timeout int, delaySeconds float64, totalTimeSeconds int) *Crawler {
-	return &Crawler{
-		baseURL:     baseURL,
-		maxDepth:    maxDepth,
-		timeout:     timeout,
-		delay:       time.Duration(delaySeconds * float64(time.Second)),
-		totalTime:   time.Duration(totalTimeSeconds) * time.Second,
-		visitedURLs: make(map[string]bool),
+// totalTimeSeconds が0以下の場合は総実行時間を制限しない
+func New(baseURL string, maxDepth, timeout int, delaySeconds float64, totalTimeSeconds int, opts ...Option) *Crawler {
+	c := &Crawler{
+		baseURL:    baseURL,
+		maxDepth:   maxDepth,
+		timeout:    timeout,
+		delay:      time.Duration(delaySeconds * float64(time.Second)),
+		totalTime:  time.Duration(totalTimeSeconds) * time.Second,
+		userAgent:  DefaultUserAgent(),
+		acceptLang: DefaultAcceptLanguage,
+		logger:     slog.New(slog.NewTextHandler(os.Stderr, nil)),
+		collected:  make(map[string]bool),
+		navIndex:   make(map[string]int),
+		linkRefs:   make(map[string][]string),
+
+		soft404MaxChars:  DefaultSoft404MaxChars,
+		authAbortRatio:   DefaultAuthAbortRatio,
+		retries:          DefaultRetries,
+		retryBackoff:     retryBackoff,
+		breakerWindow:    DefaultBreakerWindow,
+		breakerThreshold: DefaultBreakerThreshold,
+		breakerCooldown:  DefaultBreakerCooldown,
+		checkConcurrency: DefaultCheckConcurrency,
+		traps:            newTrapDetector(DefaultTrapMaxRepeats, DefaultTrapMaxVariants),
+
+		contentHashes:    make(map[string]int),
+		contentDedup:     true,
+		defaultExcludes:  true,
+		nearDupThreshold: DefaultNearDupThreshold,
+		nearDupAction:    NearDupAlias,
+		maxURLs:          DefaultMaxURLs,
+		skipExts:         newSkipExtensionSet(),
+		maxPagination:    DefaultMaxPagination,
+		depthMode:        DepthHops,
+		followFrames:     true,
+		renderTimeout:    DefaultRenderTimeout,
+		maxBodySize:      DefaultMaxBodySize,
+		selectorStats:    parser.NewSelectorStats(),
+		stats:            newCrawlStats(),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.visited = newVisitedSet(c.exactVisited)
+	c.breaker = newCircuitBreaker(c.breakerWindow, c.breakerThreshold, c.breakerCooldown, c.logger)
+	c.delayPolicy = newDelayPolicy(c.delay, c.delayJitter, c.adaptiveDelay)
+	c.limiter = newHostLimiter(c.delayPolicy.Next)
+	c.checkLimiter = newHostLimiter(func() time.Duration { return externalCheckInterval })
+	c.seeds = append([]string{baseURL}, c.seeds...)
+
+	// バージョンを指定した場合は開始URLをそのバージョンのURLに置き換える
+	if c.docVersion != "" {
+		for i, seed := range c.seeds {
+			c.seeds[i] = replaceVersion(seed, c.docVersion)
+		}
+		c.baseURL = c.seeds[0]
+	}
+
+	// HTTPクライアントは一度だけ作成し、すべてのリクエストで共有してコネクションを再利用する
+	c.client = c.newHTTPClient(c.client)
+
+	// 指定されたCookieは開始URLのドメインに設定する
+	if len(c.cookies) > 0 {
+		if u, err := url.Parse(baseURL); err == nil {
+			c.jar.SetCookies(u, c.cookies)
+		}
+	}
+	return c
+}
+
+// shutdownGrace はタイムアウトや中断の後、取得中のページの処理が終わるのを待つ最大の時間
+const shutdownGrace = 5 * time.Second
+
+// Result はクロール結果（取得したページ、取得に失敗したURL、統計情報）
+type Result struct {
+	Pages       []Page
+	Errors      []CrawlError
+	Stats       StatsSnapshot
+	BrokenLinks []BrokenLink // リンク切れのリンク（WithLinkCheck を指定した場合のみ）
+	Complete    bool         // 中断・時間切れ・エラー・URLの上限で終了せず、キューのURLをすべて取得したか
 }
 
-// Crawl はベースURLからクローリングを開始し、見つかったページをすべて返す
-func (c *Crawler) Crawl() ([]Page, error) {
+// result は取得したページと記録済みのエラーからクロール結果を作成する
+func (c *Crawler) result(pages []Page) *Result {
+	c.stats.finish()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &Result{Pages: pages, Errors: append([]CrawlError(nil), c.errors...), Stats: c.stats.Snapshot(), BrokenLinks: c.brokenLinks}
+}
+
+// Crawl はベースURLからクローリングを開始し、見つかったページと取得に失敗したURLを返す
+// ctx がキャンセルされた場合は取得を中止し、それまでに取得したページをエラーなしで返す
+// 開始URLの取得に失敗した場合、1ページも取得できなかった場合、failFast で中止した場合はエラーとともに取得済みのページを返す
+func (c *Crawler) Crawl(ctx context.Context) (*Result, error) {
 	var pages []Page
 	var mu sync.Mutex // pagesの保護用ミューテックス
-	
-	// コンテキストを作成（総時間制限付き）
-	ctx, cancel := context.WithTimeout(context.Background(), c.totalTime)
+	c.stats.start()
+
+	// チェックポイントから状態を復元（失敗したURLの再取得では前回のページに追加する）
+	if c.resume || c.retryFailed != nil {
+		restored, err := c.loadCheckpoint()
+		if err != nil {
+			return nil, err
+		}
+		pages = restored
+		for i, page := range pages {
+			c.contentHashes[contentHash(page.Content)] = i
+			if hash, ok := simhash(page.Content); ok {
+				c.fingerprints = append(c.fingerprints, pageFingerprint{hash: hash, index: i})
+			}
+		}
+	}
+
+	// コンテキストを作成（総時間制限付き、0以下の場合は無制限）
+	var cancel context.CancelFunc
+	if c.totalTime > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.totalTime)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
 	defer cancel()
 
-	// エラーチャネルを作成
-	errChan := make(chan error, 1)
-	done := make(chan bool, 1)
+	// 保存したセッションを復元し、無効な場合はフォームログインを行ってからクロールする
+	if err := c.restoreSessionOrLogin(ctx); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := c.saveSession(); err != nil {
+			c.logger.Warn("セッションファイルの保存に失敗しました", "error", err)
+		}
+	}()
 
-	// クローリングを別のゴルーチンで実行
+	// ヘッドレスブラウザは一度だけ起動し、すべてのページの描画で共有する
+	if c.render {
+		b, err := launchBrowser(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("ヘッドレスブラウザを起動できません: %w", err)
+		}
+		c.browser = b
+		if c.hasCredentials() {
+			c.logger.Warn("ヘッドレスブラウザにはBasic認証・Bearerトークンを送信しません（Cookieによる認証のみ引き継ぎます）")
+		}
+		defer func() {
+			b.close()
+			c.browser = nil
+		}()
+	}
+
+	// クローリングを別のゴルーチンで実行し、終了時に結果を1回だけ送る
+	// バッファ付きのため、呼び出し側が待つのをやめても送信でブロックしない
+	finished := make(chan error, 1)
 	go func() {
-		err := c.crawlRecursive(ctx, c.baseURL, 0, &pages, &mu)
-		if err != nil && err != context.DeadlineExceeded {
-			errChan <- err
+		err := c.crawlBFS(ctx, &pages, &mu)
+		// 終了時点の状態をチェックポイントに保存
+		if c.checkpointPath != "" {
+			mu.Lock()
+			if err := c.saveCheckpoint(pages); err != nil {
+				c.logger.Warn("チェックポイントの保存に失敗しました", "error", err)
+			}
+			mu.Unlock()
+		}
+		if err := c.writeSnapshotManifest(); err != nil {
+			c.logger.Warn("HTMLの一覧の保存に失敗しました", "error", err)
 		}
-		done <- true
+		finished <- err
 	}()
 
-	// タイムアウトまたはクローリング完了を待つ
+	// タイムアウト、中断、またはクローリング完了を待つ
+	var err error
 	select {
-	case err := <-errChan:
-		return pages, fmt.Errorf("クローリング中にエラーが発生: %w", err)
+	case err = <-finished:
 	case <-ctx.Done():
 		if ctx.Err() == context.DeadlineExceeded {
-			fmt.Println("\n指定された時間が経過したため、クローリングを終了します")
+			c.logger.Warn("指定された時間が経過したため、クローリングを終了します")
+		} else {
+			c.logger.Warn("中断されたため、クローリングを終了します")
+		}
+		// 取得中のページの処理が終わるまで待ち、終わらない場合はその時点で取得済みのページを返す
+		select {
+		case err = <-finished:
+		case <-time.After(shutdownGrace):
+			c.logger.Warn("クローリングの終了を待たずに、取得済みのページを出力します", "grace", shutdownGrace)
+			mu.Lock()
+			collected := append([]Page(nil), pages...)
+			mu.Unlock()
+			return c.result(collected), nil
 		}
-		<-done // クローリングの完了を待つ
-		return pages, nil
-	case <-done:
-		return pages, nil
 	}
+
+	// 複数のページに共通する定型文を取り除く
+	if c.boilerplateRatio > 0 && !c.dryRun {
+		c.stripBoilerplate(pages)
+	}
+
+	// ページの本文が参照する画像を保存し、参照を保存したファイルに書き換える
+	if c.imageDir != "" && !c.dryRun {
+		c.downloadImages(ctx, pages)
+	}
+
+	// ページのリンクのうちリンク切れのものを確認する
+	if c.checkLinks {
+		c.brokenLinks = c.findBrokenLinks(ctx)
+	}
+
+	// キャンセルによる中止はエラーとしない（それ以外のエラーはキャンセルと同時に起きても返す）
+	if err != nil && !(ctx.Err() != nil && errors.Is(err, ctx.Err())) {
+		return c.result(pages), fmt.Errorf("クローリング中にエラーが発生: %w", err)
+	}
+	result := c.result(pages)
+	result.Complete = err == nil && ctx.Err() == nil && c.PendingCount() == 0 && !c.URLCapReached()
+	return result, nil
 }
 
-// crawlRecursive は再帰的にページをクロールする
-func (c *Crawler) crawlRecursive(ctx context.Context, url string, depth int, pages *[]Page, mu *sync.Mutex) error {
-	// コンテキストのキャンセルをチェック
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
+// SelectorStats はユーザー指定セレクタの一致数の集計を返す
+func (c *Crawler) SelectorStats() *parser.SelectorStats {
+	return c.selectorStats
+}
+
+// crawlItem はクロール待ちのURLとその深度
+type crawlItem struct {
+	url         string
+	depth       int
+	seed        string // このURLに到達した開始URL
+	refreshHops int    // meta refreshで転送された回数
+	pageHops    int    // rel="next" でたどった回数
+	weight      int    // --priority による優先度の重み
+}
+
+// crawlOutcome は1ページのクロールで見つかった次のクロール対象
+type crawlOutcome struct {
+	links   []string // 同じドメイン内のリンク
+	refresh string   // meta refreshの転送先（ない場合は空）
+	next    string   // rel="next" の次のページ（ない場合は空）
+	frames  []string // iframe・frameで埋め込まれた同じドメイン内のページ
+
+	translation string // 言語が一致しないページの指定された言語の版（ない場合は空）
+}
+
+// enqueueSeeds は開始URLをキューに追加する
+func (c *Crawler) enqueueSeeds(ctx context.Context) error {
+	for _, seed := range c.seeds {
+		// サイトマップのみのモードではサイトマップに記載されたページをすべて深度0として取得する
+		if c.sitemapOnly {
+			urls, err := c.discoverSitemapURLs(ctx, seed)
+			if err != nil {
+				return err
+			}
+			for _, u := range urls {
+				c.enqueueNew(crawlItem{url: u, depth: 0, seed: seed})
+			}
+			continue
+		}
+		c.enqueueNew(crawlItem{url: seed, depth: 0, seed: seed})
 	}
+	return nil
+}
 
-	// 最大深度チェック
-	if depth > c.maxDepth {
-		return nil
+// crawlBFS はキューを使って幅優先でページをクロールする
+// 深度の浅いページから順に取得するため、総実行時間で打ち切られても上位のページが残る
+func (c *Crawler) crawlBFS(ctx context.Context, pages *[]Page, mu *sync.Mutex) error {
+	if c.retryFailed != nil {
+		c.enqueueFailed()
+	} else if c.urlList != nil {
+		c.enqueueURLList()
+	} else if err := c.enqueueSeeds(ctx); err != nil {
+		return err
 	}
 
-	// 既に訪問済みのURLはスキップ（スレッドセーフに）
+	processed := 0
+	var failedSeeds []string
+	localQueued := false
+	for {
+		// コンテキストのキャンセルをチェック
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		item, ok := c.dequeue()
+		if !ok && c.local != nil && !localQueued {
+			// ディスク上のサイトではリンクからたどれなかったHTMLファイルも深度0として取得する
+			localQueued = true
+			c.enqueueLocalPages()
+			item, ok = c.dequeue()
+		}
+		if !ok {
+			break
+		}
+
+		outcome, err := c.crawlPage(ctx, item, pages, mu)
+		if err != nil {
+			if ctx.Err() != nil {
+				// 取得途中のURLは再開時に取り直せるようキューに戻す
+				c.requeue(item)
+				return ctx.Err()
+			}
+			c.recordError(item, err)
+			c.logger.Warn("クロール中にエラーが発生", "url", item.url, "error", err)
+			if err := c.checkAuthWall(); err != nil {
+				return err
+			}
+			if c.failFast {
+				return fmt.Errorf("%s の取得に失敗したため中止します: %w", item.url, err)
+			}
+			// 開始ページの失敗は残りのページを取得した後にエラーとして返す（リンクをたどらないモードではすべてのページが深度0のため対象外）
+			if item.depth == 0 && c.discoversLinks() {
+				failedSeeds = append(failedSeeds, item.url)
+			}
+			continue
+		}
+
+		// meta refreshの転送先は同じ深度で次に取得する（転送回数には上限を設ける）
+		if outcome.refresh != "" {
+			if item.refreshHops >= maxRefreshHops {
+				c.logger.Warn("meta refreshの転送が多すぎるため中止します", "url", item.url)
+			} else if c.markVisited(outcome.refresh) {
+				c.requeue(crawlItem{url: outcome.refresh, depth: item.depth, seed: item.seed, refreshHops: item.refreshHops + 1})
+			}
+		}
+
+		// rel="next" の次のページは深度を増やさずに次に取得し、出力でも隣り合うようにする
+		if outcome.next != "" {
+			if c.maxPagination > 0 && item.pageHops >= c.maxPagination {
+				c.logger.Warn("ページ送りが上限に達したため中止します", "url", item.url, "max_pagination", c.maxPagination)
+			} else {
+				c.addNew(crawlItem{url: outcome.next, depth: item.depth, seed: item.seed, pageHops: item.pageHops + 1}, true)
+			}
+		}
+
+		// 指定された言語の版は元のページと同じ深度で次に取得する
+		if outcome.translation != "" {
+			c.addNew(crawlItem{url: outcome.translation, depth: item.depth, seed: item.seed}, true)
+		}
+
+		// iframeで埋め込まれたページは埋め込み元と同じ深度で取得する
+		for _, frame := range outcome.frames {
+			c.enqueueNew(crawlItem{url: frame, depth: item.depth, seed: item.seed})
+		}
+
+		// 最大深度チェック（パスの階層で数える場合はリンクごとに判定する）
+		if c.depthMode != DepthPath && item.depth+1 > c.maxDepth {
+			continue
+		}
+
+		// 未訪問のリンクをキューに追加
+		for _, link := range outcome.links {
+			depth := c.linkDepth(item, link)
+			if depth > c.maxDepth {
+				continue
+			}
+			c.enqueueNew(crawlItem{url: link, depth: depth, seed: item.seed})
+		}
+
+		// 一定間隔でチェックポイントを保存
+		processed++
+		if c.checkpointPath != "" && processed%checkpointInterval == 0 {
+			mu.Lock()
+			if err := c.saveCheckpoint(*pages); err != nil {
+				c.logger.Warn("チェックポイントの保存に失敗しました", "error", err)
+			}
+			mu.Unlock()
+		}
+	}
+
+	if len(failedSeeds) > 0 {
+		return fmt.Errorf("開始URLの取得に失敗しました: %s", strings.Join(failedSeeds, ", "))
+	}
+	// ドライランでは対象のURLがないことを呼び出し側で警告する
+	mu.Lock()
+	defer mu.Unlock()
+	if len(*pages) == 0 && !c.dryRun {
+		return ErrNoPages
+	}
+	return nil
+}
+
+// enqueue はクロール待ちのキューの末尾にURLを追加する
+func (c *Crawler) enqueue(item crawlItem) {
 	c.mu.Lock()
-	if c.visitedURLs[url] {
-		c.mu.Unlock()
-		return nil
+	defer c.mu.Unlock()
+	c.queue = append(c.queue, item)
+}
+
+// requeue は取得を完了できなかったURLをキューの先頭に戻す
+func (c *Crawler) requeue(item crawlItem) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queue = append([]crawlItem{item}, c.queue...)
+}
+
+// dequeue はクロール待ちのキューの先頭からURLを取り出す
+// キューが空の場合は false を返す
+func (c *Crawler) dequeue() (crawlItem, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.queue) == 0 {
+		return crawlItem{}, false
 	}
-	c.visitedURLs[url] = true
-	c.mu.Unlock()
+	item := c.queue[0]
+	c.queue[0] = crawlItem{}
+	c.queue = c.queue[1:]
+	return item, true
+}
 
-	fmt.Printf("ページをクロール中 (深度 %d): %s\n", depth, url)
+// unqueue はクロール待ちのキューからURLを取り除く
+// キューになかった場合は false を返す
+func (c *Crawler) unqueue(url string) bool {
+	url = c.normalizer.normalize(url)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, item := range c.queue {
+		if c.normalizer.normalize(item.url) == url {
+			c.queue = append(c.queue[:i], c.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
 
-	// 遅延を入れる
-	select {
-	case <-ctx.Done():
+// PendingCount はクロール待ちのURLの件数を返す
+func (c *Crawler) PendingCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.queue)
+}
+
+// enqueueNew は未訪問のURLを訪問済みにしてキューの末尾に追加する（スレッドセーフに）
+// URLはフラグメントを取り除き、クエリパラメータを正規化してから訪問済みかどうかを判定する
+// キューに追加したURLの総数が上限に達している場合は追加せず、初回のみ警告を表示する
+func (c *Crawler) enqueueNew(item crawlItem) {
+	c.addNew(item, false)
+}
+
+// addNew は未訪問のURLをキューに追加する（front が true の場合は先頭に追加する）
+func (c *Crawler) addNew(item crawlItem, front bool) {
+	item.url = c.normalizer.normalize(item.url)
+	// 訪問済みのURLはキューのロックを取得せずに除外する
+	if c.visited.contains(item.url) {
+		return
+	}
+	// 別のバージョンのドキュメントへのリンクはたどらない
+	if !c.matchesVersion(item.url) {
+		return
+	}
+	if pattern, first, trapped := c.traps.check(item.url); trapped {
+		if first {
+			c.logger.Warn("際限なくURLを生成するパターンのため、これ以上キューに追加しません", "pattern", pattern, "url", item.url)
+		}
+		return
+	}
+	// 上限の枠のみグローバルなロックで確保し、訪問済みの集合への追加は分割した集合ごとのロックで行う
+	if !c.reserveURL() {
+		return
+	}
+	if !c.visited.add(item.url) {
+		c.releaseURL()
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if front {
+		c.queue = append([]crawlItem{item}, c.queue...)
+	} else if len(c.priorities) > 0 {
+		item.weight = c.urlWeight(item.url)
+		c.insertByPriority(item)
+	} else {
+		c.queue = append(c.queue, item)
+	}
+}
+
+// reserveURL はキューに追加するURLの上限の枠を1つ確保する（上限に達している場合は false を返し、初回のみ警告を表示する）
+func (c *Crawler) reserveURL() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxURLs > 0 && c.enqueued >= c.maxURLs {
+		if !c.urlCapReached {
+			c.urlCapReached = true
+			c.logger.Warn("キューに追加したURLが上限に達したため、これ以上リンクをたどりません（出力は不完全な可能性があります）", "max_urls", c.maxURLs)
+		}
+		return false
+	}
+	c.enqueued++
+	return true
+}
+
+// releaseURL は確保した上限の枠を返す（訪問済みで追加しなかった場合）
+func (c *Crawler) releaseURL() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enqueued--
+}
+
+// URLCapReached はキューに追加するURLが上限に達したかを返す
+func (c *Crawler) URLCapReached() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.urlCapReached
+}
+
+// markVisited はURLを正規化して訪問済みにする（スレッドセーフに）
+// 既に訪問済みだった場合は false を返す
+func (c *Crawler) markVisited(url string) bool {
+	return c.visited.add(c.normalizer.normalize(url))
+}
+
+// redirectHops はリダイレクトの途中で経由したURLを返す（最初のリクエストと最終的なURLは含まない）
+func redirectHops(resp *http.Response) []string {
+	var hops []string
+	for req := resp.Request; req.Response != nil && req.Response.Request != nil; req = req.Response.Request {
+		if prev := req.Response.Request; prev.Response != nil {
+			hops = append(hops, prev.URL.String())
+		}
+	}
+	return hops
+}
+
+// wait は同じホストへの前回のリクエストから待機時間が経過するまで待つ（ctx がキャンセルされた場合はすぐに戻る）
+func (c *Crawler) wait(ctx context.Context, url string) error {
+	// ディスク上のサイトはサーバーへの負荷がないため待たない
+	if c.servesLocally(url) {
 		return ctx.Err()
-	case <-time.After(c.delay):
 	}
+	return c.limiter.Wait(ctx, limiterKey(url))
+}
 
-	// シンプルなHTTPクライアントを作成
-	client := &http.Client{
-		Timeout: time.Duration(c.timeout) * time.Second,
+// crawlPage は1ページを取得してページ一覧に追加し、次にクロールするURLを返す
+func (c *Crawler) crawlPage(ctx context.Context, item crawlItem, pages *[]Page, mu *sync.Mutex) (*crawlOutcome, error) {
+	url, depth := item.url, item.depth
+	c.logger.Info("ページをクロール中", "depth", depth, "pending", c.PendingCount(), "url", url)
+
+	// バージョンが決まる前にキューに追加された別のバージョンのページは取得しない
+	if !c.matchesVersion(url) {
+		c.logger.Debug("別のバージョンのページのためスキップします", "url", url)
+		return &crawlOutcome{}, nil
 	}
 
-	// リクエストの設定
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return err
+	// HEADリクエストで本文を取得すべきか事前に確認する
+	if c.precheckEnabled {
+		if err := c.wait(ctx, url); err != nil {
+			return nil, err
+		}
+		ok, err := c.precheck(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return &crawlOutcome{}, nil
+		}
 	}
 
-	// ブラウザのUser-Agentを設定
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	// 遅延を入れる
+	if err := c.wait(ctx, url); err != nil {
+		return nil, err
+	}
 
-	// リクエストを送信
-	resp, err := client.Do(req)
+	// リクエストの設定（キャッシュがある場合は変更がないか確認する）
+	req, err := c.newRequest(ctx, url)
 	if err != nil {
-		return err
+		return nil, withKind(ErrorParse, err)
+	}
+	cached := c.loadCache(url)
+	if cached != nil {
+		cached.setConditionalHeaders(req)
 	}
-	defer resp.Body.Close()
 
-	// レスポンスボディを読み込む
-	body, err := io.ReadAll(resp.Body)
+	// リクエストを送信（一時的なネットワークエラーは再試行する）
+	resp, start, err := c.doWithRetry(ctx, req)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer resp.Body.Close()
+	fetchedAt := time.Now()
+	c.delayPolicy.Observe(start, resp.StatusCode, nil)
+	c.stats.observeResponse(url, fetchedAt.Sub(start))
+
+	// リダイレクト後の最終的なURLをページのURLとして扱う
+	var requestedURL string
+	if finalURL := resp.Request.URL.String(); finalURL != url {
+		// 経由したURLへの直接のリンクも同じページのため、再び取得しないよう訪問済みにする
+		for _, hop := range redirectHops(resp) {
+			c.markVisited(hop)
+		}
+		if !c.inScope(finalURL) {
+			c.logger.Info("別のホストへリダイレクトされたためスキップします", "url", url, "location", finalURL)
+			c.stats.skip(SkipOffsiteRedirect)
+			return &crawlOutcome{}, nil
+		}
+		if !c.matchesVersion(finalURL) {
+			c.logger.Info("別のバージョンのページへリダイレクトされたためスキップします", "url", url, "location", finalURL)
+			c.stats.skip(SkipOffsiteRedirect)
+			return &crawlOutcome{}, nil
+		}
+		// 正規化すると元のURLと同じになるリダイレクト先は、元のURLとして訪問済みになっている
+		// キューで取得を待っているリダイレクト先は、この応答をそのページとして扱いキューから取り除く
+		if c.normalizer.normalize(finalURL) != c.normalizer.normalize(url) && !c.markVisited(finalURL) && !c.unqueue(finalURL) {
+			c.logger.Info("リダイレクト先は訪問済みのためスキップします", "url", url, "location", finalURL)
+			c.stats.skip(SkipVisitedRedirect)
+			return &crawlOutcome{}, nil
+		}
+		requestedURL, url = url, finalURL
+	}
+
+	// 401・403は認証が必要なページとして数える
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		c.stats.authDenied.Add(1)
+		c.stats.skip(SkipAuthDenied)
+	}
+
+	// 認証情報を送っても401が返る場合は認証に失敗している
+	if resp.StatusCode == http.StatusUnauthorized && c.hasCredentials() {
+		return nil, withKind(ErrorHTTPStatus, fmt.Errorf("認証に失敗しました (401 Unauthorized): %s", url))
+	}
+
+	// エラーを示すステータスコードのページは収集しない
+	if resp.StatusCode >= 400 {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// レスポンスボディを読み込む（304の場合はキャッシュの内容を使う）
+	var body []byte
+	var downloaded int64
+	var truncated bool
+	statusCode := resp.StatusCode
+	contentType := resp.Header.Get("Content-Type")
+	lastModified := resp.Header.Get("Last-Modified")
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		c.logger.Debug("変更がないためキャッシュを使用します", "url", url)
+		c.stats.cacheHits.Add(1)
+		body, statusCode, contentType, lastModified = cached.Body, cached.StatusCode, cached.ContentType, cached.LastModified
+	} else {
+		body, truncated, err = readResponseBody(resp, c.maxBodySize)
+		if err != nil {
+			return nil, err
+		}
+		downloaded = int64(len(body))
+		c.stats.bytesDownloaded.Add(downloaded)
+		if truncated {
+			if c.skipOversized {
+				c.logger.Warn("レスポンスが上限を超えたためスキップします", "url", url, "max_bytes", c.maxBodySize)
+				c.stats.skip(SkipOversized)
+				return &crawlOutcome{}, nil
+			}
+			c.logger.Warn("レスポンスが上限を超えたため切り詰めます", "url", url, "max_bytes", c.maxBodySize)
+		} else if resp.StatusCode == http.StatusOK {
+			c.storeCache(item.url, resp, body)
+		}
+	}
+
+	// 取得にかかった時間と受信したバイト数
+	duration := time.Since(start)
+	c.stats.observeFetch(duration)
+	c.logger.Debug("ページを取得しました", "url", url, "duration_ms", duration.Milliseconds(), "bytes", downloaded)
+
+	// PDFは保存して本文のテキストを抽出する
+	if c.isPDFResponse(contentType) {
+		c.collectPDF(item, url, requestedURL, pdfFetch{
+			body:       body,
+			statusCode: statusCode,
+			fetchedAt:  fetchedAt,
+			duration:   duration,
+			downloaded: downloaded,
+			truncated:  truncated,
+		}, pages, mu)
+		return &crawlOutcome{}, nil
+	}
+
+	// 元のHTMLを保存
+	c.saveSnapshot(url, statusCode, fetchedAt, body)
+
+	// 文字コードをUTF-8に変換
+	body = toUTF8(body, contentType)
+
+	// JavaScriptで内容を生成するページはヘッドレスブラウザで描画したDOMを使う
+	waitForMissed := false
+	if c.shouldRender(statusCode, contentType) {
+		var found bool
+		if body, found, err = c.renderPage(ctx, url); err != nil {
+			return nil, err
+		}
+		waitForMissed = !found
 	}
 
 	// HTMLを解析
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(body)))
 	if err != nil {
-		return err
+		return nil, withKind(ErrorParse, err)
+	}
+
+	// 指定された言語ではないページは収集しない（別の言語の版があればそちらを取得する）
+	if ok, translation := c.checkPageLang(doc, url); !ok {
+		c.logger.Info("言語が一致しないためスキップします", "url", url, "translation", translation)
+		c.stats.skip(SkipLanguage)
+		return &crawlOutcome{translation: translation}, nil
 	}
 
 	// タイトルを取得
 	title := doc.Find("title").Text()
-	fmt.Printf("タイトル: %s\n", title)
+	c.logger.Debug("タイトル", "title", title)
 
-	// HTMLをプレーンテキストに変換
-	textContent := extractText(doc)
+	// 正規URLを取得し、同じ正規URLのページが収集済みならスキップ
+	canonicalURL := extractCanonical(doc, url)
+	if depth == 0 {
+		c.detectVersion(doc, url, canonicalURL)
+		c.discoverScopeRoot(doc, url)
+	}
+	key := stripFragment(url)
+	if canonicalURL != "" {
+		key = canonicalURL
+	}
+	c.mu.Lock()
+	if c.collected[key] {
+		c.mu.Unlock()
+		c.logger.Info("正規URLが同じページを収集済みのためスキップします", "url", url, "canonical", key)
+		c.stats.skip(SkipDuplicateCanonical)
+		return &crawlOutcome{}, nil
+	}
+	c.collected[key] = true
+	c.collected[stripFragment(url)] = true
+	c.mu.Unlock()
+
+	// meta refreshによる転送を検出し、内容のない転送用ページは収集しない
+	outcome := &crawlOutcome{}
+	if target, ok := extractMetaRefresh(doc, url); ok && sameSite(url, target) {
+		outcome.refresh = target
+		if isStubPage(doc) {
+			c.logger.Info("meta refreshによる転送ページのためスキップします", "url", url, "location", target)
+			c.stats.skip(SkipRefreshStub)
+			return outcome, nil
+		}
+	}
+
+	// 自動生成の索引ページなどタイトルで除外するページは収集せず、指定があればリンクのみたどる
+	if c.skipsTitle(title) {
+		c.logger.Info("タイトルが除外するパターンに一致するためスキップします", "url", url, "title", title)
+		c.stats.skip(SkipTitle)
+		if !c.skipTitleFollow {
+			return outcome, nil
+		}
+		return c.collectLinks(doc, item, url, outcome), nil
+	}
+
+	// 「このページを編集」リンクを取得
+	sourceEditURL := extractEditURL(doc, url)
+
+	// 説明とOpenGraphの情報を取得
+	meta := extractMeta(doc)
+	pageTitle := c.displayTitle(title, meta)
+
+	// 元のMarkdownソースが取得できればフロントマター以外をそのまま使い、できなければHTMLをプレーンテキストに変換
+	// タイトルは Page.Title にあるためソースには加えない。見出しの一覧はどちらの場合もアンカーのあるHTMLから抽出する
+	// ドライランではリンクの探索のみを行い、コンテンツは抽出しない
+	textContent, fromSource := "", false
+	var headings []Heading
+	if c.preferSourceMarkdown && !c.dryRun {
+		textContent, fromSource = c.fetchSourceMarkdown(ctx, url, sourceEditURL)
+	}
+	if !c.dryRun {
+		content := c.contentDocument(doc)
+		converter := c.newConverter(url)
+		extracted := extractText(pageTitle, c.contentRoot(content, url), converter)
+		if !fromSource {
+			textContent = extracted
+		}
+		headings = converter.headings
+	}
 
 	// 結果を表示
-	fmt.Printf("テキストコンテンツサイズ: %d bytes\n", len(textContent))
+	c.logger.Debug("テキストコンテンツサイズ", "url", url, "bytes", len(textContent))
+
+	// 200で返された「ページが見つかりません」のページは収集せず、取得に失敗したURLとして記録する
+	if !c.dryRun && c.isSoft404(title, textContent) {
+		c.logger.Warn("ページが見つからないことを示す内容のためスキップします", "url", url, "title", title)
+		c.stats.skip(SkipSoft404)
+		c.recordError(item, errSoft404)
+		return &crawlOutcome{}, nil
+	}
 
 	// ページを追加（スレッドセーフに）
-	mu.Lock()
-	*pages = append(*pages, Page{
-		URL:     url,
-		Title:   title,
-		Content: textContent,
-		Depth:   depth,
+	c.appendPage(pages, mu, Page{
+		URL:           url,
+		RequestedURL:  requestedURL,
+		Title:         pageTitle,
+		Content:       textContent,
+		Depth:         depth,
+		Seed:          item.seed,
+		StatusCode:    statusCode,
+		FetchedAt:     fetchedAt,
+		LastModified:  parseLastModified(lastModified),
+		DurationMS:    duration.Milliseconds(),
+		Bytes:         downloaded,
+		CanonicalURL:  canonicalURL,
+		SourceEditURL: sourceEditURL,
+		FromSource:    fromSource,
+		WaitForMissed: waitForMissed,
+		Headings:      headings,
+		Description:   meta.description,
+		OGTitle:       meta.ogTitle,
+		ModifiedAt:    meta.modifiedAt,
+		Breadcrumbs:   extractBreadcrumbs(doc),
+		Outline:       BuildHeadingTree(headings),
 	})
-	mu.Unlock()
 
-	// 同じドメイン内のリンクを収集して再帰的にクロール
-	baseURL, err := parseBaseURL(url)
-	if err != nil {
-		return err
+	return c.collectLinks(doc, item, url, outcome), nil
+}
+
+// collectLinks はページから次にクロールするリンクやページ送りの次のページを outcome に追加する
+func (c *Crawler) collectLinks(doc *goquery.Document, item crawlItem, url string, outcome *crawlOutcome) *crawlOutcome {
+	// 開始ページ（指定があればすべてのページ）のナビゲーションの順序を記録する
+	if c.navSelector != "" && (item.depth == 0 || c.navEveryPage) {
+		c.recordNavOrder(doc, url)
+	}
+
+	// サイトマップのみのモードやURLリストのモードではリンクをたどらない
+	if !c.discoversLinks() {
+		return outcome
+	}
+
+	// ページ送りの次のページ
+	if next, ok := extractNextLink(doc, url); ok && c.inScope(next) {
+		outcome.next = next
 	}
 
-	var links []string
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
+	if c.followFrames {
+		outcome.frames = c.frameSources(doc, url)
+	}
+
+	// いずれかの開始URLと同じドメイン内のリンクを収集（相対リンクはページのURLを基準に解決する）
+	c.linkAnchors(doc, url).Each(func(i int, s *goquery.Selection) {
 		if href, exists := s.Attr("href"); exists {
-			nextURL, err := resolveURL(baseURL, href)
+			nextURL, err := resolveURL(url, href)
 			if err != nil {
 				return
 			}
+			c.recordLink(url, nextURL)
 
-			// 同じドメインのURLのみを処理
-			if strings.HasPrefix(nextURL, baseURL) {
-				links = append(links, nextURL)
+			// 開始URLと同じドメインのURLのみを処理（印刷用やAMP版のページ、バイナリファイルは除く）
+			if c.inScope(nextURL) && (c.keepVariants || !isVariantURL(nextURL)) && !c.hasSkippedExtension(nextURL) && c.matchesLangPath(nextURL) {
+				outcome.links = append(outcome.links, nextURL)
 			}
 		}
 	})
 
-	// 各リンクを順番にクロール
-	for _, link := range links {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			if err := c.crawlRecursive(ctx, link, depth+1, pages, mu); err != nil {
-				if err == context.DeadlineExceeded {
-					return err
-				}
-				fmt.Printf("警告: %sのクロール中にエラーが発生: %v\n", link, err)
+	return outcome
+}
+
+// linksFromSource はリンクを収集する要素のセレクタの指定元
+const linksFromSource = "--links-from"
+
+// linkAnchors はリンクを収集する<a>要素を返す
+// セレクタが指定されている場合は一致した要素内の<a>のみを対象とし、一致しないページではページ全体から収集する
+func (c *Crawler) linkAnchors(doc *goquery.Document, url string) *goquery.Selection {
+	if c.linksFrom == "" {
+		return doc.Find("a")
+	}
+	containers := doc.Find(c.linksFrom)
+	c.selectorStats.Record(linksFromSource, c.linksFrom, containers.Length())
+	if containers.Length() == 0 {
+		c.logger.Debug("リンクを収集する要素が見つからないため、ページ全体からリンクを収集します", "url", url, "selector", c.linksFrom)
+		return doc.Find("a")
+	}
+	return containers.Find("a").AddSelection(containers.Filter("a"))
+}
+
+// appendPage は取得したページを追加する（スレッドセーフに）
+// 同じ内容のページが収集済みの場合は追加せず、既存のページに別URLとして記録する
+func (c *Crawler) appendPage(pages *[]Page, mu *sync.Mutex, page Page) {
+	mu.Lock()
+	defer mu.Unlock()
+	hash := contentHash(page.Content)
+	if idx, exists := c.contentHashes[hash]; exists && c.contentDedup && !c.dryRun {
+		(*pages)[idx].AliasURLs = append((*pages)[idx].AliasURLs, page.URL)
+		c.logger.Info("同じ内容のページを収集済みのため別URLとして記録します", "url", page.URL, "existing", (*pages)[idx].URL)
+		c.stats.skip(SkipDuplicateContent)
+		return
+	}
+
+	// タイムスタンプやフッターだけが異なるほぼ同じ内容のページ
+	fingerprint, ok := simhash(page.Content)
+	if ok && c.nearDupThreshold > 0 && !c.dryRun {
+		if idx, found := c.findNearDuplicate(fingerprint); found {
+			c.stats.skip(SkipNearDuplicate)
+			if c.nearDupAction == NearDupSkip {
+				c.logger.Info("ほぼ同じ内容のページを収集済みのためスキップします", "url", page.URL, "existing", (*pages)[idx].URL)
+				return
 			}
+			(*pages)[idx].AliasURLs = append((*pages)[idx].AliasURLs, page.URL)
+			c.logger.Info("ほぼ同じ内容のページを収集済みのため別URLとして記録します", "url", page.URL, "existing", (*pages)[idx].URL)
+			return
 		}
 	}
+	if ok {
+		c.fingerprints = append(c.fingerprints, pageFingerprint{hash: fingerprint, index: len(*pages)})
+	}
+	c.contentHashes[hash] = len(*pages)
+	*pages = append(*pages, page)
+	c.stats.pagesFetched.Add(1)
+}
 
-	return nil
+// newRequest はクローラー共通のヘッダーを設定したGETリクエストを作成する
+func (c *Crawler) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	return c.newRequestWithBody(ctx, "GET", url, nil)
+}
+
+// newRequestWithBody はクローラー共通のヘッダーを設定したリクエストを作成する
+func (c *Crawler) newRequestWithBody(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept-Language", c.acceptLang)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	// ユーザー指定のヘッダーを設定（同名のヘッダーは上書きする）
+	for name, values := range c.headers {
+		req.Header.Del(name)
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	// 認証情報は開始URLのホストにのみ送信する
+	if c.isBaseHost(req.URL.Host) {
+		switch {
+		case c.token != "":
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		case c.authUser != "":
+			req.SetBasicAuth(c.authUser, c.authPass)
+		}
+	}
+	return req, nil
+}
+
+// hasCredentials は認証情報が設定されているかを返す
+func (c *Crawler) hasCredentials() bool {
+	return c.authUser != "" || c.token != ""
+}
+
+// isBaseHost はホストがいずれかの開始URLのホストと一致するかを返す
+func (c *Crawler) isBaseHost(host string) bool {
+	for _, seed := range c.seeds {
+		base, err := url.Parse(seed)
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(base.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// inScope はURLがいずれかの開始URLと同じスキームとホストかを返す（クロール範囲のルートがある場合はその配下のみ）
+func (c *Crawler) inScope(link string) bool {
+	return c.seedFor(link) != "" && c.inScopeRoot(link)
+}
+
+// seedFor はURLを範囲に含む最初の開始URLを返す（範囲外の場合は空）
+// file:// の開始URLはホストを持たないため、開始URLのディレクトリ以下を範囲とする
+func (c *Crawler) seedFor(link string) string {
+	for _, seed := range c.seeds {
+		baseURL, err := parseBaseURL(seed)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(seed, "file://") {
+			baseURL = seed[:strings.LastIndex(seed, "/")+1]
+		}
+		if strings.HasPrefix(link, baseURL) {
+			return seed
+		}
+	}
+	return ""
+}
+
+// AcceptLanguage はリクエストに設定したAccept-Languageを返す
+func (c *Crawler) AcceptLanguage() string {
+	return c.acceptLang
+}
+
+// Seeds は開始URLの一覧を返す
+func (c *Crawler) Seeds() []string {
+	return c.seeds
 }
 
 // parseBaseURL はURLからベースURLを抽出する
@@ -233,154 +1212,76 @@ func resolveURL(base, ref string) (string, error) {
 	return resolvedURL.String(), nil
 }
 
-// extractText はHTMLドキュメントからプレーンテキストを抽出する
-func extractText(doc *goquery.Document) string {
-	var sb strings.Builder
-
-	// タイトルを抽出
-	title := doc.Find("title").Text()
-	sb.WriteString("# " + title + "\n\n")
-
-	// ヘッダーを抽出して見出しとして追加
-	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
-		text := strings.TrimSpace(s.Text())
-		if text != "" {
-			// ヘッダーレベルを取得
-			nodeName := goquery.NodeName(s)
-			level := int(nodeName[1] - '0')
-			prefix := strings.Repeat("#", level) + " "
-
-			sb.WriteString("\n" + prefix + text + "\n")
-		}
-	})
-
-	// 段落を抽出
-	doc.Find("p").Each(func(i int, s *goquery.Selection) {
-		text := strings.TrimSpace(s.Text())
-		if text != "" {
-			sb.WriteString("\n" + text + "\n")
-		}
-	})
-
-	// リストを抽出
-	doc.Find("ul, ol").Each(func(i int, s *goquery.Selection) {
-		sb.WriteString("\n")
-		s.Find("li").Each(func(j int, li *goquery.Selection) {
-			text := strings.TrimSpace(li.Text())
-			if text != "" {
-				sb.WriteString("* " + text + "\n")
-			}
-		})
-	})
-
-	// テーブルを抽出
-	doc.Find("table").Each(func(i int, s *goquery.Selection) {
-		sb.WriteString("\n[テーブル]\n")
-		s.Find("tr").Each(func(j int, tr *goquery.Selection) {
-			var cells []string
-			tr.Find("th, td").Each(func(k int, cell *goquery.Selection) {
-				text := strings.TrimSpace(cell.Text())
-				cells = append(cells, text)
-			})
-			sb.WriteString(strings.Join(cells, " | ") + "\n")
-		})
-	})
-
-	// コードブロックを抽出
-	doc.Find("pre, code").Each(func(i int, s *goquery.Selection) {
-		text := strings.TrimSpace(s.Text())
-		if text != "" {
-			sb.WriteString("\n```\n" + text + "\n```\n")
-		}
-	})
-
-	return sb.String()
-}
+// extractCanonical は<link rel="canonical">から正規URLを取得する
+// 別ドメインを指す場合や自身を指す場合は空文字を返す
+func extractCanonical(doc *goquery.Document, pageURL string) string {
+	href, exists := doc.Find(`link[rel="canonical"]`).First().Attr("href")
+	if !exists || strings.TrimSpace(href) == "" {
+		return ""
+	}
 
-// GenerateTXT はクロールしたページからTXTファイルを生成する
-func (c *Crawler) GenerateTXT(pages []Page, outputPath string) error {
-	if len(pages) == 0 {
-		return fmt.Errorf("生成するページがありません")
+	canonical, err := resolveURL(pageURL, strings.TrimSpace(href))
+	if err != nil {
+		return ""
 	}
+	canonical = stripFragment(canonical)
 
-	// 出力ディレクトリを作成
-	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+	// 別ドメインの正規URLはクロール範囲に影響させない
+	pageBase, err := parseBaseURL(pageURL)
+	if err != nil {
+		return ""
+	}
+	canonicalBase, err := parseBaseURL(canonical)
+	if err != nil || canonicalBase != pageBase {
+		return ""
 	}
 
-	// 拡張子が.txtでない場合は変更
-	if !strings.HasSuffix(strings.ToLower(outputPath), ".txt") {
-		outputPath = outputPath + ".txt"
+	// 自身を指す正規URLは何もしない
+	if canonical == stripFragment(pageURL) {
+		return ""
 	}
+	return canonical
+}
 
-	// テキストファイルを作成
-	file, err := os.Create(outputPath)
+// parseLastModified はLast-Modifiedヘッダーを解析する（ない場合や不正な場合はゼロ値）
+func parseLastModified(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := http.ParseTime(value)
 	if err != nil {
-		return fmt.Errorf("テキストファイルの作成に失敗しました: %w", err)
+		return time.Time{}
 	}
-	defer file.Close()
-
-	// ヘッダー情報を書き込み
-	fmt.Fprintf(file, "# クロール結果\n")
-	fmt.Fprintf(file, "# 開始URL: %s\n", c.baseURL)
-	fmt.Fprintf(file, "# 取得日時: %s\n", time.Now().Format("2006-01-02 15:04:05"))
-	fmt.Fprintf(file, "# 取得ページ数: %d\n\n", len(pages))
+	return t
+}
 
-	// 各ページの内容を書き込み
-	for i, page := range pages {
-		fmt.Fprintf(file, "\n%s\n", strings.Repeat("=", 80))
-		fmt.Fprintf(file, "# ページ %d/%d\n", i+1, len(pages))
-		fmt.Fprintf(file, "# URL: %s\n", page.URL)
-		fmt.Fprintf(file, "# 深度: %d\n", page.Depth)
-		fmt.Fprintf(file, "%s\n", strings.Repeat("=", 80))
-		fmt.Fprintln(file)
+// contentHash は抽出したテキストのSHA-256ハッシュを返す
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
 
-		// コンテンツをクリーンアップして書き込み
-		cleanedContent := cleanupTextContent(page.Content)
-		fmt.Fprintln(file, cleanedContent)
-		fmt.Fprintln(file)
+// stripFragment はURLからフラグメント（#以降）を取り除く
+func stripFragment(urlStr string) string {
+	if idx := strings.Index(urlStr, "#"); idx != -1 {
+		return urlStr[:idx]
 	}
-
-	// 成功メッセージを表示
-	fmt.Printf("テキストファイルが生成されました: %s\n", outputPath)
-	return nil
+	return urlStr
 }
 
-// cleanupTextContent はテキストコンテンツを整形する
-func cleanupTextContent(content string) string {
-	// 改行を統一（Windowsの CRLF を LF に変換）
-	content = strings.ReplaceAll(content, "\r\n", "\n")
-	
-	// 連続する空白行を削除
-	for strings.Contains(content, "\n\n\n") {
-		content = strings.ReplaceAll(content, "\n\n\n", "\n\n")
-	}
-	
-	// 先頭と末尾の空白を削除
-	content = strings.TrimSpace(content)
-	
-	// 行末の空白を削除
-	lines := strings.Split(content, "\n")
-	for i, line := range lines {
-		lines[i] = strings.TrimRight(line, " \t")
-	}
-	
-	// 連続する空行を最大1行に制限
-	var result []string
-	prevEmpty := false
-	
-	for _, line := range lines {
-		isEmpty := len(strings.TrimSpace(line)) == 0
-		
-		if isEmpty && prevEmpty {
-			// 連続する空行をスキップ
-			continue
-		}
-		
-		result = append(result, line)
-		prevEmpty = isEmpty
+// extractText はHTMLドキュメントのタイトルと root の要素の本文をMarkdownに変換する
+// リンク・強調・インラインのコード・画像を含め、要素をページ内に現れる順に書き出す（リンクを脚注にする場合はページの末尾に一覧を書き出す）
+func extractText(title string, root *goquery.Selection, converter *markdownConverter) string {
+	var sb strings.Builder
+
+	sb.WriteString("# " + title + "\n\n")
+
+	for _, node := range root.Nodes {
+		sb.WriteString(joinBlocks(converter.blocks(node)))
 	}
-	
-	return strings.Join(result, "\n")
+	if notes := converter.footnoteList(); notes != "" {
+		sb.WriteString("\n\n" + notes)
+	}
+	sb.WriteString("\n")
+	return sb.String()
 }
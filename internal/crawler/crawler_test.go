@@ -0,0 +1,373 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chainSite は /p/0 → /p/1 → … と1つずつリンクをたどるページを n 件返すサーバーを起動する
+// 各ページは先頭のページと自身にもリンクし、リクエストを受け付けた時刻を記録する
+func chainSite(t *testing.T, n int) (*testSite, func() []time.Time) {
+	t.Helper()
+	var mu sync.Mutex
+	var times []time.Time
+	site := newTestSite(t, func(w http.ResponseWriter, r *http.Request) {
+		i, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/p/"))
+		if err != nil || i < 0 || i >= n {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		links := []string{"/p/0", r.URL.Path, r.URL.Path + "#top"}
+		if i+1 < n {
+			links = append(links, fmt.Sprintf("/p/%d", i+1))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, htmlPage(fmt.Sprintf("Page %d", i), strings.Repeat(fmt.Sprintf("chapter%d ", i), i%7+1), links...))
+	})
+	return site, func() []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]time.Time(nil), times...)
+	}
+}
+
+func TestCrawlLongChain(t *testing.T) {
+	const n = 200
+	site, requestTimes := chainSite(t, n)
+
+	c := New(site.URL+"/p/0", n, 10, 0.005, 0, WithLogger(discardLogger()))
+	result, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Pages) != n {
+		t.Fatalf("取得したページ = %d 件, want %d", len(result.Pages), n)
+	}
+	for i, page := range result.Pages {
+		path := fmt.Sprintf("/p/%d", i)
+		if got := strings.TrimPrefix(page.URL, site.URL); got != path {
+			t.Fatalf("%d 番目のページ = %s, want %s", i, got, path)
+		}
+		if page.Depth != i {
+			t.Errorf("%s の深度 = %d, want %d", path, page.Depth, i)
+		}
+		if hits := site.count(path); hits != 1 {
+			t.Errorf("%s へのリクエスト = %d 回, want 1", path, hits)
+		}
+	}
+	if !result.Complete {
+		t.Error("すべてのページを取得したのに完了していないとされました")
+	}
+
+	// 同じホストへのリクエストは --delay の間隔で予約するため、i 件目は最初のリクエストから i 回分以上後になる
+	times := requestTimes()
+	for i := 1; i < len(times); i++ {
+		if elapsed, want := times[i].Sub(times[0]), time.Duration(i)*5*time.Millisecond; elapsed < want-2*time.Millisecond {
+			t.Fatalf("%d 件目のリクエストは最初から %v 後, want >= %v", i, elapsed, want)
+		}
+	}
+}
+
+func TestCrawlLongChainDepthLimit(t *testing.T) {
+	site, _ := chainSite(t, 200)
+
+	c := New(site.URL+"/p/0", 50, 10, 0, 0, WithLogger(discardLogger()))
+	result, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Pages) != 51 {
+		t.Fatalf("取得したページ = %d 件, want 51（深度0〜50）", len(result.Pages))
+	}
+	if last := result.Pages[len(result.Pages)-1]; last.Depth != 50 {
+		t.Errorf("最も深いページの深度 = %d, want 50", last.Depth)
+	}
+	if hits := site.count("/p/51"); hits != 0 {
+		t.Errorf("最大深度を超えるページ /p/51 を %d 回取得しました", hits)
+	}
+}
+
+func TestCrawlErrorNearDeadline(t *testing.T) {
+	// 開始ページは取得でき、次のページは期限の直前に500を返す
+	site := newTestSite(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, htmlPage("Top", "top page", "/broken"))
+		case "/broken":
+			time.Sleep(150 * time.Millisecond)
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	c := New(site.URL+"/", 3, 10, 0, 0, WithLogger(discardLogger()), WithFailFast(true))
+	start := time.Now()
+	result, err := c.Crawl(ctx)
+	elapsed := time.Since(start)
+
+	if err == nil || !strings.Contains(err.Error(), "/broken") {
+		t.Errorf("期限の直前に起きたエラーが返されませんでした: %v", err)
+	}
+	if result == nil || len(result.Pages) != 1 {
+		t.Errorf("取得済みのページが返されませんでした: %+v", result)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Crawl が戻るまで %v かかりました", elapsed)
+	}
+}
+
+func TestCrawlReturnsPromptlyOnDeadline(t *testing.T) {
+	// 次のページの応答が期限を過ぎても返らない場合も、取得済みのページを返してすぐに戻る
+	release := make(chan struct{})
+	defer close(release)
+	site := newTestSite(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, htmlPage("Top", "top page", "/hang"))
+		case "/hang":
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	c := New(site.URL+"/", 3, 10, 0, 0, WithLogger(discardLogger()))
+	start := time.Now()
+	result, err := c.Crawl(ctx)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Errorf("期限による終了がエラーになりました: %v", err)
+	}
+	if result == nil || len(result.Pages) != 1 || result.Complete {
+		t.Errorf("取得済みのページ = %+v", result)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Crawl が戻るまで %v かかりました", elapsed)
+	}
+}
+
+// slowChainSite は /p/0 → /p/1 → … と限りなく続くページを、1件ごとに delay 待ってから返すサーバーを起動する
+func slowChainSite(t *testing.T, delay time.Duration) *testSite {
+	t.Helper()
+	return newTestSite(t, func(w http.ResponseWriter, r *http.Request) {
+		i, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/p/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, htmlPage(fmt.Sprintf("Page %d", i), fmt.Sprintf("page number %d", i), fmt.Sprintf("/p/%d", i+1)))
+	})
+}
+
+func TestCrawlStopsAtTotalTime(t *testing.T) {
+	site := slowChainSite(t, 100*time.Millisecond)
+
+	c := New(site.URL+"/p/0", 1000, 10, 0, 1, WithLogger(discardLogger()))
+	start := time.Now()
+	result, err := c.Crawl(context.Background())
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("総時間の経過がエラーになりました: %v", err)
+	}
+	if elapsed < time.Second || elapsed > 2*time.Second {
+		t.Errorf("Crawl が戻るまで %v かかりました（総時間は1秒）", elapsed)
+	}
+	if n := len(result.Pages); n < 3 || n > 11 {
+		t.Errorf("取得済みのページ数 = %d", n)
+	}
+	if result.Complete {
+		t.Error("総時間で終了したクロールが Complete になっています")
+	}
+}
+
+func TestCrawlTotalTime(t *testing.T) {
+	// 0 は総時間の制限なし、0 より大きい値は十分に長ければ最後まで取得する
+	for _, totalTime := range []int{0, 3600} {
+		t.Run(strconv.Itoa(totalTime), func(t *testing.T) {
+			site, _ := chainSite(t, 20)
+
+			c := New(site.URL+"/p/0", 100, 10, 0, totalTime, WithLogger(discardLogger()))
+			result, err := c.Crawl(context.Background())
+			if err != nil {
+				t.Fatalf("Crawl: %v", err)
+			}
+			if len(result.Pages) != 20 {
+				t.Errorf("取得したページ数 = %d, want 20", len(result.Pages))
+			}
+			if !result.Complete {
+				t.Error("すべてのページを取得したクロールが Complete になっていません")
+			}
+		})
+	}
+}
+
+func TestCrawlRedirectTargetFetchedOnce(t *testing.T) {
+	site := newTestSite(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, htmlPage("Top", "top page", "/old", "/new"))
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, htmlPage("New", "moved page", "/"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	c := New(site.URL+"/", 3, 10, 0, 0, WithLogger(discardLogger()))
+	result, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if n := site.count("/new"); n != 1 {
+		t.Errorf("リダイレクト先の取得回数 = %d, want 1", n)
+	}
+	if got := pagePaths(site, result.Pages); strings.Join(got, ",") != "/,/new" {
+		t.Errorf("取得したページ = %v, want [/ /new]", got)
+	}
+}
+
+func TestCrawlCanceledReturnsPartialPages(t *testing.T) {
+	// /p/5 の応答を返した時点で中断する
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	site := newTestSite(t, func(w http.ResponseWriter, r *http.Request) {
+		i, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/p/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if i == 5 {
+			cancel()
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, htmlPage(fmt.Sprintf("Page %d", i), fmt.Sprintf("page number %d", i), fmt.Sprintf("/p/%d", i+1)))
+	})
+
+	c := New(site.URL+"/p/0", 100, 10, 0, 0, WithLogger(discardLogger()))
+	result, err := c.Crawl(ctx)
+	if err != nil {
+		t.Fatalf("中断がエラーになりました: %v", err)
+	}
+	if n := len(result.Pages); n < 5 || n > 6 {
+		t.Errorf("取得済みのページ数 = %d, want 5〜6", n)
+	}
+	if result.Complete {
+		t.Error("中断したクロールが Complete になっています")
+	}
+	if n := site.count("/p/7"); n != 0 {
+		t.Errorf("中断後に /p/7 を %d 回取得しました", n)
+	}
+}
+
+// failingSite は /b のみ500を返すサイトを起動する（開始ページは /a, /b, /c の順にリンクする）
+func failingSite(t *testing.T) *testSite {
+	t.Helper()
+	return newTestSite(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, htmlPage("Top", "top page", "/a", "/b", "/c"))
+		case "/a", "/c":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, htmlPage("Page "+r.URL.Path, "page "+r.URL.Path))
+		case "/b":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestCrawlContinuesOnError(t *testing.T) {
+	site := failingSite(t)
+
+	c := New(site.URL+"/", 3, 10, 0, 0, WithLogger(discardLogger()))
+	result, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("開始URL以外の失敗がエラーになりました: %v", err)
+	}
+	if got := strings.Join(pagePaths(site, result.Pages), ","); got != "/,/a,/c" {
+		t.Errorf("取得したページ = %s, want /,/a,/c", got)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].URL != site.URL+"/b" {
+		t.Errorf("取得に失敗したURL = %+v, want /b", result.Errors)
+	}
+}
+
+func TestCrawlFailFast(t *testing.T) {
+	site := failingSite(t)
+
+	c := New(site.URL+"/", 3, 10, 0, 0, WithLogger(discardLogger()), WithFailFast(true))
+	result, err := c.Crawl(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "/b") {
+		t.Errorf("/b の失敗で中止されませんでした: %v", err)
+	}
+	if got := strings.Join(pagePaths(site, result.Pages), ","); got != "/,/a" {
+		t.Errorf("取得済みのページ = %s, want /,/a", got)
+	}
+	if n := site.count("/c"); n != 0 {
+		t.Errorf("中止後に /c を %d 回取得しました", n)
+	}
+}
+
+func TestCrawlSeedFailure(t *testing.T) {
+	site := failingSite(t)
+
+	c := New(site.URL+"/b", 3, 10, 0, 0, WithLogger(discardLogger()))
+	result, err := c.Crawl(context.Background())
+	if err == nil {
+		t.Fatal("開始URLの失敗がエラーになりませんでした")
+	}
+	if result != nil && len(result.Pages) != 0 {
+		t.Errorf("取得済みのページ = %v", pagePaths(site, result.Pages))
+	}
+}
+
+func TestCrawlSelectorStats(t *testing.T) {
+	site := newHTMLSite(t, map[string]string{
+		"/":      htmlPage("Top", `top page <span class="ad">buy now</span>`, "/guide"),
+		"/guide": htmlPage("Guide", "guide page"),
+	})
+
+	c := New(site.URL+"/", 3, 10, 0, 0, WithLogger(discardLogger()), WithExcludeSelectors([]string{".ad", ".advert"}, false))
+	if _, err := c.Crawl(context.Background()); err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	var sb strings.Builder
+	c.SelectorStats().Report(&sb)
+	report := sb.String()
+	if !strings.Contains(report, "'.ad': 1 件一致 / 2 ページ") {
+		t.Errorf("一致したセレクタの集計がありません:\n%s", report)
+	}
+	if !strings.Contains(report, "'.advert' は 2 ページで1件も一致しませんでした") {
+		t.Errorf("一致しなかったセレクタの警告がありません:\n%s", report)
+	}
+}
@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// slowResponseThreshold はこれより遅い応答を混雑とみなして待機時間を伸ばすしきい値
+	slowResponseThreshold = 2 * time.Second
+	// adaptiveMaxFactor は適応的な待機時間の上限（基準の待機時間に対する倍率）
+	adaptiveMaxFactor = 10
+	// adaptiveMinCeiling は基準の待機時間が短い場合でも伸ばせる待機時間の上限
+	adaptiveMinCeiling = 5 * time.Second
+	// adaptiveGrowth と adaptiveShrink は混雑時と快調時に待機時間に掛ける倍率
+	adaptiveGrowth = 1.5
+	adaptiveShrink = 0.8
+)
+
+// delayPolicy はリクエスト間の待機時間を決める
+// jitter で待機時間をランダムに揺らし、adaptive が有効な場合はサーバーの応答に応じて待機時間を伸縮する
+// 乱数と時計は差し替えられるため、決まった値で動作を確認できる
+type delayPolicy struct {
+	base     time.Duration // 指定された待機時間
+	jitter   float64       // 待機時間を揺らす割合（0.5で±50%）
+	adaptive bool          // 応答時間やエラーに応じて待機時間を伸縮するか
+
+	rand func() float64   // [0, 1) の乱数
+	now  func() time.Time // 現在時刻
+
+	mu      sync.Mutex
+	current time.Duration // 適応的に調整された現在の待機時間
+}
+
+// newDelayPolicy は新しいdelayPolicyを作成する
+func newDelayPolicy(base time.Duration, jitter float64, adaptive bool) *delayPolicy {
+	return &delayPolicy{
+		base:     base,
+		jitter:   jitter,
+		adaptive: adaptive,
+		rand:     rand.Float64,
+		now:      time.Now,
+		current:  base,
+	}
+}
+
+// Next は次のリクエストまでの待機時間を返す
+func (p *delayPolicy) Next() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	d := p.current
+	if p.jitter > 0 {
+		// [1-jitter, 1+jitter) の範囲で揺らす
+		d = time.Duration(float64(d) * (1 + p.jitter*(2*p.rand()-1)))
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// Observe は start に送信したリクエストの結果を記録し、適応的な待機時間を調整する
+// 応答が遅い場合やエラー、429・5xxの場合は待機時間を伸ばし、快調な場合は基準の待機時間まで縮める
+func (p *delayPolicy) Observe(start time.Time, statusCode int, err error) {
+	if !p.adaptive {
+		return
+	}
+	elapsed := p.now().Sub(start)
+	congested := err != nil || elapsed > slowResponseThreshold ||
+		statusCode == http.StatusTooManyRequests || statusCode >= 500
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if congested {
+		next := time.Duration(float64(p.current) * adaptiveGrowth)
+		if p.current == 0 {
+			next = time.Second
+		}
+		limit := p.base * adaptiveMaxFactor
+		if limit < adaptiveMinCeiling {
+			limit = adaptiveMinCeiling
+		}
+		if next > limit {
+			next = limit
+		}
+		p.current = next
+		return
+	}
+	next := time.Duration(float64(p.current) * adaptiveShrink)
+	if next < p.base {
+		next = p.base
+	}
+	p.current = next
+}
@@ -0,0 +1,110 @@
+package crawler
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newTestDelayPolicy は乱数を r、時計を now に固定したdelayPolicyを作成する
+func newTestDelayPolicy(base time.Duration, jitter float64, adaptive bool, r float64, now time.Time) *delayPolicy {
+	p := newDelayPolicy(base, jitter, adaptive)
+	p.rand = func() float64 { return r }
+	p.now = func() time.Time { return now }
+	return p
+}
+
+func TestDelayPolicyJitter(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		r    float64
+		want time.Duration
+	}{
+		{r: 0, want: 500 * time.Millisecond},
+		{r: 0.5, want: time.Second},
+		{r: 0.75, want: 1250 * time.Millisecond},
+		{r: 0.999999, want: 1499999 * time.Microsecond},
+	}
+	for _, tt := range tests {
+		p := newTestDelayPolicy(time.Second, 0.5, false, tt.r, now)
+		if got := p.Next(); got.Round(time.Microsecond) != tt.want {
+			t.Errorf("rand = %v: Next() = %v, want %v", tt.r, got, tt.want)
+		}
+	}
+
+	// 揺らさない場合は乱数によらず指定された待機時間になる
+	p := newTestDelayPolicy(time.Second, 0, false, 0.9, now)
+	if got := p.Next(); got != time.Second {
+		t.Errorf("Next() = %v, want %v", got, time.Second)
+	}
+}
+
+func TestDelayPolicyAdaptive(t *testing.T) {
+	now := time.Now()
+	p := newTestDelayPolicy(time.Second, 0, true, 0, now)
+	observe := func(elapsed time.Duration, status int, err error) time.Duration {
+		p.Observe(now.Add(-elapsed), status, err)
+		return p.Next()
+	}
+
+	steps := []struct {
+		name    string
+		elapsed time.Duration
+		status  int
+		err     error
+		want    time.Duration
+	}{
+		{name: "429", elapsed: 100 * time.Millisecond, status: http.StatusTooManyRequests, want: 1500 * time.Millisecond},
+		{name: "503", elapsed: 100 * time.Millisecond, status: http.StatusServiceUnavailable, want: 2250 * time.Millisecond},
+		{name: "遅い応答", elapsed: 3 * time.Second, status: http.StatusOK, want: 3375 * time.Millisecond},
+		{name: "ネットワークエラー", elapsed: 100 * time.Millisecond, err: errors.New("connection reset"), want: 5062500 * time.Microsecond},
+		{name: "上限", elapsed: 100 * time.Millisecond, status: http.StatusBadGateway, want: 7593750 * time.Microsecond},
+		{name: "快調な応答", elapsed: 100 * time.Millisecond, status: http.StatusOK, want: 6075 * time.Millisecond},
+	}
+	for _, step := range steps {
+		if got := observe(step.elapsed, step.status, step.err); got != step.want {
+			t.Errorf("%s: Next() = %v, want %v", step.name, got, step.want)
+		}
+	}
+
+	// 伸ばせるのは基準の10倍まで、縮めるのは基準の待機時間まで
+	for range 20 {
+		observe(0, http.StatusTooManyRequests, nil)
+	}
+	if got := p.Next(); got != 10*time.Second {
+		t.Errorf("混雑が続いた場合の待機時間 = %v, want %v", got, 10*time.Second)
+	}
+	for range 50 {
+		observe(0, http.StatusOK, nil)
+	}
+	if got := p.Next(); got != time.Second {
+		t.Errorf("快調な応答が続いた場合の待機時間 = %v, want %v", got, time.Second)
+	}
+}
+
+func TestDelayPolicyAdaptiveFromZero(t *testing.T) {
+	now := time.Now()
+	p := newTestDelayPolicy(0, 0, true, 0, now)
+
+	// 待機時間が0の場合も混雑すれば1秒から伸ばし、上限は5秒になる
+	p.Observe(now, http.StatusTooManyRequests, nil)
+	if got := p.Next(); got != time.Second {
+		t.Errorf("Next() = %v, want %v", got, time.Second)
+	}
+	for range 20 {
+		p.Observe(now, http.StatusTooManyRequests, nil)
+	}
+	if got := p.Next(); got != adaptiveMinCeiling {
+		t.Errorf("Next() = %v, want %v", got, adaptiveMinCeiling)
+	}
+}
+
+func TestDelayPolicyNotAdaptive(t *testing.T) {
+	now := time.Now()
+	p := newTestDelayPolicy(time.Second, 0, false, 0, now)
+	p.Observe(now.Add(-time.Minute), http.StatusTooManyRequests, nil)
+	if got := p.Next(); got != time.Second {
+		t.Errorf("適応しない場合の待機時間 = %v, want %v", got, time.Second)
+	}
+}
@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+)
+
+// DepthMode は深度の数え方
+type DepthMode string
+
+const (
+	// DepthHops は開始URLからたどったリンクの数を深度とする
+	DepthHops DepthMode = "hops"
+	// DepthPath は開始URLのディレクトリからのURLのパスの階層の差を深度とする
+	DepthPath DepthMode = "path"
+)
+
+// ParseDepthMode は文字列を深度の数え方に変換する
+func ParseDepthMode(s string) (DepthMode, bool) {
+	switch mode := DepthMode(strings.ToLower(s)); mode {
+	case DepthHops, DepthPath:
+		return mode, true
+	}
+	return "", false
+}
+
+// linkDepth はリンク元のページから見つかったURLの深度を返す
+func (c *Crawler) linkDepth(item crawlItem, link string) int {
+	if c.depthMode == DepthPath {
+		return c.pathDepth(link)
+	}
+	return item.depth + 1
+}
+
+// pathDepth は同じホストの開始URLのディレクトリからURLまでのパスの階層の差を返す
+// 開始URLが複数ある場合は最も近いものを使うため、クロールの順序によらず同じ値になる
+func (c *Crawler) pathDepth(link string) int {
+	u, err := url.Parse(link)
+	if err != nil {
+		return 0
+	}
+	target := pathSegments(u.Path)
+
+	depth := -1
+	for _, seed := range c.seeds {
+		s, err := url.Parse(seed)
+		if err != nil || !strings.EqualFold(s.Host, u.Host) {
+			continue
+		}
+		dir := pathSegments(s.Path)
+		if len(dir) > 0 && !strings.HasSuffix(s.Path, "/") {
+			dir = dir[:len(dir)-1] // ファイルを指す開始URLはそのディレクトリを基準にする
+		}
+
+		// 共通部分から開始URLのディレクトリまでと対象のURLまでの階層の合計
+		common := 0
+		for common < len(dir) && common < len(target) && dir[common] == target[common] {
+			common++
+		}
+		if d := len(dir) - common + len(target) - common; depth < 0 || d < depth {
+			depth = d
+		}
+	}
+	if depth < 0 {
+		return 0
+	}
+	return depth
+}
+
+// pathSegments はパスを空でない要素に分割する（末尾のindex.htmlはディレクトリと同じとみなす）
+func pathSegments(p string) []string {
+	var segments []string
+	for _, s := range strings.Split(p, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	if n := len(segments); n > 0 && (segments[n-1] == "index.html" || segments[n-1] == "index.htm") {
+		segments = segments[:n-1]
+	}
+	return segments
+}
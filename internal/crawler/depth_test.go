@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPathDepth(t *testing.T) {
+	c := New("https://docs.example.com/docs/guide/", 3, 10, 0, 0,
+		WithLogger(discardLogger()), WithSeeds([]string{"https://docs.example.com/api/reference.html"}))
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{url: "https://docs.example.com/docs/guide/", want: 0},
+		{url: "https://docs.example.com/docs/guide/index.html", want: 0},
+		{url: "https://docs.example.com/docs/guide/install", want: 1},
+		{url: "https://docs.example.com/docs/guide/install/linux/", want: 2},
+		{url: "https://docs.example.com/docs/", want: 1},
+		{url: "https://docs.example.com/docs/tutorial/first", want: 3},
+		// ファイルを指す開始URLはそのディレクトリ（/api/）を基準にし、近い方の開始URLを使う
+		{url: "https://docs.example.com/api/", want: 0},
+		{url: "https://docs.example.com/api/errors", want: 1},
+		{url: "https://DOCS.example.com/api/errors", want: 1},
+		// 開始URLと別のホストは 0
+		{url: "https://blog.example.com/docs/guide/a/b", want: 0},
+	}
+	for _, tt := range tests {
+		if got := c.pathDepth(tt.url); got != tt.want {
+			t.Errorf("pathDepth(%q) = %d, want %d", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestCrawlDepthMode(t *testing.T) {
+	pages := map[string]string{
+		"/docs/":      htmlPage("Docs", "docs top", "/docs/a/b/c", "/docs/b"),
+		"/docs/a/b/c": htmlPage("Deep", "deep page"),
+		"/docs/b":     htmlPage("B", "b page", "/docs/b/c", "/docs/y"),
+		"/docs/b/c":   htmlPage("BC", "b c page"),
+		"/docs/y":     htmlPage("Y", "y page"),
+	}
+	tests := []struct {
+		mode DepthMode
+		want string
+	}{
+		// リンクの数では、パスの階層が深くても1回でたどれるページを取得する
+		{mode: DepthHops, want: "/docs/,/docs/a/b/c,/docs/b"},
+		// パスの階層では、2回たどった先でも浅い階層のページを取得する
+		{mode: DepthPath, want: "/docs/,/docs/b,/docs/y"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			site := newHTMLSite(t, pages)
+
+			c := New(site.URL+"/docs/", 1, 10, 0, 0, WithLogger(discardLogger()), WithDepthMode(tt.mode))
+			result, err := c.Crawl(context.Background())
+			if err != nil {
+				t.Fatalf("Crawl: %v", err)
+			}
+			if got := strings.Join(pagePaths(site, result.Pages), ","); got != tt.want {
+				t.Errorf("取得したページ = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDepthMode(t *testing.T) {
+	for _, s := range []string{"hops", "PATH"} {
+		if _, ok := ParseDepthMode(s); !ok {
+			t.Errorf("ParseDepthMode(%q) が失敗しました", s)
+		}
+	}
+	if _, ok := ParseDepthMode("links"); ok {
+		t.Error(`ParseDepthMode("links") が成功しました`)
+	}
+}
@@ -0,0 +1,111 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding はリクエストで受け入れを表明するContent-Encoding
+const acceptEncoding = "gzip, deflate, br"
+
+// gzipMagic はgzipストリームの先頭バイト
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// DefaultMaxBodySize は1ページあたりのレスポンスボディの上限のデフォルト値（バイト）
+const DefaultMaxBodySize int64 = 10 << 20
+
+// readResponseBody はContent-Encodingに応じてレスポンスボディを展開して読み込む
+// Content-Encodingを付けずに圧縮したボディを返す行儀の悪いサーバーにも対応するため、gzipは先頭バイトでも判定する
+// limit が正の場合は展開後のサイズで limit バイトまでしか読み込まず、超えた場合は truncated に true を返す
+func readResponseBody(resp *http.Response, limit int64) (body []byte, truncated bool, err error) {
+	reader, err := decodeBody(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	if limit <= 0 {
+		body, err = io.ReadAll(reader)
+		return body, false, err
+	}
+
+	// 上限を超えたかどうかを判定するため1バイト余分に読み込む
+	body, err = io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(body)) > limit {
+		return body[:limit], true, nil
+	}
+	return body, false, nil
+}
+
+// decodeBody はContent-Encodingに対応する展開用のReaderを返す
+func decodeBody(contentEncoding string, body io.Reader) (io.Reader, error) {
+	var encodings []string
+	for _, e := range strings.Split(contentEncoding, ",") {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" && e != "identity" {
+			encodings = append(encodings, e)
+		}
+	}
+
+	// 複数のエンコーディングは適用された順の逆に展開する
+	reader := body
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch encodings[i] {
+		case "gzip", "x-gzip":
+			gz, err := gzip.NewReader(reader)
+			if err != nil {
+				return nil, fmt.Errorf("gzipの展開に失敗しました: %w", err)
+			}
+			reader = gz
+		case "deflate":
+			zr, err := newDeflateReader(reader)
+			if err != nil {
+				return nil, err
+			}
+			reader = zr
+		case "br":
+			reader = brotli.NewReader(reader)
+		default:
+			return nil, fmt.Errorf("未対応のContent-Encodingです: %s", encodings[i])
+		}
+	}
+	if len(encodings) > 0 {
+		return reader, nil
+	}
+
+	// Content-Encodingがなくてもgzipの先頭バイトであれば展開する
+	buffered := bufio.NewReader(reader)
+	head, _ := buffered.Peek(len(gzipMagic))
+	if bytes.Equal(head, gzipMagic) {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("gzipの展開に失敗しました: %w", err)
+		}
+		return gz, nil
+	}
+	return buffered, nil
+}
+
+// newDeflateReader はdeflateのボディを展開するReaderを返す
+// 仕様ではzlib形式だが、ヘッダーのない生のdeflateを返すサーバーもあるため先頭の2バイトで判定する
+func newDeflateReader(body io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(body)
+	head, _ := buffered.Peek(2)
+	if len(head) < 2 || head[0]&0x0f != 8 || (uint16(head[0])<<8|uint16(head[1]))%31 != 0 {
+		return flate.NewReader(buffered), nil
+	}
+	zr, err := zlib.NewReader(buffered)
+	if err != nil {
+		return nil, fmt.Errorf("deflateの展開に失敗しました: %w", err)
+	}
+	return zr, nil
+}
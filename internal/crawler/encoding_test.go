@@ -0,0 +1,80 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+// encodingFixture は展開の確認に使うHTML
+const encodingFixture = "<html><head><title>圧縮</title></head><body><p>compressed body</p></body></html>"
+
+// compress は data を w で圧縮したバイト列を返す
+func compress(t *testing.T, data []byte, newWriter func(io.Writer) io.WriteCloser) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipWriter(w io.Writer) io.WriteCloser   { return gzip.NewWriter(w) }
+func zlibWriter(w io.Writer) io.WriteCloser   { return zlib.NewWriter(w) }
+func brotliWriter(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }
+func flateWriter(w io.Writer) io.WriteCloser {
+	fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+	return fw
+}
+
+func TestDecodeBody(t *testing.T) {
+	plain := []byte(encodingFixture)
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{name: "gzip", encoding: "gzip", body: compress(t, plain, gzipWriter)},
+		{name: "x-gzip", encoding: "x-gzip", body: compress(t, plain, gzipWriter)},
+		{name: "brotli", encoding: "br", body: compress(t, plain, brotliWriter)},
+		{name: "deflate（zlib形式）", encoding: "deflate", body: compress(t, plain, zlibWriter)},
+		{name: "deflate（生のdeflate）", encoding: "deflate", body: compress(t, plain, flateWriter)},
+		{name: "gzipの後にbrotli", encoding: "gzip, br", body: compress(t, compress(t, plain, gzipWriter), brotliWriter)},
+		{name: "大文字", encoding: "GZIP", body: compress(t, plain, gzipWriter)},
+		{name: "identity", encoding: "identity", body: plain},
+		{name: "指定なし", encoding: "", body: plain},
+		{name: "指定なしのgzip", encoding: "", body: compress(t, plain, gzipWriter)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := decodeBody(tt.encoding, bytes.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("decodeBody: %v", err)
+			}
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if string(got) != encodingFixture {
+				t.Errorf("展開したボディ = %q, want %q", got, encodingFixture)
+			}
+		})
+	}
+}
+
+func TestDecodeBodyUnsupported(t *testing.T) {
+	_, err := decodeBody("compress", strings.NewReader(encodingFixture))
+	if err == nil || !strings.Contains(err.Error(), "compress") {
+		t.Errorf("未対応のContent-Encodingがエラーになりませんでした: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorKind はクロール中のエラーの種類
+type ErrorKind string
+
+const (
+	ErrorNetwork    ErrorKind = "network"     // 接続やレスポンスの読み込みの失敗
+	ErrorHTTPStatus ErrorKind = "http_status" // エラーを示すHTTPステータスコード
+	ErrorParse      ErrorKind = "parse"       // URLやHTMLの解析の失敗
+	ErrorSoft404    ErrorKind = "soft_404"    // ステータスコード200で返された「ページが見つかりません」のページ
+	ErrorRender     ErrorKind = "render"      // ヘッドレスブラウザでの描画の失敗
+)
+
+// CrawlError は取得に失敗したURLとその理由
+type CrawlError struct {
+	URL      string
+	Depth    int
+	Kind     ErrorKind
+	Err      error
+	Attempts int // 取得を試みた回数
+}
+
+// Error はエラーメッセージを返す
+func (e *CrawlError) Error() string {
+	return fmt.Sprintf("%s: %v", e.URL, e.Err)
+}
+
+// Unwrap は元のエラーを返す
+func (e *CrawlError) Unwrap() error {
+	return e.Err
+}
+
+// StatusError はエラーを示すHTTPステータスコードが返されたことを表す
+type StatusError struct {
+	StatusCode int
+}
+
+// Error はエラーメッセージを返す
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTPステータス %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// kindError はエラーに種類を付与する
+type kindError struct {
+	kind ErrorKind
+	err  error
+}
+
+func (e *kindError) Error() string { return e.err.Error() }
+func (e *kindError) Unwrap() error { return e.err }
+
+// withKind はエラーに種類を付与する（err が nil の場合は nil を返す）
+func withKind(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// classifyError はエラーの種類を判定する（種類が付与されていないエラーはネットワークエラーとみなす）
+func classifyError(err error) ErrorKind {
+	var ke *kindError
+	if errors.As(err, &ke) {
+		return ke.kind
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return ErrorHTTPStatus
+	}
+	return ErrorNetwork
+}
+
+// ErrNoPages は1ページも取得できなかったことを示す
+var ErrNoPages = errors.New("ページを1件も取得できませんでした")
+
+// ErrAuthRequired は多くのページで401・403が返され、認証が必要と判断したことを示す
+var ErrAuthRequired = errors.New("認証が必要なようです（--auth-user と --auth-pass、--token、--cookie、--login-url で認証情報を指定してください）")
+
+// DefaultAuthAbortRatio はクロールを中止する401・403の割合のデフォルト値
+const DefaultAuthAbortRatio = 0.5
+
+// authAbortMinResponses は401・403の割合で中止を判断するのに必要なレスポンスの数
+// 少数のページで拒否されただけで中止しないようにする
+const authAbortMinResponses = 10
+
+// checkAuthWall は401・403の割合が上限を超えた場合にエラーを返す
+func (c *Crawler) checkAuthWall() error {
+	if c.authAbortRatio <= 0 {
+		return nil
+	}
+	denied, total := c.stats.authDenied.Load(), c.stats.responses.Load()
+	if total < authAbortMinResponses || float64(denied) <= c.authAbortRatio*float64(total) {
+		return nil
+	}
+	return fmt.Errorf("%w: %d / %d 件のリクエストが拒否されました (401/403)", ErrAuthRequired, denied, total)
+}
+
+// recordError は取得に失敗したURLを記録する（スレッドセーフに）
+func (c *Crawler) recordError(item crawlItem, err error) {
+	attempts := 1
+	var ae *attemptsError
+	if errors.As(err, &ae) {
+		attempts = ae.attempts
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors = append(c.errors, CrawlError{
+		URL:      item.url,
+		Depth:    item.depth,
+		Kind:     classifyError(err),
+		Err:      err,
+		Attempts: attempts,
+	})
+}
@@ -0,0 +1,43 @@
+package crawler
+
+import "github.com/PuerkitoBio/goquery"
+
+// DefaultExcludeSelectors は本文の抽出の前に取り除く要素のデフォルトのセレクタ
+// ヘッダーやフッター、サイドバー、「このページを編集」リンクなど、多くのサイトで本文に混ざる要素
+var DefaultExcludeSelectors = []string{
+	"nav",
+	"footer",
+	"body > header",
+	`[role="navigation"]`,
+	`[role="banner"]`,
+	`[role="contentinfo"]`,
+	".sidebar",
+	".edit-link",
+	".edit-this-page",
+	".theme-edit-this-page",
+	".headerlink",
+	`div[class*="language-"] > span.lang`, // VitePressのコードブロックの言語名の表示
+}
+
+// excludeSelectorSource は本文の抽出の前に取り除く要素のセレクタの指定元
+const excludeSelectorSource = "--exclude-selector"
+
+// contentDocument は本文の抽出に使うドキュメントを返す
+// 取り除く要素がある場合は、リンクの収集などに使う元のドキュメントを変更しないよう複製してから取り除く
+func (c *Crawler) contentDocument(doc *goquery.Document) *goquery.Document {
+	if !c.defaultExcludes && len(c.excludeSelectors) == 0 {
+		return doc
+	}
+	content := goquery.CloneDocument(doc)
+	if c.defaultExcludes {
+		for _, selector := range DefaultExcludeSelectors {
+			content.Find(selector).Remove()
+		}
+	}
+	for _, selector := range c.excludeSelectors {
+		matched := content.Find(selector)
+		c.selectorStats.Record(excludeSelectorSource, selector, matched.Length())
+		matched.Remove()
+	}
+	return content
+}
@@ -0,0 +1,55 @@
+package crawler
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// defaultSkipExtensions はリンクをたどらないバイナリファイルの拡張子
+var defaultSkipExtensions = []string{
+	// 画像
+	".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg", ".ico", ".bmp", ".tif", ".tiff", ".avif",
+	// アーカイブ
+	".zip", ".tar", ".gz", ".tgz", ".bz2", ".xz", ".7z", ".rar",
+	// 文書
+	".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx",
+	// 音声・動画
+	".mp3", ".mp4", ".m4a", ".wav", ".ogg", ".webm", ".mov", ".avi", ".mkv",
+	// フォント
+	".woff", ".woff2", ".ttf", ".otf", ".eot",
+	// 実行ファイル・パッケージ
+	".exe", ".msi", ".dmg", ".pkg", ".deb", ".rpm", ".apk", ".jar", ".bin", ".iso",
+}
+
+// newSkipExtensionSet はデフォルトのたどらない拡張子の集合を作成する
+func newSkipExtensionSet() map[string]bool {
+	set := make(map[string]bool, len(defaultSkipExtensions))
+	for _, ext := range defaultSkipExtensions {
+		set[ext] = true
+	}
+	return set
+}
+
+// normalizeExtension は拡張子を先頭に.を付けた小文字に揃える
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// hasSkippedExtension はURLのパスの拡張子がたどらない拡張子かどうかを判定する
+// クエリ文字列は無視し、最後のパス要素の拡張子のみを見る（/docs/zip-handling/ は対象外）
+func (c *Crawler) hasSkippedExtension(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(path.Base(u.Path)))
+	if ext == "" || strings.HasSuffix(u.Path, "/") {
+		return false
+	}
+	return c.skipExts[ext]
+}
@@ -0,0 +1,37 @@
+package crawler
+
+// FailedURL は前回のクロールで取得に失敗したURL（--errors-out の一覧の1行）
+type FailedURL struct {
+	URL   string
+	Depth int
+}
+
+// enqueueFailed は前回のクロールで取得に失敗したURLのみをキューに追加する
+// チェックポイントから復元した訪問済みのURLとクロール待ちのURLは無視する
+func (c *Crawler) enqueueFailed() {
+	c.mu.Lock()
+	c.queue = c.queue[:0]
+	c.mu.Unlock()
+	for _, failed := range c.retryFailed {
+		seed := c.seedFor(failed.URL)
+		if seed == "" {
+			c.logger.Warn("開始URLの範囲外のためスキップします", "url", failed.URL)
+			c.retrySkipped = append(c.retrySkipped, failed.URL)
+			continue
+		}
+		u := c.normalizer.normalize(failed.URL)
+		c.visited.add(u)
+		c.enqueue(crawlItem{url: u, depth: failed.Depth, seed: seed})
+	}
+	c.logger.Info("前回取得に失敗したURLを取得し直します", "urls", c.PendingCount())
+}
+
+// RetrySkipped は取得し直す対象のうち開始URLの範囲外のためスキップしたURLを返す
+func (c *Crawler) RetrySkipped() []string {
+	return c.retrySkipped
+}
+
+// NormalizeURL はURLを訪問済みの判定や CrawlError.URL と同じ形に正規化する
+func (c *Crawler) NormalizeURL(rawURL string) string {
+	return c.normalizer.normalize(rawURL)
+}
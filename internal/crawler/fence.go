@@ -0,0 +1,37 @@
+package crawler
+
+import "strings"
+
+// CodeFenceLines は各行がコードブロック（``` または ~~~ で囲んだ範囲、フェンスの行を含む）の行かを返す
+// 引用（> ```python）やリストの中で字下げしたフェンスも、行頭の > と字下げを除いて判定する
+// コードブロックの中は空白も含めてそのまま書き出すため、本文を整形する処理はこれらの行を変更しない
+func CodeFenceLines(lines []string) []bool {
+	code := make([]bool, len(lines))
+	fence := ""
+	for i, line := range lines {
+		trimmed := unquoteLine(line)
+		switch {
+		case fence != "":
+			code[i] = true
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			code[i] = true
+			fence = trimmed[:3]
+			for len(fence) < len(trimmed) && trimmed[len(fence)] == trimmed[0] {
+				fence = trimmed[:len(fence)+1]
+			}
+		}
+	}
+	return code
+}
+
+// unquoteLine は行頭の引用の記号（入れ子の >> を含む）と前後の空白を取り除く
+func unquoteLine(line string) string {
+	trimmed := strings.TrimSpace(line)
+	for strings.HasPrefix(trimmed, ">") {
+		trimmed = strings.TrimSpace(trimmed[1:])
+	}
+	return trimmed
+}
@@ -0,0 +1,38 @@
+package crawler
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// frameSources はiframe・frameで埋め込まれた同じドメイン内のページのURLを返す
+// srcdocで本文を直接埋め込んだiframeは取得するページがないためスキップする
+func (c *Crawler) frameSources(doc *goquery.Document, pageURL string) []string {
+	var frames []string
+	doc.Find("iframe, frame").Each(func(i int, s *goquery.Selection) {
+		if _, ok := s.Attr("srcdoc"); ok {
+			c.logger.Debug("srcdocで埋め込まれたiframeはスキップします", "url", pageURL)
+			return
+		}
+		src := strings.TrimSpace(s.AttrOr("src", ""))
+		if src == "" || isBlankFrameSource(src) {
+			return
+		}
+		frameURL, err := resolveURL(pageURL, src)
+		if err != nil {
+			return
+		}
+		frameURL = stripFragment(frameURL)
+		if c.inScope(frameURL) && !c.hasSkippedExtension(frameURL) {
+			frames = append(frames, frameURL)
+		}
+	})
+	return frames
+}
+
+// isBlankFrameSource は内容を持たないiframeのsrc（about:blank や javascript:）かどうかを判定する
+func isBlankFrameSource(src string) bool {
+	lower := strings.ToLower(src)
+	return strings.HasPrefix(lower, "about:") || strings.HasPrefix(lower, "javascript:")
+}
@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// discardLogger はテストでログを出力しないロガーを返す
+func discardLogger() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// testSite はパスごとのリクエスト数を数えるテスト用のサーバー
+type testSite struct {
+	*httptest.Server
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+// newTestSite は handler でレスポンスを返すテスト用のサーバーを起動する
+func newTestSite(t *testing.T, handler http.HandlerFunc) *testSite {
+	t.Helper()
+	s := &testSite{hits: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		s.mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newHTMLSite はパスごとのHTMLを返すテスト用のサーバーを起動する（ないパスは404）
+func newHTMLSite(t *testing.T, pages map[string]string) *testSite {
+	t.Helper()
+	return newTestSite(t, func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, body)
+	})
+}
+
+// count はパスへのリクエスト数を返す
+func (s *testSite) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+// htmlPage はタイトル・本文・リンクからテスト用のHTMLを作成する
+func htmlPage(title, body string, links ...string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<html><head><title>%s</title></head><body><main><h1>%s</h1><p>%s</p>", title, title, body)
+	for _, link := range links {
+		fmt.Fprintf(&sb, `<a href="%s">%s</a> `, link, link)
+	}
+	sb.WriteString("</main></body></html>")
+	return sb.String()
+}
+
+// pagePaths はページのURLからサーバーのURLを除いたパスを取得した順に返す
+func pagePaths(server *testSite, pages []Page) []string {
+	paths := make([]string, len(pages))
+	for i, page := range pages {
+		paths[i] = strings.TrimPrefix(page.URL, server.URL)
+	}
+	return paths
+}
@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// lineNumberClasses はシンタックスハイライトの行番号の要素のクラス
+// （Pygmentsの linenos、Rougeの rouge-gutter、Prismの line-numbers-rows、highlight.jsの hljs-ln-numbers、GitHubの blob-num など）
+var lineNumberClasses = map[string]bool{
+	"linenos": true, "lineno": true, "linenodiv": true, "rouge-gutter": true, "gutter": true,
+	"line-numbers-rows": true, "line-number": true, "linenumber": true, "hljs-ln-numbers": true,
+	"hljs-ln-n": true, "blob-num": true, "ln": true,
+}
+
+// codeLineClasses は1行のコードを囲む要素のクラス（Shikiの line、Docusaurusの token-line など）
+var codeLineClasses = map[string]bool{
+	"line": true, "token-line": true, "code-line": true, "ec-line": true, "hljs-ln-line": true,
+}
+
+// codeText はシンタックスハイライトされたコードの要素から元のソースの行を組み立てる
+// トークンの要素は空白を入れずにつなぎ、行を囲む要素（div、tr、Shikiの span.line など）の区切りを改行にし、行番号を取り除く
+func codeText(n *html.Node) string {
+	var sb strings.Builder
+	afterLine := false // 行の要素の後に改行を補ったか（直後の改行のテキストは行の区切りとして重ねない）
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		switch {
+		case node.Type == html.TextNode:
+			text := node.Data
+			if afterLine {
+				text = strings.TrimPrefix(text, "\n")
+			}
+			if node.Data != "" {
+				afterLine = false
+			}
+			sb.WriteString(text)
+			return
+		case node.Type != html.ElementNode:
+			for c := node.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+			return
+		case node.Data == "br":
+			sb.WriteString("\n")
+			afterLine = false
+			return
+		case skippedElements[node.Data] || node.Data == "button" || isLineNumber(node):
+			return
+		}
+		start := sb.Len()
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if isCodeLine(node) && (sb.Len() == start || !strings.HasSuffix(sb.String(), "\n")) {
+			sb.WriteString("\n")
+			afterLine = true
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c)
+	}
+	return sb.String()
+}
+
+// isLineNumber は要素が行番号（またはその列）かを判定する
+func isLineNumber(n *html.Node) bool {
+	if attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if lineNumberClasses[class] {
+			return true
+		}
+	}
+	return false
+}
+
+// isCodeLine は要素がコードの1行を囲む要素かを判定する
+func isCodeLine(n *html.Node) bool {
+	if n.Data == "div" || n.Data == "tr" {
+		return true
+	}
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if codeLineClasses[class] {
+			return true
+		}
+	}
+	return false
+}
+
+// isCodeTable はtable要素が行番号の列とコードの列からなるコードブロック（Pygments・Rougeの表形式、GitHubなど）かを判定する
+func isCodeTable(n *html.Node) bool {
+	if hasClass(n, "highlighttable") || hasClass(n, "rouge-table") || hasClass(n, "hljs-ln") {
+		return true
+	}
+	var found bool
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil && !found; c = c.NextSibling {
+			if c.Type != html.ElementNode || c.Data == "table" {
+				continue
+			}
+			if (c.Data == "td" || c.Data == "th") && isLineNumber(c) {
+				found = true
+				return
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return found
+}
@@ -0,0 +1,178 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultImageCDNHosts は --allow-image-cdns で画像の取得を許可するCDNのドメイン（サブドメインも含む）
+var DefaultImageCDNHosts = []string{
+	"cloudfront.net",
+	"cloudinary.com",
+	"imgix.net",
+	"jsdelivr.net",
+	"unpkg.com",
+	"githubusercontent.com",
+	"googleusercontent.com",
+	"gstatic.com",
+	"imgur.com",
+}
+
+// imageManifestName は保存した画像の一覧を書き出すファイル名
+const imageManifestName = "manifest.json"
+
+// imageRefPattern は本文のMarkdownの画像の参照（![alt](url)、URLは <> で囲む場合もある）
+var imageRefPattern = regexp.MustCompile(`!\[((?:\\.|[^\]\\])*)\]\((<[^>]*>|[^)\s]*)\)`)
+
+// imageEntry は保存した画像ファイルと元のURLの対応
+type imageEntry struct {
+	Path string `json:"path"` // 保存先ディレクトリからの相対パス
+	URL  string `json:"url"`
+}
+
+// downloadImages はページの本文が参照する画像を保存先ディレクトリに保存し、参照を保存したファイルの相対パスに書き換える
+// 同じ内容の画像は1つのファイルにまとめ、取得できなかった画像は元のURLのまま残す
+func (c *Crawler) downloadImages(ctx context.Context, pages []Page) {
+	var order []string
+	seen := make(map[string]bool)
+	for _, page := range pages {
+		for _, m := range imageRefPattern.FindAllStringSubmatch(page.Content, -1) {
+			src := strings.TrimSuffix(strings.TrimPrefix(m[2], "<"), ">")
+			if !seen[src] && c.allowsImage(src) {
+				seen[src] = true
+				order = append(order, src)
+			}
+		}
+	}
+	if len(order) == 0 {
+		return
+	}
+	if err := os.MkdirAll(c.imageDir, 0o755); err != nil {
+		c.logger.Warn("画像の保存先ディレクトリを作成できないため、画像を保存しません", "dir", c.imageDir, "error", err)
+		return
+	}
+
+	c.logger.Info("ページの画像を保存しています", "images", len(order))
+	local := make(map[string]string, len(order))
+	var manifest []imageEntry
+	for _, src := range order {
+		if ctx.Err() != nil {
+			c.logger.Warn("画像の保存を中断しました（一部の画像は元のURLのままです）")
+			break
+		}
+		name, err := c.saveImage(ctx, src)
+		if err != nil {
+			c.logger.Warn("画像を保存できないため元のURLのまま残します", "url", src, "error", err)
+			continue
+		}
+		local[src] = c.imageRefPrefix + name
+		manifest = append(manifest, imageEntry{Path: name, URL: src})
+	}
+
+	for i := range pages {
+		pages[i].Content = imageRefPattern.ReplaceAllStringFunc(pages[i].Content, func(ref string) string {
+			m := imageRefPattern.FindStringSubmatch(ref)
+			rel, ok := local[strings.TrimSuffix(strings.TrimPrefix(m[2], "<"), ">")]
+			if !ok {
+				return ref
+			}
+			return "![" + m[1] + "](" + markdownURL(rel) + ")"
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(c.imageDir, imageManifestName), data, 0o644)
+	}
+	if err != nil {
+		c.logger.Warn("画像の一覧の保存に失敗しました", "error", err)
+	}
+}
+
+// allowsImage は画像を取得してよいURLかを判定する（開始URLと同じホスト、または許可したCDNのホスト）
+func (c *Crawler) allowsImage(src string) bool {
+	u, err := url.Parse(src)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	if c.isBaseHost(limiterKey(src)) {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range c.imageHosts {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// saveImage は画像を取得し、内容のハッシュのファイル名で保存してそのファイル名を返す
+func (c *Crawler) saveImage(ctx context.Context, src string) (string, error) {
+	req, err := c.newResourceRequest(ctx, http.MethodGet, src)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", &StatusError{StatusCode: resp.StatusCode}
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "image/") {
+		return "", fmt.Errorf("画像ではないレスポンスです: %s", mediaType)
+	}
+	body, truncated, err := readResponseBody(resp, c.maxBodySize)
+	c.stats.bytesDownloaded.Add(int64(len(body)))
+	if err != nil {
+		return "", err
+	}
+	if truncated {
+		return "", fmt.Errorf("レスポンスが上限（%dバイト）を超えています", c.maxBodySize)
+	}
+
+	sum := sha256.Sum256(body)
+	name := hex.EncodeToString(sum[:8]) + imageExtension(src, mediaType)
+	dest := filepath.Join(c.imageDir, name)
+	if _, err := os.Stat(dest); err == nil {
+		return name, nil
+	}
+	if err := os.WriteFile(dest, body, 0o644); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// imageExtension は画像のファイルの拡張子をURLのパス、なければメディアタイプから決める
+func imageExtension(src, mediaType string) string {
+	if u, err := url.Parse(src); err == nil {
+		switch ext := strings.ToLower(path.Ext(u.Path)); ext {
+		case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".avif", ".bmp", ".ico":
+			return ext
+		}
+	}
+	switch mediaType {
+	case "image/svg+xml":
+		return ".svg"
+	case "image/jpeg":
+		return ".jpg"
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
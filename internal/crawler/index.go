@@ -0,0 +1,108 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// ページの前回のクロールからの変化
+const (
+	ChangeAdded     = "added"     // 前回のクロールにはなかったページ
+	ChangeChanged   = "changed"   // 内容が変わったページ
+	ChangeUnchanged = "unchanged" // 内容が変わっていないページ
+)
+
+// Index は次回のクロールで変化を検出するために保存するページの一覧
+type Index struct {
+	CreatedAt time.Time    `json:"created_at"`
+	Entries   []IndexEntry `json:"entries"`
+}
+
+// IndexEntry はインデックスに保存する1ページ分の情報
+type IndexEntry struct {
+	URL         string    `json:"url"`
+	ContentHash string    `json:"content_hash"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// BuildIndex はクロールしたページからインデックスを作成する
+func BuildIndex(pages []Page) *Index {
+	idx := &Index{CreatedAt: time.Now()}
+	for _, page := range pages {
+		idx.Entries = append(idx.Entries, IndexEntry{
+			URL:         page.URL,
+			ContentHash: contentHash(page.Content),
+			Title:       page.Title,
+			Description: page.Description,
+			FetchedAt:   page.FetchedAt,
+		})
+	}
+	return idx
+}
+
+// LoadIndex はインデックスファイルを読み込む
+func LoadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("インデックスの読み込みに失敗しました: %w", err)
+	}
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("インデックスの解析に失敗しました: %w", err)
+	}
+	return &idx, nil
+}
+
+// Save はインデックスをファイルに保存する
+func (idx *Index) Save(path string) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("インデックスの作成に失敗しました: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("インデックスの保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// ApplyChanges は前回のインデックスと比較して各ページの ChangeStatus を設定し、
+// 今回のクロールで見つからなかったページのURLを返す
+func (idx *Index) ApplyChanges(pages []Page) []string {
+	previous := make(map[string]string, len(idx.Entries))
+	for _, entry := range idx.Entries {
+		previous[entry.URL] = entry.ContentHash
+	}
+
+	seen := make(map[string]bool, len(pages))
+	for i := range pages {
+		page := &pages[i]
+		seen[page.URL] = true
+		for _, alias := range page.AliasURLs {
+			seen[alias] = true
+		}
+
+		hash, ok := previous[page.URL]
+		switch {
+		case !ok:
+			page.ChangeStatus = ChangeAdded
+		case hash != contentHash(page.Content):
+			page.ChangeStatus = ChangeChanged
+		default:
+			page.ChangeStatus = ChangeUnchanged
+		}
+	}
+
+	var removed []string
+	for u := range previous {
+		if !seen[u] {
+			removed = append(removed, u)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// localeCodes はパスの要素として言語を示すとみなす言語コード
+// js や ui などの2文字のディレクトリと区別するため、ドキュメントサイトでよく使われるものに限る
+var localeCodes = map[string]bool{
+	"en": true, "ja": true, "zh": true, "ko": true, "fr": true, "de": true, "es": true,
+	"pt": true, "it": true, "ru": true, "nl": true, "pl": true, "tr": true, "vi": true,
+	"th": true, "id": true, "ar": true, "he": true, "uk": true, "cs": true, "sv": true,
+	"fi": true, "da": true, "nb": true, "hu": true, "ro": true, "el": true, "fa": true,
+	"hi": true, "ms": true,
+}
+
+// normalizeLang は言語タグを小文字のハイフン区切りに揃える（pt_BR → pt-br）
+func normalizeLang(tag string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(tag)), "_", "-")
+}
+
+// langMatches は言語タグが指定された言語に一致するかを判定する
+// en は en-US にも一致し、pt-br は地域を持たない pt にも一致する
+func langMatches(tag, want string) bool {
+	tag, want = normalizeLang(tag), normalizeLang(want)
+	if tag == want || strings.HasPrefix(tag, want+"-") {
+		return true
+	}
+	primary, _, _ := strings.Cut(want, "-")
+	return tag == primary
+}
+
+// localeSegment はパスの先頭2つの要素から言語を示すもの（/en/ や /pt-br/）を返す
+func localeSegment(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	segments := pathSegments(u.Path)
+	if len(segments) > 2 {
+		segments = segments[:2]
+	}
+	for _, s := range segments {
+		s = normalizeLang(s)
+		primary, region, hasRegion := strings.Cut(s, "-")
+		if !localeCodes[primary] {
+			continue
+		}
+		if !hasRegion || (len(region) >= 2 && len(region) <= 4) {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// matchesLangPath はURLのパスが示す言語が指定された言語に一致するかを判定する（言語を示さないURLは一致とみなす）
+func (c *Crawler) matchesLangPath(rawURL string) bool {
+	if c.langFilter == "" {
+		return true
+	}
+	locale, ok := localeSegment(rawURL)
+	return !ok || langMatches(locale, c.langFilter)
+}
+
+// checkPageLang はページのhtmlのlang属性が指定された言語に一致するかを判定する
+// 一致しない場合は hreflang で指定された言語の版があればそのURLを返し、なければ唯一の版として収集する
+func (c *Crawler) checkPageLang(doc *goquery.Document, pageURL string) (ok bool, translation string) {
+	if c.langFilter == "" {
+		return true, ""
+	}
+	lang := strings.TrimSpace(doc.Find("html").AttrOr("lang", ""))
+	if lang == "" || langMatches(lang, c.langFilter) {
+		return true, ""
+	}
+
+	doc.Find(`link[rel="alternate"][hreflang]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if !langMatches(s.AttrOr("hreflang", ""), c.langFilter) {
+			return true
+		}
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if href == "" {
+			return true
+		}
+		resolved, err := resolveURL(pageURL, href)
+		if err != nil || !c.inScope(resolved) {
+			return true
+		}
+		translation = stripFragment(resolved)
+		return false
+	})
+	if translation == "" {
+		c.logger.Debug("指定された言語の版がないため収集します", "url", pageURL, "lang", lang)
+		return true, ""
+	}
+	return false, translation
+}
@@ -0,0 +1,198 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCheckConcurrency はリンク切れの確認で同時に送るリクエストの数のデフォルト値
+const DefaultCheckConcurrency = 4
+
+// externalCheckInterval は開始URLと別のホストへの確認のリクエストの間隔
+const externalCheckInterval = time.Second
+
+// BrokenLink はリンク切れのリンクとそれを含むページ
+type BrokenLink struct {
+	Page       string // リンクを含むページ
+	URL        string // リンク先
+	StatusCode int    // HTTPステータスコード（応答がなかった場合は0）
+	Err        string // エラーの内容
+	External   bool   // 開始URLと別のホストへのリンクか
+}
+
+// linkStatus はリンク先の確認の結果
+type linkStatus struct {
+	statusCode int
+	err        string
+}
+
+// recordLink はリンク切れの確認のためにページに含まれるリンクを記録する
+func (c *Crawler) recordLink(pageURL, link string) {
+	if !c.checkLinks {
+		return
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	link = c.normalizer.normalize(stripFragment(link))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	refs, ok := c.linkRefs[link]
+	if !ok {
+		c.linkOrder = append(c.linkOrder, link)
+	}
+	for _, ref := range refs {
+		if ref == pageURL {
+			return
+		}
+	}
+	c.linkRefs[link] = append(refs, pageURL)
+}
+
+// findBrokenLinks は記録したリンクのうちリンク切れのものをリンクを含むページの順に返す
+// クロールしたリンクは取得の結果を使い、クロールしなかったリンクはHEAD（失敗した場合はGET）で確認する
+func (c *Crawler) findBrokenLinks(ctx context.Context) []BrokenLink {
+	c.mu.Lock()
+	failed := make(map[string]CrawlError, len(c.errors))
+	for _, e := range c.errors {
+		failed[c.normalizer.normalize(e.URL)] = e
+	}
+	pending := make(map[string]bool, len(c.queue))
+	for _, item := range c.queue {
+		pending[item.url] = true
+	}
+	links := append([]string(nil), c.linkOrder...)
+	c.mu.Unlock()
+
+	statuses := make(map[string]linkStatus)
+	var targets []string
+	for _, link := range links {
+		if e, ok := failed[link]; ok {
+			var statusErr *StatusError
+			status := linkStatus{err: e.Err.Error()}
+			if errors.As(e.Err, &statusErr) {
+				status.statusCode = statusErr.StatusCode
+			}
+			statuses[link] = status
+			continue
+		}
+		if c.visited.contains(link) && !pending[link] {
+			continue
+		}
+		if !c.isBaseHost(limiterKey(link)) && !c.checkExternal {
+			continue
+		}
+		targets = append(targets, link)
+	}
+
+	if len(targets) > 0 {
+		c.logger.Info("クロールしなかったリンクのリンク切れを確認しています", "links", len(targets))
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	work := make(chan string)
+	for i := 0; i < max(c.checkConcurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range work {
+				status := c.checkLink(ctx, link)
+				mu.Lock()
+				statuses[link] = status
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, link := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- link
+	}
+	close(work)
+	wg.Wait()
+	if ctx.Err() != nil {
+		c.logger.Warn("リンク切れの確認を中断しました（一部のリンクは確認していません）")
+	}
+
+	var broken []BrokenLink
+	for _, link := range links {
+		status, ok := statuses[link]
+		if !ok || (status.err == "" && status.statusCode < 400) {
+			continue
+		}
+		external := !c.isBaseHost(limiterKey(link))
+		c.mu.Lock()
+		refs := c.linkRefs[link]
+		c.mu.Unlock()
+		for _, page := range refs {
+			broken = append(broken, BrokenLink{Page: page, URL: link, StatusCode: status.statusCode, Err: status.err, External: external})
+		}
+	}
+	sort.SliceStable(broken, func(i, j int) bool { return broken[i].Page < broken[j].Page })
+	return broken
+}
+
+// checkLink はリンク先にHEADでリクエストし、失敗した場合はGETで確認し直す
+// HEADに対応していないサーバーは405や404を返すことがあるため、GETの結果を採用する
+func (c *Crawler) checkLink(ctx context.Context, link string) linkStatus {
+	status, err := c.probeLink(ctx, http.MethodHead, link)
+	if err == nil && status < 400 {
+		return linkStatus{statusCode: status}
+	}
+	if ctx.Err() != nil {
+		return linkStatus{}
+	}
+	status, err = c.probeLink(ctx, http.MethodGet, link)
+	if err != nil {
+		if ctx.Err() != nil {
+			return linkStatus{}
+		}
+		return linkStatus{statusCode: status, err: err.Error()}
+	}
+	if status >= 400 {
+		return linkStatus{statusCode: status, err: (&StatusError{StatusCode: status}).Error()}
+	}
+	return linkStatus{statusCode: status}
+}
+
+// probeLink はリンク先に1回リクエストしてステータスコードを返す
+func (c *Crawler) probeLink(ctx context.Context, method, link string) (int, error) {
+	req, err := c.newResourceRequest(ctx, method, link)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// newResourceRequest はページ以外のリソースへのリクエストを待機してから作成する
+// 開始URLと別のホストにはユーザー指定のヘッダーや認証情報を送らず、確認用の間隔で送る
+func (c *Crawler) newResourceRequest(ctx context.Context, method, link string) (*http.Request, error) {
+	if c.isBaseHost(limiterKey(link)) {
+		if err := c.wait(ctx, link); err != nil {
+			return nil, err
+		}
+		return c.newRequestWithBody(ctx, method, link, nil)
+	}
+	if err := c.checkLimiter.Wait(ctx, limiterKey(link)); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	return req, nil
+}
@@ -0,0 +1,176 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// localSite はディスク上に保存したサイト（wgetやHTTrackのミラーなど）
+type localSite struct {
+	root string   // サイトのディレクトリの絶対パス
+	base *url.URL // ページのURLとして扱うベースURL（nilの場合は file:// のURL）
+}
+
+// LocalSeedURL はディレクトリに保存したサイトをクロールするときの開始URLを返す
+// base を指定した場合はそのURLを、指定しない場合はディレクトリを指す file:// のURLを返す
+func LocalSeedURL(dir, base string) (string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("ディレクトリを開けません: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("ディレクトリではありません: %s", dir)
+	}
+	if base != "" {
+		u, err := url.Parse(base)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "", fmt.Errorf("ベースURLの形式が正しくありません: %s", base)
+		}
+		if !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+		}
+		return u.String(), nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs) + "/"}).String(), nil
+}
+
+// filePath はURLに対応するファイルのパスを返す（このサイトのURLでない場合は false）
+func (s *localSite) filePath(u *url.URL) (string, bool) {
+	var rel string
+	switch {
+	case s.base == nil && u.Scheme == "file":
+		rootPath := filepath.ToSlash(s.root) + "/"
+		if !strings.HasPrefix(u.Path+"/", rootPath) {
+			return "", false
+		}
+		rel = strings.TrimPrefix(u.Path, rootPath)
+	case s.base != nil && u.Scheme == s.base.Scheme && strings.EqualFold(u.Host, s.base.Host) && strings.HasPrefix(u.Path+"/", s.base.Path):
+		rel = strings.TrimPrefix(u.Path, s.base.Path)
+	default:
+		return "", false
+	}
+
+	// ../ でディレクトリの外を指さないよう正規化する
+	cleaned := path.Clean("/" + rel)
+	p := filepath.Join(s.root, filepath.FromSlash(cleaned))
+	if info, err := os.Stat(p); err == nil && info.IsDir() {
+		p = filepath.Join(p, "index.html")
+	}
+	return p, true
+}
+
+// pageURL はサイトのディレクトリからの相対パスをページのURLに変換する
+func (s *localSite) pageURL(rel string) string {
+	rel = filepath.ToSlash(rel)
+	if s.base != nil {
+		return s.base.ResolveReference(&url.URL{Path: rel}).String()
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(s.root) + "/" + rel}).String()
+}
+
+// pages はサイトのディレクトリ内のHTMLファイルをすべてページのURLとして返す
+func (s *localSite) pages() ([]string, error) {
+	var urls []string
+	err := filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		if d.IsDir() || (ext != ".html" && ext != ".htm") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		urls = append(urls, s.pageURL(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ディレクトリの走査に失敗しました: %w", err)
+	}
+	return urls, nil
+}
+
+// enqueueLocalPages はディスク上のサイトのHTMLファイルを深度0としてキューに追加する（訪問済みのものは除く）
+func (c *Crawler) enqueueLocalPages() {
+	urls, err := c.local.pages()
+	if err != nil {
+		c.logger.Warn("ローカルのHTMLファイルの一覧を取得できませんでした", "error", err)
+		return
+	}
+	for _, u := range urls {
+		if seed := c.seedFor(u); seed != "" {
+			c.enqueueNew(crawlItem{url: u, depth: 0, seed: seed})
+		}
+	}
+}
+
+// servesLocally はURLがディスク上のサイトのものかを返す
+func (c *Crawler) servesLocally(rawURL string) bool {
+	if c.local == nil {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	_, ok := c.local.filePath(u)
+	return ok
+}
+
+// localTransport はディスク上のサイトのURLへのリクエストをファイルの読み込みで処理する
+type localTransport struct {
+	site *localSite
+	next http.RoundTripper // サイト以外のURLへのリクエストに使う
+}
+
+// RoundTrip はURLに対応するファイルをレスポンスとして返す
+func (t *localTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p, ok := t.site.filePath(req.URL)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	resp := &http.Response{
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Request:    req,
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		resp.StatusCode = http.StatusNotFound
+		resp.Status = "404 Not Found"
+		resp.Body = io.NopCloser(strings.NewReader(""))
+		return resp, nil
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(p))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	resp.StatusCode = http.StatusOK
+	resp.Status = "200 OK"
+	resp.Header.Set("Content-Type", contentType)
+	resp.ContentLength = int64(len(data))
+	if req.Method == http.MethodHead {
+		data = nil
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
@@ -0,0 +1,49 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// LoginConfig はクロール開始前に行うフォームログインの設定
+type LoginConfig struct {
+	URL          string         // ログインフォームの送信先URL
+	Fields       url.Values     // 送信するフォームの値（ログには出力しない）
+	SuccessRegex *regexp.Regexp // ログイン成功時のレスポンス本文に一致する正規表現（nilの場合はステータスコードで判定）
+}
+
+// doLogin はログインフォームを送信し、共有のCookieジャーにセッションCookieを保存する
+func (c *Crawler) doLogin(ctx context.Context) error {
+	c.logger.Info("ログイン中", "url", c.login.URL)
+
+	req, err := c.newRequestWithBody(ctx, "POST", c.login.URL, strings.NewReader(c.login.Fields.Encode()))
+	if err != nil {
+		return fmt.Errorf("ログインリクエストの作成に失敗しました: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ログインに失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _, err := readResponseBody(resp, c.maxBodySize)
+	if err != nil {
+		return fmt.Errorf("ログインレスポンスの読み込みに失敗しました: %w", err)
+	}
+
+	if c.login.SuccessRegex != nil {
+		if !c.login.SuccessRegex.Match(body) {
+			return fmt.Errorf("ログインに失敗しました: レスポンスが --login-success-regex (%s) に一致しません (%s)", c.login.SuccessRegex, resp.Status)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("ログインに失敗しました: %s が %s を返しました", c.login.URL, resp.Status)
+	}
+
+	c.logger.Info("ログインに成功しました")
+	return nil
+}
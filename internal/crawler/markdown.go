@@ -0,0 +1,969 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// blockElements はMarkdownのブロックとして扱う要素
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true, "dd": true,
+	"details": true, "dialog": true, "div": true, "dl": true, "dt": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hgroup": true, "hr": true,
+	"li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true,
+	"summary": true, "table": true, "ul": true,
+}
+
+// skippedElements は本文として変換しない要素
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "head": true,
+	"svg": true, "canvas": true, "iframe": true, "object": true,
+}
+
+// LinkStyle は本文のリンクの書き出し方
+type LinkStyle string
+
+const (
+	LinkInline   LinkStyle = "inline"   // [text](url) のMarkdownのリンク
+	LinkFootnote LinkStyle = "footnote" // text [1] とページの末尾のリンクの一覧
+)
+
+// ParseLinkStyle は --link-style の値を解析する
+func ParseLinkStyle(s string) (LinkStyle, error) {
+	switch style := LinkStyle(strings.ToLower(s)); style {
+	case LinkInline, LinkFootnote:
+		return style, nil
+	}
+	return "", fmt.Errorf("--link-style には inline または footnote を指定してください: %s", s)
+}
+
+// markdownConverter はHTMLの要素をMarkdownに変換する
+type markdownConverter struct {
+	base          string    // 相対URLを解決する基準のURL
+	linkStyle     LinkStyle // リンクの書き出し方（空の場合は inline）
+	keepFragments bool      // 同じページ内の見出しなどへのリンクも残すか
+	tabsMode      TabsMode  // タブで切り替える内容の書き出し方（空の場合は all）
+	dedentCode    bool      // コードブロックの共通の字下げを取り除くか
+
+	footnotes []string       // footnote で書き出したリンク先（番号順）
+	noteIndex map[string]int // リンク先の番号
+	headings  []Heading      // 書き出した見出し
+	anchors   map[string]int // 見出しのアンカーごとの使われた回数
+	skip      *html.Node     // 変換しない要素（書き出し済みの注記の見出し）
+}
+
+// mdBlock は変換したMarkdownのブロック
+type mdBlock struct {
+	text  string
+	quote bool // 引用（blockquote と注記）から変換したブロックか（入れ子の引用の判定に使う）
+}
+
+// joinBlocks はブロックを空行で区切ってつなげる
+func joinBlocks(blocks []mdBlock) string {
+	texts := make([]string, len(blocks))
+	for i, b := range blocks {
+		texts[i] = b.text
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// Heading は本文の見出しとページ内のリンク先のアンカー
+type Heading struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`
+}
+
+// blocks は要素の子ノードをMarkdownのブロックに変換する
+// ブロック要素の間にある連続したテキストやインライン要素は1つの段落とする
+func (m *markdownConverter) blocks(n *html.Node) []mdBlock {
+	var blocks []mdBlock
+	var inline strings.Builder
+	flush := func() {
+		if text := normalizeInline(inline.String()); text != "" {
+			blocks = append(blocks, mdBlock{text: text})
+		}
+		inline.Reset()
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if tex, display, ok := mathFormula(child); ok {
+			if display {
+				flush()
+				if formula := formatMath(tex, true, true); formula != "" {
+					blocks = append(blocks, mdBlock{text: formula})
+				}
+				continue
+			}
+			inline.WriteString(formatMath(tex, false, false))
+			continue
+		}
+		if child.Type == html.ElementNode && (skippedElements[child.Data] || isHidden(child)) || child == m.skip {
+			continue
+		}
+		if child.Type == html.ElementNode && blockElements[child.Data] {
+			flush()
+			blocks = append(blocks, m.block(child)...)
+			continue
+		}
+		m.inline(&inline, child)
+	}
+	flush()
+	return blocks
+}
+
+// block はブロック要素を要素の種類に応じたMarkdownに変換する（それ以外の要素は子ノードをたどる）
+func (m *markdownConverter) block(n *html.Node) []mdBlock {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := strings.ReplaceAll(m.inlineText(n), "\\\n", " ")
+		if text == "" {
+			return nil
+		}
+		level := int(n.Data[1] - '0')
+		plain := strings.Join(strings.Fields(textContent(n)), " ")
+		anchor := m.headingAnchor(n, plain)
+		m.headings = append(m.headings, Heading{Level: level, Text: plain, Anchor: anchor})
+		return []mdBlock{{text: strings.Repeat("#", level) + " " + text + " {#" + anchor + "}"}}
+	case "p", "dt", "dd", "figcaption", "summary":
+		if text := m.inlineText(n); text != "" {
+			return []mdBlock{{text: text}}
+		}
+		return nil
+	case "ul", "ol":
+		if list := m.list(n, ""); list != "" {
+			return []mdBlock{{text: list}}
+		}
+		return nil
+	case "dl":
+		if list := m.definitionList(n); list != "" {
+			return []mdBlock{{text: list}}
+		}
+		return nil
+	case "table":
+		if isCodeTable(n) {
+			if code := m.codeBlock(n); code != "" {
+				return []mdBlock{{text: code}}
+			}
+			return nil
+		}
+		if table := m.table(n); table != "" {
+			return []mdBlock{{text: table}}
+		}
+		return nil
+	case "pre":
+		if code := m.codeBlock(n); code != "" {
+			return []mdBlock{{text: code}}
+		}
+		return nil
+	case "blockquote":
+		if quote := m.quoteBlocks(m.blocks(n)); quote != "" {
+			return []mdBlock{{text: quote, quote: true}}
+		}
+		return nil
+	case "hr":
+		return []mdBlock{{text: "---"}}
+	default:
+		if tabs, ok := m.tabGroup(n); ok {
+			return tabs
+		}
+		if label, title, ok := admonition(n); ok {
+			if callout := m.admonitionBlock(n, label, title); callout != "" {
+				return []mdBlock{{text: callout, quote: true}}
+			}
+			return nil
+		}
+		return m.blocks(n)
+	}
+}
+
+// quoteBlocks はブロックを引用として各行の先頭に > を付ける（入れ子の引用は >> のように記号を続ける）
+// 入れ子の引用は行の内容ではなく、引用から変換したブロックかどうかで判定する（> で始まるコードの行などを引用とみなさない）
+func (m *markdownConverter) quoteBlocks(blocks []mdBlock) string {
+	var lines []string
+	for _, block := range blocks {
+		if len(lines) > 0 {
+			lines = append(lines, ">")
+		}
+		prefix := "> "
+		if block.quote {
+			prefix = ">"
+		}
+		for _, line := range strings.Split(block.text, "\n") {
+			lines = append(lines, strings.TrimRight(prefix+line, " "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inlineText は要素の内容をインラインのMarkdownに変換する
+func (m *markdownConverter) inlineText(n *html.Node) string {
+	var sb strings.Builder
+	m.inlineChildren(&sb, n)
+	return normalizeInline(sb.String())
+}
+
+// inlineChildren は子ノードをインラインのMarkdownとして書き出す
+func (m *markdownConverter) inlineChildren(sb *strings.Builder, n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		m.inline(sb, child)
+	}
+}
+
+// inline はノードをインラインのMarkdownとして書き出す
+func (m *markdownConverter) inline(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(escapeMarkdown(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if tex, display, ok := mathFormula(n); ok {
+		sb.WriteString(formatMath(tex, display, false))
+		return
+	}
+	if skippedElements[n.Data] || isHidden(n) {
+		return
+	}
+
+	switch n.Data {
+	case "br":
+		sb.WriteString("\\\n")
+	case "a":
+		text := m.inlineText(n)
+		href := strings.TrimSpace(attr(n, "href"))
+		if text == "" {
+			return
+		}
+		if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			sb.WriteString(text)
+			return
+		}
+		resolved := m.resolve(href)
+		if !m.keepFragments && m.isFragmentLink(href, resolved) {
+			sb.WriteString(text)
+			return
+		}
+		m.link(sb, strings.ReplaceAll(text, "\\\n", " "), strings.TrimSpace(textContent(n)), resolved)
+	case "img":
+		src := imageSource(n)
+		if src == "" || isDecorativeImage(n) {
+			return
+		}
+		src = m.resolve(src)
+		fmt.Fprintf(sb, "![%s](%s)", escapeMarkdown(imageAlt(n, src)), markdownURL(src))
+	case "strong", "b":
+		m.emphasis(sb, n, "**")
+	case "em", "i":
+		m.emphasis(sb, n, "*")
+	case "del", "s", "strike":
+		m.emphasis(sb, n, "~~")
+	case "code", "kbd", "samp", "tt":
+		sb.WriteString(codeSpan(textContent(n)))
+	default:
+		// 段落の中などインラインとして扱うブロック要素は前後を空白で区切る
+		if blockElements[n.Data] {
+			sb.WriteString(" ")
+			m.inlineChildren(sb, n)
+			sb.WriteString(" ")
+			return
+		}
+		m.inlineChildren(sb, n)
+	}
+}
+
+// link はリンクを書き出し方に応じて書き出す
+// リンクのテキストがURLそのものの場合はURLを繰り返さない
+func (m *markdownConverter) link(sb *strings.Builder, label, raw, target string) {
+	sameAsURL := raw == target || strings.TrimSuffix(raw, "/") == strings.TrimSuffix(target, "/")
+	if m.linkStyle == LinkFootnote {
+		if sameAsURL {
+			sb.WriteString(target)
+			return
+		}
+		number, ok := m.noteIndex[target]
+		if !ok {
+			if m.noteIndex == nil {
+				m.noteIndex = make(map[string]int)
+			}
+			m.footnotes = append(m.footnotes, target)
+			number = len(m.footnotes)
+			m.noteIndex[target] = number
+		}
+		fmt.Fprintf(sb, "%s [%d]", label, number)
+		return
+	}
+	if sameAsURL && !strings.ContainsAny(target, " <>") {
+		sb.WriteString("<" + target + ">")
+		return
+	}
+	fmt.Fprintf(sb, "[%s](%s)", label, markdownURL(target))
+}
+
+// isFragmentLink は同じページ内へのリンク（#section など）かを判定する
+func (m *markdownConverter) isFragmentLink(href, resolved string) bool {
+	if strings.HasPrefix(href, "#") {
+		return true
+	}
+	return strings.Contains(resolved, "#") && m.base != "" && stripFragment(resolved) == stripFragment(m.base)
+}
+
+// footnoteList は footnote で書き出したリンクの一覧を返す（リンクがない場合は空）
+func (m *markdownConverter) footnoteList() string {
+	lines := make([]string, len(m.footnotes))
+	for i, target := range m.footnotes {
+		lines[i] = fmt.Sprintf("[%d] %s", i+1, target)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// headingAnchor は見出しのアンカーを返す
+// 見出しの id 属性、中のa要素の name・id 属性の順に使い、どちらもなければ見出しのテキストから作る（ページ内で重複する場合は -1 などを付ける）
+func (m *markdownConverter) headingAnchor(n *html.Node, text string) string {
+	anchor := strings.TrimSpace(attr(n, "id"))
+	if anchor == "" {
+		anchor = childAnchor(n)
+	}
+	if anchor == "" {
+		anchor = slugify(text)
+	}
+	if m.anchors == nil {
+		m.anchors = make(map[string]int)
+	}
+	base := anchor
+	for i := 1; m.anchors[anchor] > 0; i++ {
+		anchor = fmt.Sprintf("%s-%d", base, i)
+	}
+	m.anchors[anchor]++
+	return anchor
+}
+
+// childAnchor は要素の中の最初のa要素の name または id 属性を返す
+func childAnchor(n *html.Node) string {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if c.Data == "a" {
+			if name := strings.TrimSpace(attr(c, "name")); name != "" {
+				return name
+			}
+			if id := strings.TrimSpace(attr(c, "id")); id != "" {
+				return id
+			}
+		}
+		if anchor := childAnchor(c); anchor != "" {
+			return anchor
+		}
+	}
+	return ""
+}
+
+// slugify は見出しのテキストからアンカーを作る（小文字にし、空白を - にして記号を取り除く）
+func slugify(text string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			sb.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-':
+			sb.WriteRune('-')
+		}
+	}
+	slug := strings.Trim(sb.String(), "-")
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+	if slug == "" {
+		return "section"
+	}
+	return slug
+}
+
+// emphasis は強調の記号で内容を囲む（前後の空白は記号の外に出す）
+func (m *markdownConverter) emphasis(sb *strings.Builder, n *html.Node, marker string) {
+	var inner strings.Builder
+	m.inlineChildren(&inner, n)
+	raw := inner.String()
+	text := normalizeInline(raw)
+	if text == "" {
+		sb.WriteString(strings.Repeat(" ", min(len(raw), 1)))
+		return
+	}
+	if strings.TrimLeftFunc(raw, unicode.IsSpace) != raw {
+		sb.WriteString(" ")
+	}
+	sb.WriteString(marker + text + marker)
+	if strings.TrimRightFunc(raw, unicode.IsSpace) != raw {
+		sb.WriteString(" ")
+	}
+}
+
+// list はリストを項目ごとの行に変換する（入れ子のリストは項目の記号の幅だけ字下げし、* の項目では1段につき2文字になる）
+// 番号付きリストは start 属性と項目の value 属性の番号を使う
+// reversed 属性のリストは降順の番号をそのまま書き出す（Markdownとして表示すると最初の番号からの昇順になる）
+func (m *markdownConverter) list(n *html.Node, indent string) string {
+	var lines []string
+	reversed := n.Data == "ol" && hasAttr(n, "reversed")
+	step := 1
+	number := 1
+	if reversed {
+		step = -1
+		number = 0
+		for li := n.FirstChild; li != nil; li = li.NextSibling {
+			if li.Type == html.ElementNode && li.Data == "li" {
+				number++
+			}
+		}
+	}
+	if start, err := strconv.Atoi(strings.TrimSpace(attr(n, "start"))); err == nil {
+		number = start
+	}
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.Data != "li" || isHidden(li) {
+			continue
+		}
+		marker := "* "
+		if n.Data == "ol" {
+			if value, err := strconv.Atoi(strings.TrimSpace(attr(li, "value"))); err == nil {
+				number = value
+			}
+			marker = strconv.Itoa(number) + ". "
+			number += step
+		}
+		childIndent := indent + strings.Repeat(" ", len(marker))
+
+		var text strings.Builder
+		var nested []string
+		var collect func(*html.Node)
+		collect = func(node *html.Node) {
+			for c := node.FirstChild; c != nil; c = c.NextSibling {
+				switch {
+				case isHidden(c):
+				case c.Type == html.ElementNode && (c.Data == "ul" || c.Data == "ol"):
+					if list := m.list(c, childIndent); list != "" {
+						nested = append(nested, list)
+					}
+				case c.Type == html.ElementNode && c.Data == "pre":
+					if code := m.codeBlock(c); code != "" {
+						nested = append(nested, indentLines(code, childIndent))
+					}
+				case c.Type == html.ElementNode && c.Data == "blockquote":
+					if quote := m.block(c); len(quote) > 0 {
+						nested = append(nested, indentLines(quote[0].text, childIndent))
+					}
+				case c.Type == html.ElementNode && blockElements[c.Data] && c.Data != "table" && hasListBlock(c):
+					// div などで囲まれた入れ子のリストも項目のテキストに混ぜずに字下げする
+					text.WriteString(" ")
+					collect(c)
+					text.WriteString(" ")
+				default:
+					m.inline(&text, c)
+				}
+			}
+		}
+		collect(li)
+		item := normalizeInline(text.String())
+		if item == "" && len(nested) == 0 {
+			continue
+		}
+		lines = append(lines, indent+marker+strings.ReplaceAll(item, "\n", "\n"+childIndent))
+		lines = append(lines, nested...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// definitionList は定義リストを太字の用語の行とその下に字下げした説明に変換する
+// 説明を共有する複数の用語はそれぞれの行に書き出し、説明の中の入れ子の定義リストはさらに字下げする
+func (m *markdownConverter) definitionList(n *html.Node) string {
+	var sb strings.Builder
+	prev := ""
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || isHidden(c) {
+				continue
+			}
+			var text string
+			switch c.Data {
+			case "dt":
+				if term := strings.ReplaceAll(m.inlineText(c), "\\\n", " "); term != "" {
+					text = "**" + term + "**"
+				}
+			case "dd":
+				text = indentLines(joinBlocks(m.blocks(c)), "  ")
+			case "div":
+				// dl の中で dt と dd の組を囲む div
+				walk(c)
+				continue
+			}
+			if text == "" {
+				continue
+			}
+			// 用語と説明、続けて並ぶ用語は改行のみで区切る
+			if prev != "" {
+				if prev == "dt" {
+					sb.WriteString("\n")
+				} else {
+					sb.WriteString("\n\n")
+				}
+			}
+			sb.WriteString(text)
+			prev = c.Data
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// hasListBlock は要素の中にリスト、コードブロック、引用があるかを判定する（テーブルの中は除く）
+func hasListBlock(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data == "table" {
+			continue
+		}
+		if c.Data == "ul" || c.Data == "ol" || c.Data == "pre" || c.Data == "blockquote" || hasListBlock(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// table はテーブルをMarkdownの表に変換する
+// thead要素（なければtbody要素）の最初の行を見出しの行とする
+// 途中にあるすべてのセルがth要素の行（グループの区切りなど）は見出しにせず、ソースの位置のまま出力する
+// tfoot要素の行はブラウザの表示と同じく、ソースでの位置によらず最後に出力する
+func (m *markdownConverter) table(n *html.Node) string {
+	var body, foot [][]string
+	var walk func(*html.Node, *[][]string)
+	walk = func(node *html.Node, rows *[][]string) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "thead", "tbody":
+				walk(c, rows)
+			case "tfoot":
+				walk(c, &foot)
+			case "tr":
+				var cells []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "th" || cell.Data == "td") {
+						// セル内の改行は表の行を壊すため空白にする
+						text := strings.Join(strings.Fields(strings.ReplaceAll(m.inlineText(cell), "\\\n", " ")), " ")
+						cells = append(cells, strings.ReplaceAll(text, "|", "\\|"))
+					}
+				}
+				if len(cells) > 0 {
+					*rows = append(*rows, cells)
+				}
+			}
+		}
+	}
+	walk(n, &body)
+
+	rows := append(body, foot...)
+	if len(rows) == 0 {
+		return ""
+	}
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	var sb strings.Builder
+	writeRow := func(cells []string) {
+		for len(cells) < columns {
+			cells = append(cells, "")
+		}
+		sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	writeRow(rows[0])
+	sb.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// codeBlock はpre要素（または行番号の列のあるコードのtable要素）をフェンスで囲んだコードブロックに変換する（内容はエスケープしない）
+// 中にcode要素があればそのテキストのみを使い、コピーボタンなどpre要素内のほかの要素は含めない
+// 字下げや空白は前後の空行を除いてそのまま残す（dedentCode の指定があれば共通の字下げを取り除く）
+func (m *markdownConverter) codeBlock(n *html.Node) string {
+	source := n
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "code" {
+			source = c
+			break
+		}
+	}
+	code := trimBlankLines(codeText(source))
+	if code == "" {
+		return ""
+	}
+	if m.dedentCode {
+		code = dedent(code)
+	}
+	lang := codeLanguage(n)
+	if lang == "" && n.Data == "table" {
+		if pre := findElement(n, "pre"); pre != nil {
+			lang = codeLanguage(pre)
+		}
+	}
+	fence := strings.Repeat("`", max(3, longestRun(code, '`')+1))
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+// trimBlankLines は先頭と末尾の空白のみの行を取り除く（残す行の字下げや空白は変更しない）
+func trimBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dedent はすべての行（空白のみの行を除く）に共通する行頭の空白を取り除く
+func dedent(s string) string {
+	lines := strings.Split(s, "\n")
+	prefix, found := "", false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			prefix, found = indent, true
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if prefix == "" {
+		return s
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, prefix)
+		if strings.TrimSpace(lines[i]) == "" && !strings.HasPrefix(line, prefix) {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// codeLanguagePrefixes はコードの言語を表すクラスの接頭辞（Prism・highlight.jsの language-、lang-、Sphinxの highlight-）
+var codeLanguagePrefixes = []string{"language-", "lang-", "highlight-source-", "highlight-"}
+
+// plainLanguages は言語の指定のないコードを表すクラスの値
+var plainLanguages = map[string]bool{
+	"none": true, "nohighlight": true, "plain": true, "plaintext": true, "text": true, "default": true,
+}
+
+// maxCodeWrappers はコードの言語のクラスを探すpre要素を囲むdiv要素の数（Rouge・Sphinxの div.highlight など）
+const maxCodeWrappers = 2
+
+// codeLanguage はpre要素、その中のcode要素、pre要素を囲むdiv要素のクラス（language-go、highlight-python など）からコードの言語を返す
+// 言語が分からない場合は空を返す
+func codeLanguage(n *html.Node) string {
+	candidates := []*html.Node{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "code" {
+			candidates = append(candidates, c)
+		}
+	}
+	candidates = append(candidates, n)
+	for p, i := n.Parent, 0; p != nil && i < maxCodeWrappers && p.Type == html.ElementNode && p.Data == "div"; p, i = p.Parent, i+1 {
+		candidates = append(candidates, p)
+	}
+	for _, node := range candidates {
+		for _, class := range strings.Fields(attr(node, "class")) {
+			for _, prefix := range codeLanguagePrefixes {
+				lang, ok := strings.CutPrefix(strings.ToLower(class), prefix)
+				if !ok || !isLanguageName(lang) {
+					continue
+				}
+				if plainLanguages[lang] {
+					return ""
+				}
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// isLanguageName は値がフェンスの情報文字列に使える言語名かを判定する
+func isLanguageName(lang string) bool {
+	if lang == "" {
+		return false
+	}
+	for _, r := range lang {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("+#-_.", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// lazyImageAttributes は遅延読み込みの画像の実際のURLを持つ属性
+var lazyImageAttributes = []string{"data-src", "data-original", "data-lazy-src", "data-srcset", "srcset"}
+
+// maxPixelSize はトラッキング用の画像とみなす幅・高さの上限
+const maxPixelSize = 1
+
+// imageSource は画像のURLを返す
+// src がないかプレースホルダー（data: や空白の画像）の場合は、遅延読み込みの属性や srcset の最初の候補を使う
+func imageSource(n *html.Node) string {
+	src := strings.TrimSpace(attr(n, "src"))
+	if src != "" && !isPlaceholderImage(src) {
+		return src
+	}
+	for _, name := range lazyImageAttributes {
+		value := strings.TrimSpace(attr(n, name))
+		if strings.HasSuffix(name, "srcset") {
+			// srcset は "URL 幅, URL 幅" の形式のため最初の候補のURLのみを使う
+			value, _, _ = strings.Cut(value, ",")
+			if fields := strings.Fields(value); len(fields) > 0 {
+				value = fields[0]
+			}
+		}
+		if value != "" && !isPlaceholderImage(value) {
+			return value
+		}
+	}
+	return ""
+}
+
+// isPlaceholderImage は遅延読み込みの前に表示するプレースホルダーの画像のURLかを判定する
+func isPlaceholderImage(src string) bool {
+	lower := strings.ToLower(src)
+	if strings.HasPrefix(lower, "data:") {
+		return true
+	}
+	for _, name := range []string{"placeholder", "blank.gif", "spacer.gif", "transparent.gif", "pixel.gif"} {
+		if strings.Contains(lower, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDecorativeImage は内容を持たない装飾やトラッキング用の画像かを判定する
+// alt が空で role="presentation" の画像と、幅または高さが1ピクセル以下の画像
+func isDecorativeImage(n *html.Node) bool {
+	role := strings.ToLower(strings.TrimSpace(attr(n, "role")))
+	if strings.TrimSpace(attr(n, "alt")) == "" && (role == "presentation" || role == "none") {
+		return true
+	}
+	for _, name := range []string{"width", "height"} {
+		if size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(attr(n, name)), "px")); err == nil && size <= maxPixelSize {
+			return true
+		}
+	}
+	return false
+}
+
+// imageAlt は画像の代替テキストを返す（空の場合は title 属性、それもなければファイル名）
+func imageAlt(n *html.Node, src string) string {
+	if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+		return alt
+	}
+	if title := strings.TrimSpace(attr(n, "title")); title != "" {
+		return title
+	}
+	if u, err := url.Parse(src); err == nil {
+		if name := path.Base(u.Path); name != "." && name != "/" {
+			if unescaped, err := url.PathUnescape(name); err == nil {
+				return unescaped
+			}
+			return name
+		}
+	}
+	return ""
+}
+
+// codeSpan はインラインのコードをバッククォートで囲む（内容にバッククォートを含む場合は囲む数を増やす）
+func codeSpan(code string) string {
+	code = strings.Join(strings.Fields(code), " ")
+	if code == "" {
+		return ""
+	}
+	fence := strings.Repeat("`", longestRun(code, '`')+1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+// textContent はノード以下のテキストをそのまま連結する（br は改行とする）
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		switch {
+		case node.Type == html.TextNode:
+			sb.WriteString(node.Data)
+		case node.Type == html.ElementNode && node.Data == "br":
+			sb.WriteString("\n")
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// normalizeInline はインラインのMarkdownの連続する空白を1つにし、前後の空白と改行を取り除く
+// 改行は br による強制改行（行末の \）のみ残す
+func normalizeInline(s string) string {
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" && line != "\\" {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	kept[len(kept)-1] = strings.TrimSuffix(kept[len(kept)-1], "\\")
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// escapeMarkdown はテキストのうちMarkdownの記法と解釈される文字をエスケープする
+// 単語の途中の _ は強調にならないためエスケープしない
+func escapeMarkdown(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch r {
+		case '\\', '`', '*', '[', ']':
+			sb.WriteRune('\\')
+		case '>':
+			// 行頭の > は引用の記号と区別する
+			if atLineStart(runes[:i]) {
+				sb.WriteRune('\\')
+			}
+		case '_':
+			inWord := i > 0 && i < len(runes)-1 && isWordRune(runes[i-1]) && isWordRune(runes[i+1])
+			if !inWord {
+				sb.WriteRune('\\')
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// atLineStart はテキストの行頭（前が空白のみ）かどうかを判定する
+func atLineStart(before []rune) bool {
+	for i := len(before) - 1; i >= 0; i-- {
+		if before[i] == '\n' {
+			return true
+		}
+		if !unicode.IsSpace(before[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isWordRune は単語を構成する文字かどうかを判定する
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// markdownURL はリンク先のURLに空白や括弧が含まれる場合は <> で囲む
+func markdownURL(u string) string {
+	if strings.ContainsAny(u, " ()") {
+		return "<" + strings.ReplaceAll(u, ">", "%3E") + ">"
+	}
+	return u
+}
+
+// resolve はリンク先のURLをページのURLを基準に絶対URLにする（解決できない場合はそのまま）
+func (m *markdownConverter) resolve(ref string) string {
+	if m.base == "" {
+		return ref
+	}
+	resolved, err := resolveURL(m.base, ref)
+	if err != nil {
+		return ref
+	}
+	return resolved
+}
+
+// indentLines は各行の先頭に字下げを加える（空行は字下げしない）
+func indentLines(s, indent string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// longestRun は文字列に含まれる文字 r の最長の連続数を返す
+func longestRun(s string, r rune) int {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c == r {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+// attr は要素の属性の値を返す（ない場合は空）
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// findElement は子孫のうち最初の tag の要素を返す（なければnil）
+func findElement(n *html.Node, tag string) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if c.Data == tag {
+			return c
+		}
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// hasAttr は要素に属性があるかを判定する（値のない真偽値の属性も含む）
+func hasAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package crawler
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var updateGolden = flag.Bool("update", false, "testdata の golden ファイルを現在の出力で更新する")
+
+// fixtureURL はテスト用のHTMLを取得したとみなすページのURL
+const fixtureURL = "https://docs.example.com/guide/page.html"
+
+// convertFixture はHTMLのファイルをクロール時と同じ手順（除外する要素の削除、本文の要素の推定、Markdownへの変換）で変換する
+func convertFixture(t *testing.T, c *Crawler, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+	converter := c.newConverter(fixtureURL)
+	return extractText(title, c.contentRoot(c.contentDocument(doc), fixtureURL), converter)
+}
+
+// assertGolden は出力を .html と同じ名前の .md ファイルと比較する（-update の場合は .md ファイルを更新する）
+func assertGolden(t *testing.T, htmlPath, got string) {
+	t.Helper()
+	golden := strings.TrimSuffix(htmlPath, ".html") + ".md"
+	if *updateGolden {
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("golden ファイルを読み込めません（go test -update で作成できます）: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s と出力が異なります\ngot:\n%s\nwant:\n%s", golden, got, want)
+	}
+}
+
+// TestMarkdownGolden は testdata/markdown のHTMLを変換した結果を golden ファイルと比較する
+func TestMarkdownGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "markdown", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("testdata/markdown にHTMLがありません")
+	}
+	c := New("https://docs.example.com/", 3, 10, 0, 0, WithLogger(discardLogger()))
+	for _, file := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".html"), func(t *testing.T) {
+			assertGolden(t, file, convertFixture(t, c, file))
+		})
+	}
+}
+
+// TestExtractTextDocumentOrder は見出し・段落・リスト・表・コード・引用が文書の順に出力されることを確認する
+func TestExtractTextDocumentOrder(t *testing.T) {
+	c := New("https://docs.example.com/", 3, 10, 0, 0, WithLogger(discardLogger()))
+	got := convertFixture(t, c, filepath.Join("testdata", "markdown", "document-order.html"))
+
+	markers := []string{
+		"## File format",
+		"A minimal configuration",
+		"```yaml",
+		"Every key is optional.",
+		"## Options",
+		"* **port**",
+		"| Option | Default |",
+		"> Changing `host`",
+		"### Environment variables",
+		"1. Prefix the name",
+		"```bash",
+		"## Next steps",
+	}
+	last := -1
+	for _, marker := range markers {
+		i := strings.Index(got, marker)
+		if i < 0 {
+			t.Fatalf("%q が出力に含まれていません:\n%s", marker, got)
+		}
+		if i < last {
+			t.Errorf("%q が文書の順に出力されていません:\n%s", marker, got)
+		}
+		last = i
+	}
+}
+
+// TestExtractTextCodeOnce はブロックのコードとインラインのコードがそれぞれ1回だけ出力されることを確認する
+func TestExtractTextCodeOnce(t *testing.T) {
+	c := New("https://docs.example.com/", 3, 10, 0, 0, WithLogger(discardLogger()))
+	got := convertFixture(t, c, filepath.Join("testdata", "markdown", "code-once.html"))
+
+	for _, sample := range []string{
+		"example build --release",
+		"./scripts/legacy-deploy.sh",
+		"export EXAMPLE_TOKEN=changeme",
+		"`example build`",
+		"`--release`",
+		"`EXAMPLE_TOKEN`",
+		"`example rollback`",
+	} {
+		if n := strings.Count(got, sample); n != 1 {
+			t.Errorf("%q の出力回数 = %d, want 1:\n%s", sample, n, got)
+		}
+	}
+	// フェンスはpre要素の数だけ出力し、インラインのコードはフェンスにしない
+	if n := strings.Count(got, "```"); n != 6 {
+		t.Errorf("フェンスの数 = %d, want 6:\n%s", n, got)
+	}
+}
+
+// TestExtractTextSphinxParameters はSphinxの定義リストの引数の説明が失われないことを確認する
+func TestExtractTextSphinxParameters(t *testing.T) {
+	c := New("https://docs.example.com/", 3, 10, 0, 0, WithLogger(discardLogger()))
+	got := convertFixture(t, c, filepath.Join("testdata", "markdown", "sphinx-dl.html"))
+
+	for _, want := range []string{
+		"example.client.Client(*base_url*, *timeout=30*)",
+		"HTTP client for the Example API.",
+		"**base_url** (*str*) – Root URL of the API server.",
+		"**timeout** (*float*) – Seconds to wait for a response.",
+		"**ValueError** – If *base_url* is not absolute.",
+		// 1つの説明を共有する別名の項目はすべて出力する
+		"**get(*path*)**",
+		"**fetch(*path*)**",
+		"Send a GET request and return the decoded JSON body.",
+		"The response payload.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q が出力に含まれていません:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "¶") {
+		t.Errorf("見出しのリンクの記号が出力されています:\n%s", got)
+	}
+}
+
+// TestExtractTextTabsFirst は --tabs first で選択されているタブのみが出力されることを確認する
+func TestExtractTextTabsFirst(t *testing.T) {
+	c := New("https://docs.example.com/", 3, 10, 0, 0, WithLogger(discardLogger()), WithTabsMode(TabsFirst))
+	got := convertFixture(t, c, filepath.Join("testdata", "markdown", "tabs.html"))
+
+	for _, want := range []string{"**Yarn**", "yarn add example", "**Go**", `import "example.com/client"`, "**Linux**", "apt install example"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("選択されているタブの %q が出力に含まれていません:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"npm install example", "pnpm add example", "**Python**", "import example\n", "**macOS**", "brew install example"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("選択されていないタブの %q が出力されています:\n%s", unwanted, got)
+		}
+	}
+}
+
+// TestExtractTextQuoteMarkers は > で始まるテキストやコードの行が引用や入れ子の引用にならないことを確認する
+func TestExtractTextQuoteMarkers(t *testing.T) {
+	c := New("https://docs.example.com/", 3, 10, 0, 0, WithLogger(discardLogger()))
+	got := convertFixture(t, c, filepath.Join("testdata", "markdown", "quote-markers.html"))
+
+	for _, want := range []string{
+		"\n\\> Replies quote the previous message", // 引用の外の段落の行頭の > はエスケープする
+		"Values must be > 0.",                      // 行の途中の > はエスケープしない
+		"\n> \\> reply from the maintainer\n",      // 引用の中の段落は入れ子の引用にしない
+		"\n> > npm install example@next\n",         // 引用の中のコードの行も入れ子の引用にしない
+		"\n>> Nested quote.",                       // blockquote の入れ子のみ >> にする
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q が出力に含まれていません:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// texEncoding はMathMLの annotation でTeXのソースを表すエンコーディング
+const texEncoding = "application/x-tex"
+
+// mathFormula は数式の要素であれば、元のTeXのソースと別行立ての数式かを返す
+// KaTeX（.katex の annotation）、MathJax v2（script[type="math/tex"]）、MathJax v3（mjx-container の MathML）、
+// pymdownx.arithmatex、MathMLの math 要素に対応する
+// MathJax v2 の描画結果の要素は script と内容が重複するため、ソースを空として ok を返す
+func mathFormula(n *html.Node) (tex string, display, ok bool) {
+	if n.Type != html.ElementNode {
+		return "", false, false
+	}
+	switch {
+	case n.Data == "script":
+		typ := strings.ToLower(attr(n, "type"))
+		if !strings.HasPrefix(typ, "math/tex") {
+			return "", false, false
+		}
+		return strings.TrimSpace(textContent(n)), strings.Contains(typ, "mode=display"), true
+	case n.Data == "mjx-container":
+		return mathSource(n), attr(n, "display") == "true", true
+	case hasClass(n, "katex-display"):
+		return mathSource(n), true, true
+	case hasClass(n, "katex"):
+		return mathSource(n), false, true
+	case hasClass(n, "mwe-math-element"):
+		math := findElement(n, "math")
+		return mathSource(n), math != nil && attr(math, "display") == "block", true
+	case hasClass(n, "arithmatex"):
+		return arithmatexSource(n)
+	case n.Data == "math":
+		return mathSource(n), attr(n, "display") == "block", true
+	}
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if strings.HasPrefix(class, "MathJax") || strings.HasPrefix(class, "MJX") {
+			return "", false, true
+		}
+	}
+	return "", false, false
+}
+
+// mathSource は数式の要素のTeXのソースを返す
+// annotation のTeX、なければ math 要素の alttext、なければ math 要素のテキストを使う
+func mathSource(n *html.Node) string {
+	var annotation *html.Node
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil && annotation == nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "annotation" && strings.EqualFold(attr(c, "encoding"), texEncoding) {
+				annotation = c
+				return
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	if annotation != nil {
+		return strings.TrimSpace(textContent(annotation))
+	}
+	math := n
+	if n.Data != "math" {
+		math = findElement(n, "math")
+	}
+	if math == nil {
+		return ""
+	}
+	if alt := strings.TrimSpace(attr(math, "alttext")); alt != "" {
+		return alt
+	}
+	return strings.Join(strings.Fields(textContent(math)), " ")
+}
+
+// arithmatexSource は pymdownx.arithmatex の要素（\(...\) や \[...\] で囲んだTeX）のソースを返す
+func arithmatexSource(n *html.Node) (string, bool, bool) {
+	text := strings.TrimSpace(textContent(n))
+	for _, delims := range [][2]string{{`\[`, `\]`}, {`$$`, `$$`}, {`\(`, `\)`}} {
+		if inner, ok := strings.CutPrefix(text, delims[0]); ok && strings.HasSuffix(inner, delims[1]) {
+			return strings.TrimSpace(strings.TrimSuffix(inner, delims[1])), delims[0] != `\(`, true
+		}
+	}
+	return text, n.Data == "div", true
+}
+
+// formatMath はTeXのソースを $...$（別行立ての数式は $$...$$）で囲む
+// block が true の場合は別行立ての数式をブロックとして区切り記号を前後の行に置く
+func formatMath(tex string, display, block bool) string {
+	switch {
+	case tex == "":
+		return ""
+	case display && block:
+		return "$$\n" + tex + "\n$$"
+	case display:
+		return "$$" + tex + "$$"
+	}
+	return "$" + tex + "$"
+}
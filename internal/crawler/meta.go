@@ -0,0 +1,63 @@
+package crawler
+
+import (
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// pageMeta はページの meta 要素から取得した説明とOpenGraphの情報
+type pageMeta struct {
+	description string    // meta description（なければ og:description）
+	ogTitle     string    // og:title
+	modifiedAt  time.Time // article:modified_time（なければ og:updated_time）
+}
+
+// modifiedTimeLayouts は article:modified_time の日時の形式
+var modifiedTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// extractMeta はページの meta 要素から説明、og:title、記事の更新日時を取得する
+func extractMeta(doc *goquery.Document) pageMeta {
+	meta := pageMeta{
+		description: metaContent(doc, "description"),
+		ogTitle:     metaContent(doc, "og:title"),
+	}
+	if meta.description == "" {
+		meta.description = metaContent(doc, "og:description")
+	}
+	modified := metaContent(doc, "article:modified_time")
+	if modified == "" {
+		modified = metaContent(doc, "og:updated_time")
+	}
+	for _, layout := range modifiedTimeLayouts {
+		if t, err := time.Parse(layout, modified); err == nil {
+			meta.modifiedAt = t
+			break
+		}
+	}
+	return meta
+}
+
+// metaContent は name または property が key の meta 要素の content を返す（空白は1つにまとめる）
+func metaContent(doc *goquery.Document, key string) string {
+	var content string
+	doc.Find("meta[name], meta[property]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		name := s.AttrOr("name", s.AttrOr("property", ""))
+		if !strings.EqualFold(strings.TrimSpace(name), key) {
+			return true
+		}
+		content = strings.Join(strings.Fields(s.AttrOr("content", "")), " ")
+		return content == ""
+	})
+	return content
+}
+
+// displayTitle は出力に表示するページのタイトルを返す
+// --prefer-og-title の指定があれば、「| サイト名」などの付かないことが多い og:title を <title> より優先する
+func (c *Crawler) displayTitle(title string, meta pageMeta) string {
+	if c.preferOGTitle && meta.ogTitle != "" {
+		return meta.ogTitle
+	}
+	return title
+}
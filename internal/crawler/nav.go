@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefaultNavSelector はナビゲーションの順序を取得する要素のデフォルトのセレクタ
+const DefaultNavSelector = "nav"
+
+// recordNavOrder はナビゲーション要素が参照する範囲内のURLを出現順に記録する
+// 既に記録済みのURLは最初の位置のままにする
+func (c *Crawler) recordNavOrder(doc *goquery.Document, pageURL string) {
+	doc.Find(c.navSelector).Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		resolved, err := resolveURL(pageURL, s.AttrOr("href", ""))
+		if err != nil || !c.inScope(resolved) {
+			return
+		}
+		u := c.normalizer.normalize(stripFragment(resolved))
+		c.mu.Lock()
+		if _, ok := c.navIndex[u]; !ok {
+			c.navIndex[u] = len(c.navOrder)
+			c.navOrder = append(c.navOrder, u)
+		}
+		c.mu.Unlock()
+	})
+}
+
+// NavOrder はナビゲーションに記載されていた順のURLを返す
+func (c *Crawler) NavOrder() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.navOrder...)
+}
+
+// SortByNav はページをナビゲーションの順に並べ替える
+// ナビゲーションにないページはその後にパスのディレクトリごとにまとめて並べる（同じディレクトリ内はクロール順）
+func SortByNav(pages []Page, nav []string) {
+	index := make(map[string]int, len(nav))
+	for i, u := range nav {
+		index[u] = i
+	}
+	position := func(p Page) (int, bool) {
+		for _, u := range append([]string{p.URL, p.RequestedURL, p.CanonicalURL}, p.AliasURLs...) {
+			if i, ok := index[stripFragment(u)]; ok && u != "" {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	directory := func(p Page) string {
+		if i := strings.Index(p.URL, "://"); i >= 0 {
+			if j := strings.Index(p.URL[i+3:], "/"); j >= 0 {
+				return path.Dir(p.URL[i+3+j:])
+			}
+		}
+		return "/"
+	}
+
+	sort.SliceStable(pages, func(i, j int) bool {
+		pi, oki := position(pages[i])
+		pj, okj := position(pages[j])
+		switch {
+		case oki && okj:
+			return pi < pj
+		case oki != okj:
+			return oki
+		default:
+			return directory(pages[i]) < directory(pages[j])
+		}
+	})
+}
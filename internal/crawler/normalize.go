@@ -0,0 +1,63 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+)
+
+// defaultStripParams は内容に影響しないため取り除くクエリパラメータ（トラッキング用や表示テーマの切り替え）
+var defaultStripParams = []string{
+	"fbclid", "gclid", "dclid", "msclkid", "yclid", "igshid",
+	"mc_cid", "mc_eid", "_ga", "_gl",
+	"ref", "ref_src",
+	"theme", "color-scheme",
+}
+
+// defaultStripParamPrefixes は前方一致で取り除くクエリパラメータ
+var defaultStripParamPrefixes = []string{"utm_"}
+
+// queryNormalizer はURLのクエリ文字列を正規化して重複したURLを1つにまとめる
+type queryNormalizer struct {
+	stripAll bool            // クエリ文字列をすべて取り除くか
+	keep     map[string]bool // 取り除かずに残すパラメータ
+	strip    map[string]bool // デフォルトに加えて取り除くパラメータ（小文字）
+}
+
+// normalize はフラグメント（#以降）とクエリパラメータを取り除いて並べ替えたURLを返す
+// ページ内の見出しへのリンクなどフラグメントのみが異なるURLは同じページとして扱う
+func (n *queryNormalizer) normalize(rawURL string) string {
+	rawURL = stripFragment(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+
+	query := u.Query()
+	for name := range query {
+		if n.keep[name] {
+			continue
+		}
+		if n.stripAll || n.strip[strings.ToLower(name)] || isStrippedParam(name) {
+			query.Del(name)
+		}
+	}
+	// Encode はパラメータ名でソートするため、順序の違うURLも同じになる
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// isStrippedParam はパラメータがデフォルトで取り除く対象かどうかを判定する
+func isStrippedParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range defaultStripParams {
+		if name == p {
+			return true
+		}
+	}
+	for _, prefix := range defaultStripParamPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package crawler
+
+import "testing"
+
+func TestQueryNormalizerStripParams(t *testing.T) {
+	c := &Crawler{}
+	WithQueryNormalization(false, []string{"keep_sid"}, []string{"SessionID", "keep_sid", "tab"})(c)
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		// デフォルトの除去対象に加えて指定したパラメータも取り除く（大文字と小文字は区別しない）
+		{"https://example.com/docs?page=2&sessionid=abc&utm_source=x", "https://example.com/docs?page=2"},
+		{"https://example.com/docs?tab=api&SessionID=abc#top", "https://example.com/docs"},
+		// --keep-param で指定したパラメータは除去対象であっても残す
+		{"https://example.com/docs?keep_sid=1&ref=nav", "https://example.com/docs?keep_sid=1"},
+		{"https://example.com/docs?b=2&a=1", "https://example.com/docs?a=1&b=2"},
+	}
+	for _, tt := range tests {
+		if got := c.normalizer.normalize(tt.in); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
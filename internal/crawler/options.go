@@ -0,0 +1,583 @@
+package crawler
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Option はCrawlerの追加設定を行う関数
+type Option func(*Crawler)
+
+// WithUserAgent はリクエストに設定するUser-Agentを指定する（空の場合はデフォルトのまま）
+func WithUserAgent(userAgent string) Option {
+	return func(c *Crawler) {
+		if userAgent != "" {
+			c.userAgent = userAgent
+		}
+	}
+}
+
+// WithAcceptLanguage はリクエストに設定するAccept-Languageを指定する（空の場合はデフォルトのまま）
+// 同じURLでも言語によって内容が変わるサイトがあるが、訪問済みの判定にはAccept-Languageを含めない
+func WithAcceptLanguage(acceptLanguage string) Option {
+	return func(c *Crawler) {
+		if acceptLanguage != "" {
+			c.acceptLang = acceptLanguage
+		}
+	}
+}
+
+// WithHeaders はすべてのリクエストに追加するヘッダーを指定する
+func WithHeaders(headers http.Header) Option {
+	return func(c *Crawler) {
+		c.headers = headers.Clone()
+	}
+}
+
+// WithBasicAuth はBasic認証の認証情報を指定する
+// 認証情報は開始URLのホストへのリクエストにのみ送信される
+func WithBasicAuth(user, pass string) Option {
+	return func(c *Crawler) {
+		c.authUser = user
+		c.authPass = pass
+	}
+}
+
+// WithBearerToken はBearerトークンによる認証を指定する
+// トークンは開始URLのホストへのリクエストにのみ送信され、ログには出力されない
+func WithBearerToken(token string) Option {
+	return func(c *Crawler) {
+		c.token = token
+	}
+}
+
+// WithCookies はクロール開始前にCookieジャーへ設定するCookieを指定する
+// Cookieは開始URLのドメインを対象に設定される
+func WithCookies(cookies []*http.Cookie) Option {
+	return func(c *Crawler) {
+		c.cookies = append(c.cookies, cookies...)
+	}
+}
+
+// WithLogin はクロール開始前に行うフォームログインを設定する
+func WithLogin(login *LoginConfig) Option {
+	return func(c *Crawler) {
+		c.login = login
+	}
+}
+
+// WithProxy は経由するプロキシを指定する（http://、https://、socks5:// に対応）
+// noEnvProxy が true の場合、プロキシ未指定時に HTTP_PROXY などの環境変数を無視する
+func WithProxy(proxyURL *url.URL, noEnvProxy bool) Option {
+	return func(c *Crawler) {
+		c.proxyURL = proxyURL
+		c.noEnvProxy = noEnvProxy
+	}
+}
+
+// WithHTTPClient はリクエストに使用するHTTPクライアントを差し替える
+// タイムアウトやプロキシの設定は client 側の設定が優先され、Cookieジャーが未設定の場合のみ補われる
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Crawler) {
+		c.client = client
+	}
+}
+
+// WithPreferSourceMarkdown は元のMarkdownソースが取得できるページでHTML抽出の代わりにソースを使用する
+func WithPreferSourceMarkdown(enabled bool) Option {
+	return func(c *Crawler) {
+		c.preferSourceMarkdown = enabled
+	}
+}
+
+// WithCheckpoint はクロール状態を定期的に保存するチェックポイントファイルを設定する
+// resume が true の場合はクロール開始時にファイルから状態を復元する
+func WithCheckpoint(path string, resume bool) Option {
+	return func(c *Crawler) {
+		c.checkpointPath = path
+		c.resume = resume
+	}
+}
+
+// WithContentDedup は同じ内容のページの重複排除を有効または無効にする
+// 同じ定型ページが複数あることが想定されるサイトでは無効にする
+func WithContentDedup(enabled bool) Option {
+	return func(c *Crawler) {
+		c.contentDedup = enabled
+	}
+}
+
+// WithMaxBodySize は1ページあたりのレスポンスボディの上限（バイト）を設定する
+// skipOversized が true の場合、上限を超えたページは切り詰めずにスキップする
+func WithMaxBodySize(limit int64, skipOversized bool) Option {
+	return func(c *Crawler) {
+		c.maxBodySize = limit
+		c.skipOversized = skipOversized
+	}
+}
+
+// WithPrecheck はGETの前にHEADリクエストを送り、HTMLではないページや上限を超えるページをスキップする
+func WithPrecheck(enabled bool) Option {
+	return func(c *Crawler) {
+		c.precheckEnabled = enabled
+	}
+}
+
+// WithSeeds は New に渡したURLに加えてクロールを開始するURLを追加する
+// すべての開始URLは深度0として扱われ、訪問済みのURLは共有される
+func WithSeeds(seeds []string) Option {
+	return func(c *Crawler) {
+		c.seeds = append(c.seeds, seeds...)
+	}
+}
+
+// WithSitemapOnly はリンクをたどらず、サイトマップに記載されたページのみを取得する
+// サイトマップが見つからない場合、クロールはエラーになる
+func WithSitemapOnly(enabled bool) Option {
+	return func(c *Crawler) {
+		c.sitemapOnly = enabled
+	}
+}
+
+// WithCache はレスポンスをディスクにキャッシュし、次回以降はETagやLast-Modifiedで変更を確認する
+// noRead が true の場合はキャッシュを読まずにすべて取得し直し、キャッシュの書き込みのみ行う
+func WithCache(dir string, noRead bool) Option {
+	return func(c *Crawler) {
+		c.cacheDir = dir
+		c.noCacheRead = noRead
+	}
+}
+
+// WithDelayJitter は待機時間を jitter の割合でランダムに揺らす（0.5で±50%）
+// adaptive が true の場合は、応答が遅いときやエラーが続くときに待機時間を伸ばし、快調なときは元の待機時間まで縮める
+func WithDelayJitter(jitter float64, adaptive bool) Option {
+	return func(c *Crawler) {
+		c.delayJitter = jitter
+		c.adaptiveDelay = adaptive
+	}
+}
+
+// WithMaxURLs はキューに追加できるURLの総数の上限を設定する（0以下は無制限）
+// 上限に達した後は新しいリンクをたどらず、キューに残っているURLのみを取得する
+func WithMaxURLs(limit int) Option {
+	return func(c *Crawler) {
+		c.maxURLs = limit
+	}
+}
+
+// WithKeepVariants は印刷用・AMP・モバイル版のページ（?print=1 や /amp/ など）もたどるようにする
+func WithKeepVariants(enabled bool) Option {
+	return func(c *Crawler) {
+		c.keepVariants = enabled
+	}
+}
+
+// WithLogger はクロールの進捗や警告を出力するロガーを設定する（デフォルトは標準エラー出力のInfoレベル）
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Crawler) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithDryRun はページを取得してリンクの探索のみを行い、コンテンツを抽出しない（Page.Content は空になる）
+func WithDryRun(enabled bool) Option {
+	return func(c *Crawler) {
+		c.dryRun = enabled
+	}
+}
+
+// WithSaveHTML は取得したページの元のHTMLをURLのパスに対応するファイルとして dir に保存する
+// dir には保存したファイルとURLの対応を示す manifest.json も書き出される
+func WithSaveHTML(dir string) Option {
+	return func(c *Crawler) {
+		c.htmlDir = dir
+	}
+}
+
+// WithExtensionFilter はリンクをたどらない拡張子を調整する
+// skip の拡張子をデフォルトの一覧に追加し、allow の拡張子を一覧から除く（先頭の.は省略可）
+func WithExtensionFilter(skip, allow []string) Option {
+	return func(c *Crawler) {
+		for _, ext := range skip {
+			if ext = normalizeExtension(ext); ext != "" {
+				c.skipExts[ext] = true
+			}
+		}
+		for _, ext := range allow {
+			delete(c.skipExts, normalizeExtension(ext))
+		}
+	}
+}
+
+// WithQueryNormalization はクエリパラメータの正規化を設定する
+// stripAll が true の場合はすべてのクエリ文字列を取り除き、strip のパラメータはデフォルトの除去対象に加えて取り除く
+// keep のパラメータは除去対象であっても残す
+func WithQueryNormalization(stripAll bool, keep, strip []string) Option {
+	return func(c *Crawler) {
+		c.normalizer.stripAll = stripAll
+		c.normalizer.keep = make(map[string]bool, len(keep))
+		for _, name := range keep {
+			c.normalizer.keep[name] = true
+		}
+		c.normalizer.strip = make(map[string]bool, len(strip))
+		for _, name := range strip {
+			c.normalizer.strip[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// WithURLList はリンクをたどらず、指定されたURLのみを順に取得する
+// 開始URLはクロール範囲の判定にのみ使用され、開始URL自体は取得しない
+func WithURLList(urls []ListedURL) Option {
+	return func(c *Crawler) {
+		c.urlList = urls
+	}
+}
+
+// WithMaxPagination は rel="next" で連続してたどるページ数の上限を設定する（0以下は無制限）
+func WithMaxPagination(limit int) Option {
+	return func(c *Crawler) {
+		c.maxPagination = limit
+	}
+}
+
+// WithFailFast はいずれかのページの取得に失敗した時点でクロールを中止するかを設定する
+func WithFailFast(enabled bool) Option {
+	return func(c *Crawler) {
+		c.failFast = enabled
+	}
+}
+
+// WithDepthMode は深度の数え方を設定する
+func WithDepthMode(mode DepthMode) Option {
+	return func(c *Crawler) {
+		c.depthMode = mode
+	}
+}
+
+// WithLinksFrom はリンクを収集する要素のセレクタを設定する（空の場合はページ全体から収集する）
+func WithLinksFrom(selector string) Option {
+	return func(c *Crawler) {
+		c.linksFrom = selector
+		if selector != "" {
+			c.selectorStats.Register(linksFromSource, selector)
+		}
+	}
+}
+
+// WithFollowFrames はiframe・frameで埋め込まれたページも取得するかを設定する
+func WithFollowFrames(enabled bool) Option {
+	return func(c *Crawler) {
+		c.followFrames = enabled
+	}
+}
+
+// WithLangFilter は収集する言語を設定する（空の場合はすべての言語）
+func WithLangFilter(lang string) Option {
+	return func(c *Crawler) {
+		c.langFilter = normalizeLang(lang)
+	}
+}
+
+// WithExactVisited は訪問済みのURLをハッシュではなくURL全体で判定するかを設定する
+func WithExactVisited(enabled bool) Option {
+	return func(c *Crawler) {
+		c.exactVisited = enabled
+	}
+}
+
+// WithLocalDir はネットワークの代わりにディスク上に保存したサイトのディレクトリから取得するよう設定する
+// base を指定した場合はディレクトリからの相対パスをそのURLからの相対パスとしてページのURLを決める
+func WithLocalDir(dir, base string) Option {
+	return func(c *Crawler) {
+		if dir == "" {
+			return
+		}
+		root, err := filepath.Abs(dir)
+		if err != nil {
+			root = dir
+		}
+		site := &localSite{root: root}
+		if base != "" {
+			if u, err := url.Parse(base); err == nil {
+				if !strings.HasSuffix(u.Path, "/") {
+					u.Path += "/"
+				}
+				site.base = u
+			}
+		}
+		c.local = site
+	}
+}
+
+// WithNavOrder はナビゲーションの順序を記録する要素のセレクタを設定する（空の場合は記録しない）
+// everyPage が true の場合は開始ページ以外のページのナビゲーションも記録する
+func WithNavOrder(selector string, everyPage bool) Option {
+	return func(c *Crawler) {
+		c.navSelector = selector
+		c.navEveryPage = everyPage
+	}
+}
+
+// WithSoft404 はソフト404と判定するパターンと本文の最大文字数を設定する
+// patterns が空の場合はデフォルトのパターンを使い、maxChars が0以下の場合は判定しない
+func WithSoft404(patterns []*regexp.Regexp, maxChars int) Option {
+	return func(c *Crawler) {
+		if len(patterns) > 0 {
+			c.soft404Patterns = patterns
+		}
+		c.soft404MaxChars = maxChars
+	}
+}
+
+// WithAuthAbortRatio は401・403の割合がこれを超えたらクロールを中止する値を設定する（0以下は中止しない）
+func WithAuthAbortRatio(ratio float64) Option {
+	return func(c *Crawler) {
+		c.authAbortRatio = ratio
+	}
+}
+
+// WithRetries は一時的なネットワークエラーで再試行する回数を設定する（0の場合は再試行しない）
+func WithRetries(n int) Option {
+	return func(c *Crawler) {
+		if n >= 0 {
+			c.retries = n
+		}
+	}
+}
+
+// WithMaxBandwidth はすべてのリクエストを合わせた受信速度の上限（1秒あたりのバイト数）を設定する（0以下は無制限）
+func WithMaxBandwidth(bytesPerSecond int64) Option {
+	return func(c *Crawler) {
+		if bytesPerSecond > 0 {
+			c.bandwidth = &bandwidthLimiter{rate: bytesPerSecond}
+		}
+	}
+}
+
+// WithTrapDetection はクローラートラップと判定するしきい値を設定する（0以下の場合はその判定を行わない）
+// maxRepeats は1つのURLで同じパスの要素が繰り返してよい回数、maxVariants は数字やクエリの値だけが異なるURLが伸び続けてよい回数の上限
+func WithTrapDetection(maxRepeats, maxVariants int) Option {
+	return func(c *Crawler) {
+		c.traps = newTrapDetector(maxRepeats, maxVariants)
+	}
+}
+
+// WithRender はヘッドレスブラウザ（Chrome）で描画したDOMからコンテンツを抽出する
+// JavaScriptで内容を生成するサイト向けで、ブラウザはクロール開始時に一度だけ起動する
+func WithRender(enabled bool) Option {
+	return func(c *Crawler) {
+		c.render = enabled
+	}
+}
+
+// WithRenderWait は --render での描画の待ち方を設定する
+// timeout はネットワークが落ち着くのと waitFor の要素が現れるのを待つ時間の上限、delay はDOMを取得する前に一律に待つ時間
+func WithRenderWait(timeout time.Duration, waitFor string, delay time.Duration) Option {
+	return func(c *Crawler) {
+		if timeout > 0 {
+			c.renderTimeout = timeout
+		}
+		c.waitFor = waitFor
+		if delay > 0 {
+			c.waitDelay = delay
+		}
+	}
+}
+
+// WithVersion はバージョン付きのドキュメント（/en/v2.3/、/latest/ など）で取得するバージョンを絞り込む
+// version を指定した場合はそのバージョンに固定し、latestOnly の場合は最初に取得した開始ページのバージョンに絞り込む
+func WithVersion(version string, latestOnly bool) Option {
+	return func(c *Crawler) {
+		c.latestOnly = latestOnly
+		if version != "" {
+			c.docVersion = version
+			c.versionDecided = true
+		}
+	}
+}
+
+// WithIncludePDFs はリンク先のPDFを dir に保存し、本文のテキストを抽出したページとして収集する（空の場合は収集しない）
+func WithIncludePDFs(dir string) Option {
+	return func(c *Crawler) {
+		c.pdfDir = dir
+		if dir != "" {
+			delete(c.skipExts, ".pdf")
+		}
+	}
+}
+
+// WithSessionFile はCookieを保存・復元するセッションファイルを設定する
+// 開始時にファイルのCookieを読み込み、終了時にCookieを保存する。ファイルにはセッションの秘密情報が含まれる
+func WithSessionFile(path string) Option {
+	return func(c *Crawler) {
+		c.sessionFile = path
+	}
+}
+
+// WithCircuitBreaker は直近 window 件のリクエストのうち5xxやタイムアウトの割合が threshold 以上になったら cooldown の間すべてのリクエストを一時停止する
+// window を0にすると一時停止しない
+func WithCircuitBreaker(window int, threshold float64, cooldown time.Duration) Option {
+	return func(c *Crawler) {
+		if window >= 0 {
+			c.breakerWindow = window
+		}
+		if threshold > 0 {
+			c.breakerThreshold = threshold
+		}
+		if cooldown > 0 {
+			c.breakerCooldown = cooldown
+		}
+	}
+}
+
+// WithNearDuplicates はsimhashのハミング距離が threshold 未満のほぼ同じ内容のページの扱いを設定する（threshold が0の場合は判定しない）
+func WithNearDuplicates(threshold int, action NearDupAction) Option {
+	return func(c *Crawler) {
+		if threshold >= 0 {
+			c.nearDupThreshold = threshold
+		}
+		if action != "" {
+			c.nearDupAction = action
+		}
+	}
+}
+
+// WithPriorities はURLのパターンごとの重みを設定する
+// キューからは深度の浅い順、同じ深度では重みの大きい順、同じ重みでは追加した順に取り出す
+func WithPriorities(rules []PriorityRule) Option {
+	return func(c *Crawler) {
+		c.priorities = rules
+	}
+}
+
+// WithSkipTitles はタイトルがいずれかのパターンに一致するページを収集しないよう設定する
+// follow が true の場合は収集しないページのリンクもたどる
+func WithSkipTitles(patterns []*regexp.Regexp, follow bool) Option {
+	return func(c *Crawler) {
+		c.skipTitlePatterns = patterns
+		c.skipTitleFollow = follow
+	}
+}
+
+// WithPreferOGTitle はページのタイトルに og:title があれば <title> より優先して使うよう設定する
+// タイトルによる除外（WithSkipTitles）は <title> で判定する
+func WithPreferOGTitle(enabled bool) Option {
+	return func(c *Crawler) {
+		c.preferOGTitle = enabled
+	}
+}
+
+// WithScopeRoot はクロール範囲のルートを設定する（root 以下のURLのみをたどる）
+// root が空で discover が true の場合は、開始ページのナビゲーションやパンくずリストからドキュメントのルートを探す
+func WithScopeRoot(root string, discover bool) Option {
+	return func(c *Crawler) {
+		if root != "" && !strings.HasSuffix(root, "/") {
+			root += "/"
+		}
+		c.scopeRoot = root
+		c.discoverRoot = discover
+	}
+}
+
+// WithLinkCheck はクロール後にページのリンクのリンク切れを確認するよう設定する
+// external が true の場合は開始URLと別のホストへのリンクも確認する（concurrency が0以下の場合はデフォルト）
+func WithLinkCheck(enabled, external bool, concurrency int) Option {
+	return func(c *Crawler) {
+		c.checkLinks = enabled
+		c.checkExternal = external
+		if concurrency > 0 {
+			c.checkConcurrency = concurrency
+		}
+	}
+}
+
+// WithContentSelector は本文を抽出する要素のセレクタを設定する
+// 一致する要素がないページや空の場合は、メインコンテンツの要素を推定する
+func WithContentSelector(selector string) Option {
+	return func(c *Crawler) {
+		c.contentSelector = selector
+		if selector != "" {
+			c.selectorStats.Register(contentSelectorSource, selector)
+		}
+	}
+}
+
+// WithAutoContent は本文の要素を推定できないページで、本文らしさの点数が最も高い要素から抽出するかを設定する
+func WithAutoContent(enabled bool) Option {
+	return func(c *Crawler) {
+		c.autoContent = enabled
+	}
+}
+
+// WithExcludeSelectors は本文の抽出の前に取り除く要素のセレクタを設定する
+// defaults が false の場合は DefaultExcludeSelectors の要素を取り除かない
+func WithExcludeSelectors(selectors []string, defaults bool) Option {
+	return func(c *Crawler) {
+		c.excludeSelectors = selectors
+		c.defaultExcludes = defaults
+		for _, selector := range selectors {
+			c.selectorStats.Register(excludeSelectorSource, selector)
+		}
+	}
+}
+
+// WithDownloadImages はクロール後にページの本文が参照する画像を dir に保存し、参照を refPrefix に続けたファイル名に書き換えるよう設定する
+// 開始URLと同じホストの画像のほか、hosts のドメイン（サブドメインも含む）の画像も保存する（dir が空の場合は保存しない）
+func WithDownloadImages(dir, refPrefix string, hosts []string) Option {
+	return func(c *Crawler) {
+		c.imageDir = dir
+		c.imageRefPrefix = refPrefix
+		c.imageHosts = hosts
+	}
+}
+
+// WithBoilerplateStripping はクロール後に割合 ratio 以上のページに共通する maxChars 文字以下のブロックを本文から取り除くよう設定する
+// ratio が0以下の場合は取り除かない（maxChars が0以下の場合はデフォルト）
+func WithBoilerplateStripping(ratio float64, maxChars int) Option {
+	return func(c *Crawler) {
+		c.boilerplateRatio = ratio
+		c.boilerplateMaxChars = DefaultBoilerplateMaxChars
+		if maxChars > 0 {
+			c.boilerplateMaxChars = maxChars
+		}
+	}
+}
+
+// WithLinkStyle は本文のリンクの書き出し方を設定する（空の場合は inline）
+// keepFragments が true の場合は同じページ内の見出しなどへのリンクも残す
+func WithLinkStyle(style LinkStyle, keepFragments bool) Option {
+	return func(c *Crawler) {
+		c.linkStyle = style
+		c.keepFragmentLinks = keepFragments
+	}
+}
+
+// WithTabsMode はタブで切り替える内容の書き出し方を設定する（空の場合は all）
+func WithTabsMode(mode TabsMode) Option {
+	return func(c *Crawler) {
+		c.tabsMode = mode
+	}
+}
+
+// WithCodeDedent はコードブロックのすべての行に共通する行頭の字下げを取り除くよう設定する
+func WithCodeDedent(enabled bool) Option {
+	return func(c *Crawler) {
+		c.dedentCode = enabled
+	}
+}
+
+// WithRetryFailed は前回のクロールで取得に失敗したURLのみを取得し直すよう設定する
+// チェックポイントファイルの前回のページを復元し、取得できたページを追加する
+func WithRetryFailed(failed []FailedURL) Option {
+	return func(c *Crawler) {
+		c.retryFailed = failed
+	}
+}
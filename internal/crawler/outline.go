@@ -0,0 +1,30 @@
+package crawler
+
+// HeadingNode は見出しの階層（目次）の1項目
+type HeadingNode struct {
+	Heading
+	Depth    int            `json:"depth"` // 階層の深さ（最上位は1）
+	Children []*HeadingNode `json:"children,omitempty"`
+}
+
+// BuildHeadingTree は文書の順に並んだ見出しを階層のツリーにする
+// 見出しはその前にあるより小さいレベルの最も近い見出しの子とし、レベルが飛んでいる場合（h1 の直後の h4 など）も
+// 1段深い子とする。前により小さいレベルの見出しがなければ最上位とする
+func BuildHeadingTree(headings []Heading) []*HeadingNode {
+	var roots []*HeadingNode
+	var stack []*HeadingNode // 現在の見出しの祖先（最上位から順）
+	for _, h := range headings {
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		node := &HeadingNode{Heading: h, Depth: len(stack) + 1}
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+	}
+	return roots
+}
@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// outlineShape はツリーを深さに応じて字下げした見出しのテキストの行にする
+func outlineShape(nodes []*HeadingNode, lines *[]string) {
+	for _, node := range nodes {
+		*lines = append(*lines, strings.Repeat("  ", node.Depth-1)+node.Text)
+		outlineShape(node.Children, lines)
+	}
+}
+
+func TestBuildHeadingTree(t *testing.T) {
+	headings := []Heading{
+		{Level: 2, Text: "はじめに"}, // h1 より前の h2 は最上位になる
+		{Level: 1, Text: "Guide"},
+		{Level: 4, Text: "Skipped"}, // h1 の直後の h4 は1段深い子になる
+		{Level: 3, Text: "Sibling"}, // h4 より小さいレベルのため h4 の兄弟になる
+		{Level: 2, Text: "Install"},
+		{Level: 3, Text: "npm"},
+		{Level: 6, Text: "Deep"},
+		{Level: 2, Text: "Usage"},
+		{Level: 1, Text: "API"},
+		{Level: 1, Text: "FAQ"},
+	}
+	var lines []string
+	outlineShape(BuildHeadingTree(headings), &lines)
+
+	want := []string{
+		"はじめに",
+		"Guide",
+		"  Skipped",
+		"  Sibling",
+		"  Install",
+		"    npm",
+		"      Deep",
+		"  Usage",
+		"API",
+		"FAQ",
+	}
+	if got := strings.Join(lines, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("ツリーの形が異なります\ngot:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildHeadingTreeEmpty(t *testing.T) {
+	if roots := BuildHeadingTree(nil); roots != nil {
+		t.Errorf("見出しがない場合は nil を返すべきです: %v", roots)
+	}
+}
+
+func TestPageOutlineJSON(t *testing.T) {
+	page := Page{Outline: BuildHeadingTree([]Heading{
+		{Level: 1, Text: "Guide", Anchor: "guide"},
+		{Level: 3, Text: "Step", Anchor: "step"},
+	})}
+	data, err := json.Marshal(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"outline":[{"level":1,"text":"Guide","anchor":"guide","depth":1,"children":[{"level":3,"text":"Step","anchor":"step","depth":2}]}]`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("JSONに目次が含まれていません\ngot: %s\nwant: %s", data, want)
+	}
+}
@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefaultMaxPagination は rel="next" で連続してたどるページ数の上限のデフォルト値
+const DefaultMaxPagination = 50
+
+// extractNextLink は<link rel="next">または<a rel="next">から次のページの絶対URLを取得する
+func extractNextLink(doc *goquery.Document, pageURL string) (string, bool) {
+	var next string
+	doc.Find("link[rel], a[rel]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if !hasRelToken(s.AttrOr("rel", ""), "next") {
+			return true
+		}
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if href == "" {
+			return true
+		}
+		resolved, err := resolveURL(pageURL, href)
+		if err != nil || stripFragment(resolved) == stripFragment(pageURL) {
+			return true
+		}
+		next = stripFragment(resolved)
+		return false
+	})
+	return next, next != ""
+}
+
+// hasRelToken はrel属性に指定した値が含まれるかを判定する
+func hasRelToken(rel, token string) bool {
+	for _, t := range strings.Fields(rel) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/yugo-ibuki/docrawl/internal/pathutil"
+	"github.com/yugo-ibuki/docrawl/internal/pdftext"
+)
+
+// pdfFetch はPDFのレスポンスの取得結果
+type pdfFetch struct {
+	body       []byte
+	statusCode int
+	fetchedAt  time.Time
+	duration   time.Duration
+	downloaded int64
+	truncated  bool // レスポンスが上限を超えて切り詰められたか
+}
+
+// acceptsPDF はメディアタイプがPDFで、PDFも収集する設定かどうかを判定する
+func (c *Crawler) acceptsPDF(mediaType string) bool {
+	return c.pdfDir != "" && mediaType == "application/pdf"
+}
+
+// isPDFResponse はレスポンスをPDFとして収集するかどうかを判定する
+func (c *Crawler) isPDFResponse(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && c.acceptsPDF(mediaType)
+}
+
+// collectPDF はPDFを保存し、本文のテキストを抽出したページとして追加する
+// テキストを抽出できなかった場合は保存先を示す添付ファイルとして追加する
+// 上限を超えて切り詰められたPDFは壊れたファイルになるため、保存も抽出もせず元のURLを示す添付ファイルとして追加する
+func (c *Crawler) collectPDF(item crawlItem, pageURL, requestedURL string, f pdfFetch, pages *[]Page, mu *sync.Mutex) {
+	if c.dryRun {
+		return
+	}
+
+	title, content, assetPath := pdfFileName(pageURL), "", ""
+	if f.truncated {
+		c.logger.Warn("PDFが上限を超えたため保存せず添付ファイルとして記録します", "url", pageURL, "max_bytes", c.maxBodySize)
+		content = fmt.Sprintf("（PDFが --max-body-size の上限を超えたため保存していません。元のファイル: %s）", pageURL)
+	} else {
+		assetPath = c.savePDF(pageURL, f.body)
+		title, content = c.pdfContent(pageURL, assetPath, title, f.body)
+	}
+
+	c.appendPage(pages, mu, Page{
+		URL:          pageURL,
+		RequestedURL: requestedURL,
+		Title:        title,
+		Content:      content,
+		Depth:        item.depth,
+		Seed:         item.seed,
+		StatusCode:   f.statusCode,
+		FetchedAt:    f.fetchedAt,
+		DurationMS:   f.duration.Milliseconds(),
+		Bytes:        f.downloaded,
+		FromPDF:      true,
+		AssetPath:    assetPath,
+	})
+}
+
+// pdfContent はPDFの本文のテキストとタイトル（文書情報にない場合は title）を返す
+// テキストを抽出できなかった場合は保存先（保存に失敗した場合は元のURL）を示す本文を返す
+func (c *Crawler) pdfContent(pageURL, assetPath, title string, body []byte) (string, string) {
+	doc, err := pdftext.Extract(body)
+	if err != nil {
+		c.logger.Warn("PDFの本文を抽出できないため添付ファイルとして記録します", "url", pageURL, "error", err)
+		if assetPath == "" {
+			return title, fmt.Sprintf("（PDFの本文を抽出できませんでした。元のファイル: %s）", pageURL)
+		}
+		return title, fmt.Sprintf("（PDFの本文を抽出できませんでした。添付ファイル: %s）", assetPath)
+	}
+	if doc.Title != "" {
+		title = doc.Title
+	}
+	return title, doc.Text
+}
+
+// savePDF はPDFをURLのパスに対応するファイルとして保存し、保存先のパスを返す（失敗した場合は空）
+func (c *Crawler) savePDF(pageURL string, body []byte) string {
+	dest := filepath.Join(c.pdfDir, filepath.FromSlash(pathutil.FromURL(pageURL)))
+	if filepath.Ext(dest) == "" {
+		dest += ".pdf"
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		c.logger.Warn("PDFの保存に失敗しました", "url", pageURL, "error", err)
+		return ""
+	}
+	if err := os.WriteFile(dest, body, 0o644); err != nil {
+		c.logger.Warn("PDFの保存に失敗しました", "url", pageURL, "error", err)
+		return ""
+	}
+	return dest
+}
+
+// pdfFileName はPDFのURLのファイル名を返す（タイトルがないPDFのタイトルに使う）
+func pdfFileName(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return pageURL
+	}
+	name := path.Base(u.Path)
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	if name == "/" || name == "." {
+		return pageURL
+	}
+	return name
+}
@@ -0,0 +1,136 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPDF はページの内容のストリームと文書情報のみの最小限のPDFを作る（info が空の場合は文書情報を含めない）
+func testPDF(info, content string) []byte {
+	var sb strings.Builder
+	sb.WriteString("%PDF-1.4\n")
+	if info != "" {
+		fmt.Fprintf(&sb, "1 0 obj\n%s\nendobj\n", info)
+	}
+	fmt.Fprintf(&sb, "2 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n%%%%EOF\n", len(content), content)
+	return []byte(sb.String())
+}
+
+// pdfSite はHTMLのページとPDFを返すテスト用のサーバーを起動する
+func pdfSite(t *testing.T, pdfs map[string][]byte, links ...string) *testSite {
+	t.Helper()
+	return newTestSite(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/docs/" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, htmlPage("Docs", "資料の一覧", links...))
+			return
+		}
+		body, ok := pdfs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(body)
+	})
+}
+
+func TestCollectPDF(t *testing.T) {
+	pdfs := map[string][]byte{
+		"/docs/spec.pdf":   testPDF("<< /Title <FEFF0053007000650063> >>", "BT (API specification) Tj ET"),
+		"/docs/manual.pdf": testPDF("", "BT (Operator manual) Tj ET"),
+		"/docs/scan.pdf":   testPDF("", "q 100 0 0 100 0 0 cm /Im1 Do Q"),
+	}
+	site := pdfSite(t, pdfs, "/docs/spec.pdf", "/docs/manual.pdf", "/docs/scan.pdf")
+	dir := t.TempDir()
+
+	c := New(site.URL+"/docs/", 1, 10, 0, 0, WithLogger(discardLogger()), WithIncludePDFs(dir))
+	result, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 本文を抽出できないPDFもクロールのエラーにはしない
+	if len(result.Errors) != 0 {
+		t.Errorf("クロールのエラー = %+v", result.Errors)
+	}
+
+	byPath := make(map[string]Page)
+	for _, page := range result.Pages {
+		byPath[strings.TrimPrefix(page.URL, site.URL)] = page
+	}
+	for _, tt := range []struct {
+		path    string
+		title   string
+		content string
+	}{
+		{"/docs/spec.pdf", "Spec", "API specification"},       // 文書情報のタイトル
+		{"/docs/manual.pdf", "manual.pdf", "Operator manual"}, // タイトルがなければファイル名
+		{"/docs/scan.pdf", "scan.pdf", "PDFの本文を抽出できませんでした"},   // 抽出できなければ添付ファイル
+	} {
+		page, ok := byPath[tt.path]
+		if !ok {
+			t.Errorf("%s が収集されていません: %v", tt.path, pagePaths(site, result.Pages))
+			continue
+		}
+		if !page.FromPDF {
+			t.Errorf("%s の FromPDF が設定されていません", tt.path)
+		}
+		if page.Title != tt.title {
+			t.Errorf("%s のタイトル = %q, want %q", tt.path, page.Title, tt.title)
+		}
+		if !strings.Contains(page.Content, tt.content) {
+			t.Errorf("%s の本文に %q が含まれていません: %q", tt.path, tt.content, page.Content)
+		}
+		if !strings.HasPrefix(page.AssetPath, dir+string(filepath.Separator)) {
+			t.Errorf("%s の保存先 = %q, want %s の中", tt.path, page.AssetPath, dir)
+			continue
+		}
+		saved, err := os.ReadFile(page.AssetPath)
+		if err != nil {
+			t.Errorf("%s が保存されていません: %v", tt.path, err)
+		} else if !bytes.Equal(saved, pdfs[tt.path]) {
+			t.Errorf("%s の保存した内容が元のPDFと異なります", tt.path)
+		}
+	}
+	if scan := byPath["/docs/scan.pdf"]; !strings.Contains(scan.Content, scan.AssetPath) {
+		t.Errorf("添付ファイルの本文に保存先が含まれていません: %q", scan.Content)
+	}
+}
+
+func TestCollectPDFTruncated(t *testing.T) {
+	large := testPDF("<< /Title (Large) >>", "BT ("+strings.Repeat("x", 4096)+") Tj ET")
+	site := pdfSite(t, map[string][]byte{"/docs/large.pdf": large}, "/docs/large.pdf")
+	dir := t.TempDir()
+
+	c := New(site.URL+"/docs/", 1, 10, 0, 0, WithLogger(discardLogger()), WithIncludePDFs(dir), WithMaxBodySize(1024, false))
+	result, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page *Page
+	for i := range result.Pages {
+		if strings.HasSuffix(result.Pages[i].URL, "/docs/large.pdf") {
+			page = &result.Pages[i]
+		}
+	}
+	if page == nil {
+		t.Fatalf("上限を超えたPDFが添付ファイルとして記録されていません: %v", pagePaths(site, result.Pages))
+	}
+	// 切り詰めた内容は壊れたPDFになるため保存せず、元のURLを示す
+	if page.AssetPath != "" || !page.FromPDF || page.Title != "large.pdf" || !strings.Contains(page.Content, page.URL) {
+		t.Errorf("上限を超えたPDFのページ = %+v", *page)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("切り詰めたPDFが保存されています: %v", entries)
+	}
+}
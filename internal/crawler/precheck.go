@@ -0,0 +1,55 @@
+package crawler
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// precheck はGETの前にHEADリクエストを送り、本文を取得すべきかどうかを判定する
+// HEADに対応していないサーバーやContent-Typeを返さないサーバーの場合はGETで判定するため true を返す
+func (c *Crawler) precheck(ctx context.Context, url string) (bool, error) {
+	req, err := c.newRequestWithBody(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return false, err
+	}
+	c.stats.headRequests.Add(1)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		// HEADの失敗はGETで改めて判定する
+		return true, nil
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		return true, nil
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		return true, nil
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && !isHTMLMediaType(mediaType) && !c.acceptsPDF(mediaType) {
+		c.logger.Info("HTMLではないためスキップします", "url", url, "content_type", mediaType)
+		c.stats.skip(SkipNotHTML)
+		return false, nil
+	}
+
+	if c.maxBodySize > 0 {
+		if length, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil && length > c.maxBodySize {
+			c.logger.Warn("レスポンスが上限を超えるためスキップします", "url", url, "bytes", length, "max_bytes", c.maxBodySize)
+			c.stats.skip(SkipOversized)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isHTMLMediaType はメディアタイプがHTMLかどうかを判定する
+func isHTMLMediaType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
@@ -0,0 +1,40 @@
+package crawler
+
+import "regexp"
+
+// PriorityRule はURLがパターンに一致した場合にクロールの優先度に加える重み
+type PriorityRule struct {
+	Pattern *regexp.Regexp
+	Weight  int
+}
+
+// urlWeight はURLに一致する優先度の規則のうち最大の重みを返す（一致しない場合は0）
+func (c *Crawler) urlWeight(rawURL string) int {
+	weight, matched := 0, false
+	for _, rule := range c.priorities {
+		if rule.Pattern.MatchString(rawURL) && (!matched || rule.Weight > weight) {
+			weight, matched = rule.Weight, true
+		}
+	}
+	return weight
+}
+
+// dequeuedAfter は a が b よりも後に取り出されるべきかを判定する（深度の浅い順、重みの大きい順）
+func dequeuedAfter(a, b crawlItem) bool {
+	if a.depth != b.depth {
+		return a.depth > b.depth
+	}
+	return a.weight < b.weight
+}
+
+// insertByPriority はキューの順序を保つ位置にURLを追加する（呼び出し側で c.mu を保持する）
+// 同じ深度・重みのURLは追加した順に取り出すため、末尾から挿入位置を探す
+func (c *Crawler) insertByPriority(item crawlItem) {
+	i := len(c.queue)
+	for i > 0 && dequeuedAfter(c.queue[i-1], item) {
+		i--
+	}
+	c.queue = append(c.queue, crawlItem{})
+	copy(c.queue[i+1:], c.queue[i:])
+	c.queue[i] = item
+}
@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestInsertByPriority(t *testing.T) {
+	c := &Crawler{}
+	for _, item := range []crawlItem{
+		{url: "d1-w0-a", depth: 1},
+		{url: "d2-w9", depth: 2, weight: 9},
+		{url: "d1-w5", depth: 1, weight: 5},
+		{url: "d1-w0-b", depth: 1},
+		{url: "d0", depth: 0},
+		{url: "d1-w-3", depth: 1, weight: -3},
+		{url: "d1-w5-b", depth: 1, weight: 5},
+		{url: "d2-w0", depth: 2},
+	} {
+		c.insertByPriority(item)
+	}
+
+	// 深度の浅い順、同じ深度では重みの大きい順、同じ深度・重みでは追加した順
+	var got []string
+	for _, item := range c.queue {
+		got = append(got, item.url)
+	}
+	want := "d0,d1-w5,d1-w5-b,d1-w0-a,d1-w0-b,d1-w-3,d2-w9,d2-w0"
+	if strings.Join(got, ",") != want {
+		t.Errorf("キューの順序 = %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestURLWeight(t *testing.T) {
+	c := &Crawler{priorities: []PriorityRule{
+		{Pattern: regexp.MustCompile(`/docs/`), Weight: 5},
+		{Pattern: regexp.MustCompile(`/docs/api/`), Weight: 10},
+		{Pattern: regexp.MustCompile(`/blog/`), Weight: -5},
+	}}
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{url: "https://example.com/docs/intro", want: 5},
+		{url: "https://example.com/docs/api/users", want: 10},
+		{url: "https://example.com/blog/post", want: -5},
+		{url: "https://example.com/about", want: 0},
+	}
+	for _, tt := range tests {
+		if got := c.urlWeight(tt.url); got != tt.want {
+			t.Errorf("urlWeight(%q) = %d, want %d", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestCrawlPriorities(t *testing.T) {
+	site := newHTMLSite(t, map[string]string{
+		"/":           htmlPage("Top", "top page", "/blog/news", "/about", "/docs/intro", "/api/users"),
+		"/blog/news":  htmlPage("News", "news page", "/docs/deep"),
+		"/about":      htmlPage("About", "about page"),
+		"/docs/intro": htmlPage("Intro", "intro page"),
+		"/api/users":  htmlPage("Users", "users page"),
+		"/docs/deep":  htmlPage("Deep", "deep page"),
+	})
+
+	c := New(site.URL+"/", 3, 10, 0, 0, WithLogger(discardLogger()), WithPriorities([]PriorityRule{
+		{Pattern: regexp.MustCompile(`/docs/`), Weight: 10},
+		{Pattern: regexp.MustCompile(`/api/`), Weight: 5},
+		{Pattern: regexp.MustCompile(`/blog/`), Weight: -5},
+	}))
+	result, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	// 重みが大きくても深いページは浅いページの後に取得する
+	want := "/,/docs/intro,/api/users,/about,/blog/news,/docs/deep"
+	if got := strings.Join(pagePaths(site, result.Pages), ","); got != want {
+		t.Errorf("取得した順序 = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostLimiter はホストごとにリクエストの間隔を制限するトークンバケット
+// 各ホストのバケットの容量は1で、interval が返す間隔ごとに1つのトークンが補充される
+type hostLimiter struct {
+	interval func() time.Duration
+	mu       sync.Mutex
+	next     map[string]time.Time // ホストごとに次のリクエストを送信できる時刻
+}
+
+// newHostLimiter は新しいhostLimiterを作成する
+func newHostLimiter(interval func() time.Duration) *hostLimiter {
+	return &hostLimiter{
+		interval: interval,
+		next:     make(map[string]time.Time),
+	}
+}
+
+// Wait はホストへのリクエストを送信できるまで待つ
+// 予約した送信時刻はすぐに確定するため、複数のゴルーチンから呼び出しても同じホストへの間隔は interval 以上になる
+func (l *hostLimiter) Wait(ctx context.Context, host string) error {
+	interval := l.interval()
+	if interval <= 0 {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// limiterKey はレート制限の単位となるホスト名を返す
+func limiterKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
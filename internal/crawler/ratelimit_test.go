@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// timerSlack は間隔の確認で許容するタイマーの遅れ
+const timerSlack = 15 * time.Millisecond
+
+func TestHostLimiterSpacing(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	l := newHostLimiter(func() time.Duration { return interval })
+
+	// 同じホストへ同時に待っても、送信できる時刻は interval ずつずれる
+	var mu sync.Mutex
+	var times []time.Time
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Wait(context.Background(), "docs.example.com"); err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < interval-timerSlack {
+			t.Errorf("%d 回目と %d 回目の間隔 = %v, want >= %v", i, i+1, gap, interval)
+		}
+	}
+}
+
+func TestHostLimiterSeparateHosts(t *testing.T) {
+	l := newHostLimiter(func() time.Duration { return time.Hour })
+
+	// 別のホストへの最初のリクエストは待たない
+	start := time.Now()
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		if err := l.Wait(context.Background(), host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("別のホストへのリクエストで %v 待ちました", elapsed)
+	}
+}
+
+func TestHostLimiterCanceled(t *testing.T) {
+	l := newHostLimiter(func() time.Duration { return time.Hour })
+	if err := l.Wait(context.Background(), "docs.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 待機中にキャンセルされた場合はすぐに戻る
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.Wait(ctx, "docs.example.com"); err != context.DeadlineExceeded {
+		t.Errorf("Wait = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("キャンセル後に戻るまで %v かかりました", elapsed)
+	}
+}
+
+func TestCrawlRequestSpacing(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	site, requestTimes := chainSite(t, 6)
+
+	c := New(site.URL+"/p/0", 10, 10, interval.Seconds(), 0, WithLogger(discardLogger()))
+	if _, err := c.Crawl(context.Background()); err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	// サーバーが受け付けたリクエストの間隔は --delay 以上になる
+	times := requestTimes()
+	if len(times) != 6 {
+		t.Fatalf("リクエスト数 = %d, want 6", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < interval-timerSlack {
+			t.Errorf("%d 回目と %d 回目の間隔 = %v, want >= %v", i, i+1, gap, interval)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package crawler
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// maxRefreshDelay はリダイレクトとして追跡するmeta refreshの最大待機秒数
+	maxRefreshDelay = 5
+	// maxRefreshHops はmeta refreshによる転送を連続して追跡する最大回数
+	maxRefreshHops = 5
+	// stubTextLength はこれより短い本文のページを内容のない転送用ページとみなす文字数
+	stubTextLength = 200
+)
+
+// extractMetaRefresh は<meta http-equiv="refresh">から転送先の絶対URLを取得する
+// 待機時間が長いもの（定期的な再読み込み）や自身への転送は無視する
+func extractMetaRefresh(doc *goquery.Document, pageURL string) (string, bool) {
+	var target string
+	doc.Find("meta[http-equiv]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if !strings.EqualFold(strings.TrimSpace(s.AttrOr("http-equiv", "")), "refresh") {
+			return true
+		}
+		delay, ref, ok := parseRefreshContent(s.AttrOr("content", ""))
+		if !ok || delay > maxRefreshDelay {
+			return true
+		}
+		resolved, err := resolveURL(pageURL, ref)
+		if err != nil || stripFragment(resolved) == stripFragment(pageURL) {
+			return true
+		}
+		target = stripFragment(resolved)
+		return false
+	})
+	return target, target != ""
+}
+
+// parseRefreshContent はmeta refreshのcontent属性（"0; url=/new/"）を解析する
+func parseRefreshContent(content string) (int, string, bool) {
+	delayPart, rest, found := strings.Cut(content, ";")
+	if !found {
+		delayPart, rest, found = strings.Cut(content, ",")
+	}
+	if !found {
+		return 0, "", false
+	}
+
+	delay, err := strconv.ParseFloat(strings.TrimSpace(delayPart), 64)
+	if err != nil || delay < 0 {
+		return 0, "", false
+	}
+
+	rest = strings.TrimSpace(rest)
+	if len(rest) >= 4 && strings.EqualFold(rest[:3], "url") {
+		if eq := strings.Index(rest, "="); eq != -1 {
+			rest = rest[eq+1:]
+		}
+	}
+	ref := strings.Trim(strings.TrimSpace(rest), `"'`)
+	if ref == "" {
+		return 0, "", false
+	}
+	return int(delay), ref, true
+}
+
+// isStubPage は本文がほとんどない転送用ページかを返す
+func isStubPage(doc *goquery.Document) bool {
+	text := strings.Join(strings.Fields(doc.Find("body").Text()), " ")
+	return len([]rune(text)) < stubTextLength
+}
+
+// sameSite は2つのURLがスキームとホストを同じくするかを返す
+func sameSite(pageURL, target string) bool {
+	pageBase, err := parseBaseURL(pageURL)
+	if err != nil {
+		return false
+	}
+	targetBase, err := parseBaseURL(target)
+	return err == nil && pageBase == targetBase
+}
+
+// sameHost は2つのURLのホストが同じかを返す（http から https への転送などスキームの違いは許容する）
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	return err == nil && strings.EqualFold(ua.Host, ub.Host)
+}
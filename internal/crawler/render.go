@@ -0,0 +1,303 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultRenderTimeout はヘッドレスブラウザでページの描画を待つ時間の上限のデフォルト値
+const DefaultRenderTimeout = 30 * time.Second
+
+// browserStartTimeout はヘッドレスブラウザの起動を待つ時間の上限
+const browserStartTimeout = 20 * time.Second
+
+// ErrChromeNotFound はヘッドレスブラウザとして使うChromeが見つからないことを示す
+var ErrChromeNotFound = errors.New("ChromeまたはChromiumが見つかりません（--render を使うにはインストールするか、CHROME_PATH で実行ファイルを指定してください）")
+
+// chromeCandidates はChromeの実行ファイルとして探す名前とパス
+var chromeCandidates = []string{
+	"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome",
+	"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+	"/Applications/Chromium.app/Contents/MacOS/Chromium",
+}
+
+// findChrome はChromeの実行ファイルを探す（CHROME_PATH が設定されていればそれを使う）
+func findChrome() (string, error) {
+	if path := os.Getenv("CHROME_PATH"); path != "" {
+		return path, nil
+	}
+	for _, name := range chromeCandidates {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", ErrChromeNotFound
+}
+
+// browser はクロール全体で共有するヘッドレスブラウザ
+// ページごとにタブを開き、chromedpで描画後のDOMを取得する
+type browser struct {
+	ctx         context.Context // ブラウザのコンテキスト（タブはこのコンテキストから作成する）
+	cancel      context.CancelFunc
+	cancelAlloc context.CancelFunc // ブラウザのプロセスを終了して一時的なプロファイルを削除する
+}
+
+// launchBrowser はヘッドレスブラウザを起動する
+func launchBrowser(ctx context.Context) (*browser, error) {
+	path, err := findChrome()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.ExecPath(path),
+		chromedp.Flag("headless", "new"),
+		chromedp.DisableGPU,
+	)
+	// rootで実行する場合はサンドボックスを無効にしないと起動できない
+	if os.Geteuid() == 0 {
+		opts = append(opts, chromedp.NoSandbox)
+	}
+	// ブラウザはクロールの終了時に close で終了するため、呼び出し元のコンテキストから切り離す
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.WithoutCancel(ctx), opts...)
+	browserCtx, cancel := chromedp.NewContext(allocCtx)
+	b := &browser{ctx: browserCtx, cancel: cancel, cancelAlloc: cancelAlloc}
+
+	// 最初の Run でブラウザのプロセスを起動する
+	started := make(chan error, 1)
+	go func() { started <- chromedp.Run(browserCtx) }()
+	timer := time.NewTimer(browserStartTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-started:
+		if err != nil {
+			b.close()
+			return nil, fmt.Errorf("ブラウザの起動に失敗しました: %w", err)
+		}
+		return b, nil
+	case <-timer.C:
+		b.close()
+		return nil, errors.New("ブラウザの起動がタイムアウトしました")
+	case <-ctx.Done():
+		b.close()
+		return nil, ctx.Err()
+	}
+}
+
+// renderRequest は1ページを描画するための設定
+type renderRequest struct {
+	url       string
+	userAgent string
+	headers   map[string]string
+	cookies   []*http.Cookie
+	timeout   time.Duration // ネットワークが落ち着くのと waitFor の要素が現れるのを待つ時間の上限
+	waitFor   string        // 現れるまで待つ要素のCSSセレクタ（空の場合は待たない）
+	waitDelay time.Duration // DOMを取得する前に一律に待つ時間
+}
+
+// lifecycleEvent はタブで発生したページのライフサイクルのイベント
+type lifecycleEvent struct {
+	frameID  cdp.FrameID
+	loaderID cdp.LoaderID
+	name     string
+}
+
+// render は新しいタブでページを開き、ネットワークが落ち着くかタイムアウトした時点のDOMを返す
+// waitFor を指定した場合はその要素が現れるまで待ち、タイムアウトまでに現れなかった場合は found が false になる
+func (b *browser) render(ctx context.Context, r renderRequest) (html string, found bool, err error) {
+	tabCtx, closeTab := chromedp.NewContext(b.ctx)
+	defer closeTab()
+	// 待機後のDOMの取得にも時間がかかるため、全体の上限には余裕を持たせる
+	runCtx, cancel := context.WithTimeout(tabCtx, 2*r.timeout+r.waitDelay)
+	defer cancel()
+	// クロールが中断された場合は描画も中断する
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	defer func() {
+		if ctx.Err() != nil {
+			html, found, err = "", false, ctx.Err()
+		}
+	}()
+
+	// 読み手が追いつかない場合は読み捨てる（描画の待機はタイムアウトで打ち切られる）
+	events := make(chan lifecycleEvent, 256)
+	chromedp.ListenTarget(tabCtx, func(ev any) {
+		if e, ok := ev.(*page.EventLifecycleEvent); ok {
+			select {
+			case events <- lifecycleEvent{frameID: e.FrameID, loaderID: e.LoaderID, name: e.Name}:
+			default:
+			}
+		}
+	})
+
+	cookies := make([]*network.CookieParam, 0, len(r.cookies))
+	for _, cookie := range r.cookies {
+		cookies = append(cookies, &network.CookieParam{Name: cookie.Name, Value: cookie.Value, URL: r.url})
+	}
+	headers := make(network.Headers, len(r.headers))
+	for name, value := range r.headers {
+		headers[name] = value
+	}
+
+	var frameID cdp.FrameID
+	var loaderID cdp.LoaderID
+	err = chromedp.Run(runCtx,
+		page.Enable(),
+		page.SetLifecycleEventsEnabled(true),
+		network.Enable(),
+		emulation.SetUserAgentOverride(r.userAgent),
+		network.SetExtraHTTPHeaders(headers),
+		network.SetCookies(cookies),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var errorText string
+			var err error
+			frameID, loaderID, errorText, _, err = page.Navigate(r.url).Do(ctx)
+			if err != nil {
+				return err
+			}
+			if errorText != "" {
+				return fmt.Errorf("ページを開けませんでした: %s", errorText)
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return "", false, err
+	}
+
+	// 開いたページのネットワークが落ち着くのを待つ（タイムアウトした場合はその時点のDOMを使う）
+	deadline := time.Now().Add(r.timeout)
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+wait:
+	for {
+		select {
+		case e := <-events:
+			if e.frameID == frameID && e.loaderID == loaderID && e.name == "networkIdle" {
+				break wait
+			}
+		case <-timer.C:
+			break wait
+		case <-runCtx.Done():
+			return "", false, runCtx.Err()
+		}
+	}
+
+	// 遅れて描画される要素が現れるのを待つ
+	found = true
+	if r.waitFor != "" {
+		waitCtx, cancelWait := context.WithDeadline(runCtx, deadline)
+		err := chromedp.Run(waitCtx, chromedp.WaitReady(r.waitFor, chromedp.ByQuery))
+		cancelWait()
+		switch {
+		case err == nil:
+		case errors.Is(err, context.DeadlineExceeded) && runCtx.Err() == nil:
+			found = false
+		default:
+			return "", false, err
+		}
+	}
+	if r.waitDelay > 0 {
+		if err := sleepContext(runCtx, r.waitDelay); err != nil {
+			return "", false, err
+		}
+	}
+
+	if err := chromedp.Run(runCtx, chromedp.Evaluate("document.documentElement.outerHTML", &html)); err != nil {
+		return "", false, fmt.Errorf("DOMの取得に失敗しました: %w", err)
+	}
+	return html, found, nil
+}
+
+// close はブラウザを終了して一時的なプロファイルを削除する
+func (b *browser) close() {
+	ctx, cancel := context.WithTimeout(b.ctx, 5*time.Second)
+	defer cancel()
+	// 正常に終了できなかった場合も cancelAlloc でプロセスを強制終了する
+	chromedp.Cancel(ctx)
+	b.cancel()
+	b.cancelAlloc()
+}
+
+// sleepContext は d だけ待つ（ctx がキャンセルされた場合は中断する）
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// shouldRender はレスポンスをヘッドレスブラウザで描画し直すかどうかを判定する
+func (c *Crawler) shouldRender(statusCode int, contentType string) bool {
+	if c.browser == nil || statusCode != http.StatusOK {
+		return false
+	}
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err != nil || isHTMLMediaType(mediaType)
+}
+
+// renderPage はヘッドレスブラウザでページを描画し、描画後のHTMLを返す
+// ユーザー指定のヘッダーとCookieはブラウザのリクエストにも設定する
+// ブラウザは外部のホストの画像やスクリプトにも同じヘッダーを送るため、Authorizationは設定しない
+// waitFor の要素が現れなかった場合は found が false になる
+// ブラウザもページを取得し直すため、HTTPでの取得と同じくリクエストの間隔を空けてから開く
+func (c *Crawler) renderPage(ctx context.Context, pageURL string) (body []byte, found bool, err error) {
+	if err := c.wait(ctx, pageURL); err != nil {
+		return nil, false, err
+	}
+	req, err := c.newRequest(ctx, pageURL)
+	if err != nil {
+		return nil, false, withKind(ErrorParse, err)
+	}
+	headers := make(map[string]string, len(req.Header))
+	for name, values := range req.Header {
+		// Accept-Encodingはブラウザに任せる
+		if name == "User-Agent" || name == "Accept-Encoding" || name == "Authorization" {
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	var cookies []*http.Cookie
+	if u, err := url.Parse(pageURL); err == nil && c.jar != nil {
+		cookies = c.jar.Cookies(u)
+	}
+
+	html, found, err := c.browser.render(ctx, renderRequest{
+		url:       pageURL,
+		userAgent: c.userAgent,
+		headers:   headers,
+		cookies:   cookies,
+		timeout:   c.renderTimeout,
+		waitFor:   c.waitFor,
+		waitDelay: c.waitDelay,
+	})
+	if err != nil {
+		return nil, false, withKind(ErrorRender, fmt.Errorf("ページの描画に失敗しました: %w", err))
+	}
+	if !found {
+		c.logger.Warn("描画の待機中に要素が現れませんでした", "url", pageURL, "selector", c.waitFor, "timeout", c.renderTimeout)
+		c.stats.waitForMissing.Add(1)
+	}
+	return []byte(html), found, nil
+}
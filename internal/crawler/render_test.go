@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderJavaScriptPage(t *testing.T) {
+	if _, err := findChrome(); err != nil {
+		t.Skip(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>App</title></head><body><main id="app"></main>
+			<script>
+			setTimeout(function () {
+				document.getElementById("app").innerHTML = '<h1>App</h1><p class="late">JavaScriptで描画した本文</p>';
+			}, 200);
+			</script></body></html>`))
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/", 0, 10, 0, 0,
+		WithLogger(discardLogger()),
+		WithRender(true),
+		WithRenderWait(5*time.Second, ".late", 0),
+	)
+	result, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Pages) != 1 {
+		t.Fatalf("取得したページ = %d 件, want 1", len(result.Pages))
+	}
+	page := result.Pages[0]
+	if !strings.Contains(page.Content, "JavaScriptで描画した本文") {
+		t.Errorf("描画後の本文が抽出されていません:\n%s", page.Content)
+	}
+	if page.WaitForMissed {
+		t.Error("--wait-for の要素が現れなかったとされました")
+	}
+}
+
+func TestShouldRender(t *testing.T) {
+	c := &Crawler{browser: &browser{}}
+	tests := []struct {
+		status      int
+		contentType string
+		want        bool
+	}{
+		{http.StatusOK, "text/html; charset=utf-8", true},
+		{http.StatusOK, "application/xhtml+xml", true},
+		{http.StatusOK, "", true},
+		{http.StatusOK, "application/pdf", false},
+		{http.StatusNotModified, "text/html", false},
+		{http.StatusNotFound, "text/html", false},
+	}
+	for _, tt := range tests {
+		if got := c.shouldRender(tt.status, tt.contentType); got != tt.want {
+			t.Errorf("shouldRender(%d, %q) = %v, want %v", tt.status, tt.contentType, got, tt.want)
+		}
+	}
+	if (&Crawler{}).shouldRender(http.StatusOK, "text/html") {
+		t.Error("ブラウザがない場合に描画するとされました")
+	}
+}
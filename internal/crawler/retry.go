@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// DefaultRetries は一時的なネットワークエラーで再試行する回数のデフォルト値
+const DefaultRetries = 2
+
+// retryBackoff は最初の再試行までの待機時間（再試行のたびに2倍にする）
+const retryBackoff = time.Second
+
+// attemptsError は再試行しても失敗したエラーとその試行回数
+type attemptsError struct {
+	err      error
+	attempts int
+}
+
+func (e *attemptsError) Error() string { return e.err.Error() }
+func (e *attemptsError) Unwrap() error { return e.err }
+
+// isTransient はリクエストのエラーが再試行で解決しうる一時的なものかを判定する
+// タイムアウト、接続の拒否・切断、途中で切れたレスポンスは一時的、証明書のエラーや存在しないホストは恒久的とみなす
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// 証明書のエラーは再試行しても変わらない
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &certErr) || errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return false
+	}
+
+	// 存在しないホスト (NXDOMAIN) 以外のDNSエラーは一時的な障害とみなす
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// doWithRetry はリクエストを送信し、一時的なネットワークエラーの場合は待機時間を伸ばしながら再試行する
+// 成功した試行の開始時刻を返し、失敗した場合は試行回数を付けたエラーを返す
+func (c *Crawler) doWithRetry(ctx context.Context, req *http.Request) (*http.Response, time.Time, error) {
+	backoff := c.retryBackoff
+	for attempt := 1; ; attempt++ {
+		// サーバーの応答が悪化している間は一時停止する
+		probe, err := c.breaker.wait(ctx)
+		if err != nil {
+			return nil, time.Now(), err
+		}
+		start := time.Now()
+		resp, err := c.client.Do(req.Clone(ctx))
+		if err == nil {
+			c.breaker.record(probe, resp.StatusCode >= http.StatusInternalServerError)
+			return resp, start, nil
+		}
+		if ctx.Err() != nil {
+			c.breaker.release(probe)
+		} else {
+			c.breaker.record(probe, isTransient(err))
+		}
+		c.delayPolicy.Observe(start, 0, err)
+		err = c.wrapProxyError(err)
+
+		if attempt > c.retries || !isTransient(err) || ctx.Err() != nil {
+			if attempt > 1 {
+				err = &attemptsError{err: err, attempts: attempt}
+			}
+			return nil, start, err
+		}
+
+		c.logger.Warn("一時的なエラーのため再試行します", "url", req.URL.String(), "attempt", attempt, "backoff", backoff, "error", err)
+		c.stats.retries.Add(1)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, start, ctx.Err()
+		case <-timer.C:
+		}
+		if err := c.wait(ctx, req.URL.String()); err != nil {
+			return nil, start, err
+		}
+		backoff *= 2
+	}
+}
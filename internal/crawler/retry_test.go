@@ -0,0 +1,151 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// timeoutError はタイムアウトを示すnet.Error
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "キャンセル", err: context.Canceled, want: false},
+		{name: "タイムアウト", err: &url.Error{Op: "Get", URL: "https://docs.example.com/", Err: timeoutError{}}, want: true},
+		{name: "接続の拒否", err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, want: true},
+		{name: "接続のリセット", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "切れたレスポンス", err: &url.Error{Op: "Get", URL: "https://docs.example.com/", Err: io.ErrUnexpectedEOF}, want: true},
+		{name: "EOF", err: io.EOF, want: true},
+		{name: "一時的なDNSエラー", err: &net.DNSError{Err: "server misbehaving", Name: "docs.example.com", IsTemporary: true}, want: true},
+		{name: "存在しないホスト", err: &net.DNSError{Err: "no such host", Name: "docs.example.com", IsNotFound: true}, want: false},
+		{name: "証明書の検証", err: &tls.CertificateVerificationError{Err: errors.New("expired")}, want: false},
+		{name: "不明な認証局", err: &url.Error{Op: "Get", URL: "https://docs.example.com/", Err: x509.UnknownAuthorityError{}}, want: false},
+		{name: "ホスト名の不一致", err: x509.HostnameError{Host: "docs.example.com"}, want: false},
+		{name: "その他", err: errors.New("unsupported protocol scheme"), want: false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("%s: isTransient(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+// flakyTransport は最初の failures 回のリクエストを err で失敗させ、以降は status を返すRoundTripper
+type flakyTransport struct {
+	failures int
+	err      error
+	status   int
+	calls    atomic.Int64
+}
+
+func (rt *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if n := rt.calls.Add(1); int(n) <= rt.failures {
+		return nil, rt.err
+	}
+	return &http.Response{
+		StatusCode: rt.status,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader("ok")),
+		Request:    req,
+	}, nil
+}
+
+func TestDoWithRetry(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	tests := []struct {
+		name      string
+		retries   int
+		transport *flakyTransport
+		calls     int
+		attempts  int // 失敗した場合の試行回数（0は再試行なしのエラー、-1は成功）
+	}{
+		{name: "再試行で成功", retries: 2, transport: &flakyTransport{failures: 2, err: reset, status: http.StatusOK}, calls: 3, attempts: -1},
+		{name: "再試行しても失敗", retries: 2, transport: &flakyTransport{failures: 10, err: reset}, calls: 3, attempts: 3},
+		{name: "再試行なし", retries: 0, transport: &flakyTransport{failures: 10, err: reset}, calls: 1, attempts: 0},
+		{name: "恒久的なエラー", retries: 2, transport: &flakyTransport{failures: 10, err: x509.UnknownAuthorityError{}}, calls: 1, attempts: 0},
+		{name: "500は再試行しない", retries: 2, transport: &flakyTransport{status: http.StatusInternalServerError}, calls: 1, attempts: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("https://docs.example.com/", 3, 10, 0, 0,
+				WithLogger(discardLogger()), WithRetries(tt.retries), WithHTTPClient(&http.Client{Transport: tt.transport}))
+			c.retryBackoff = time.Millisecond
+			req, err := c.newRequest(context.Background(), "https://docs.example.com/")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, _, err := c.doWithRetry(context.Background(), req)
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if got := int(tt.transport.calls.Load()); got != tt.calls {
+				t.Errorf("リクエスト数 = %d, want %d", got, tt.calls)
+			}
+			var attemptsErr *attemptsError
+			switch {
+			case tt.attempts < 0:
+				if err != nil {
+					t.Errorf("doWithRetry: %v", err)
+				}
+			case tt.attempts == 0:
+				if err == nil || errors.As(err, &attemptsErr) {
+					t.Errorf("再試行しないエラー = %v", err)
+				}
+			default:
+				if !errors.As(err, &attemptsErr) || attemptsErr.attempts != tt.attempts {
+					t.Errorf("試行回数つきのエラー = %#v, want attempts %d", err, tt.attempts)
+				}
+			}
+			if got, want := c.stats.retries.Load(), int64(tt.calls-1); got != want {
+				t.Errorf("再試行の回数 = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestDoWithRetryCanceledDuringBackoff(t *testing.T) {
+	transport := &flakyTransport{failures: 10, err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}}
+	c := New("https://docs.example.com/", 3, 10, 0, 0,
+		WithLogger(discardLogger()), WithRetries(5), WithHTTPClient(&http.Client{Transport: transport}))
+	c.retryBackoff = time.Hour
+
+	// 再試行の待機中にキャンセルされた場合はすぐに戻る
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := c.newRequest(ctx, "https://docs.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, _, err := c.doWithRetry(ctx, req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("doWithRetry = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("キャンセル後に戻るまで %v かかりました", elapsed)
+	}
+	if n := transport.calls.Load(); n != 1 {
+		t.Errorf("リクエスト数 = %d, want 1", n)
+	}
+}
@@ -0,0 +1,151 @@
+package crawler
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// breadcrumbSelector はパンくずリストのリンクを探すセレクタ
+const breadcrumbSelector = `nav[aria-label*="breadcrumb" i] a[href], .breadcrumb a[href], .breadcrumbs a[href], [itemtype*="BreadcrumbList"] a[href]`
+
+// sidebarSelector はサイドバーのナビゲーションのリンクを探すセレクタ
+const sidebarSelector = `aside a[href], .sidebar a[href], .sidenav a[href], nav[aria-label*="sidebar" i] a[href]`
+
+// homeSelector はドキュメントのトップページへのリンクを探すセレクタ
+const homeSelector = `a[rel~="home"], link[rel~="home"]`
+
+// minSidebarLinks はサイドバーのリンクの共通部分を範囲とするのに必要なリンクの数
+const minSidebarLinks = 3
+
+// inScopeRoot はURLがクロール範囲のルート以下にあるかを判定する（ルートが未確定の場合は含める）
+func (c *Crawler) inScopeRoot(link string) bool {
+	c.mu.Lock()
+	root := c.scopeRoot
+	c.mu.Unlock()
+	return root == "" || strings.HasPrefix(link, root)
+}
+
+// ScopeRoot はクロール範囲のルートを返す（開始URLのホスト全体を範囲とする場合は空）
+func (c *Crawler) ScopeRoot() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.scopeRoot
+}
+
+// discoverScopeRoot は最初に取得した開始ページからドキュメントのルートを探し、クロール範囲とする
+// サイドバーのリンクに共通するディレクトリ、パンくずリスト、rel="home" のリンクの順に、開始ページの上位のディレクトリを探す
+func (c *Crawler) discoverScopeRoot(doc *goquery.Document, pageURL string) {
+	if !c.discoverRoot {
+		return
+	}
+	c.mu.Lock()
+	decided := c.scopeRoot != "" || c.rootDiscovered
+	c.rootDiscovered = true
+	c.mu.Unlock()
+	if decided {
+		return
+	}
+
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return
+	}
+	pageDir := page.Path
+	if !strings.HasSuffix(pageDir, "/") {
+		pageDir = path.Dir(pageDir) + "/"
+	}
+
+	root := sidebarRoot(doc, page, pageDir)
+	if root == "" {
+		root = ancestorLink(doc, breadcrumbSelector, page, pageDir)
+	}
+	if root == "" {
+		root = ancestorLink(doc, homeSelector, page, pageDir)
+	}
+	if root == "" {
+		c.logger.Warn("ドキュメントのルートを判定できないため、開始URLのホスト全体をクロール範囲とします", "url", pageURL)
+		return
+	}
+
+	scopeRoot := page.Scheme + "://" + page.Host + root
+	c.mu.Lock()
+	c.scopeRoot = scopeRoot
+	c.mu.Unlock()
+	c.logger.Info("ドキュメントのルートをクロール範囲とします（誤っている場合は --scope-root で指定してください）", "root", scopeRoot)
+}
+
+// sidebarRoot はサイドバーの同じホストのリンクに共通するディレクトリを返す（開始ページの上位でない場合は空）
+func sidebarRoot(doc *goquery.Document, page *url.URL, pageDir string) string {
+	var common []string
+	count := 0
+	doc.Find(sidebarSelector).Each(func(i int, s *goquery.Selection) {
+		link, ok := sameHostPath(page, s.AttrOr("href", ""))
+		if !ok {
+			return
+		}
+		if !strings.HasSuffix(link, "/") {
+			link = path.Dir(link)
+		}
+		dir := strings.Split(strings.Trim(link, "/"), "/")
+		if count == 0 {
+			common = dir
+		} else {
+			n := 0
+			for n < len(common) && n < len(dir) && common[n] == dir[n] {
+				n++
+			}
+			common = common[:n]
+		}
+		count++
+	})
+	if count < minSidebarLinks || len(common) == 0 || common[0] == "" {
+		return ""
+	}
+	root := "/" + strings.Join(common, "/") + "/"
+	if !strings.HasPrefix(pageDir, root) {
+		return ""
+	}
+	return root
+}
+
+// ancestorLink はセレクタに一致するリンクのうち、開始ページの上位のディレクトリを指す最初のものを返す
+// サイトのトップ（/）を指すリンクはドキュメントのルートとみなさない
+func ancestorLink(doc *goquery.Document, selector string, page *url.URL, pageDir string) string {
+	var root string
+	doc.Find(selector).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		link, ok := sameHostPath(page, s.AttrOr("href", ""))
+		if !ok {
+			return true
+		}
+		// 末尾のスラッシュのない /docs のようなリンクもディレクトリとして扱う
+		switch {
+		case strings.HasSuffix(link, "/"):
+		case !strings.Contains(path.Base(link), ".") && strings.HasPrefix(pageDir, link+"/"):
+			link += "/"
+		default:
+			link = path.Dir(link) + "/"
+		}
+		if link != "/" && strings.HasPrefix(pageDir, link) {
+			root = link
+			return false
+		}
+		return true
+	})
+	return root
+}
+
+// sameHostPath は開始ページと同じホストのリンクのパスを返す
+func sameHostPath(page *url.URL, href string) (string, bool) {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || href == "" {
+		return "", false
+	}
+	u := page.ResolveReference(ref)
+	if !strings.EqualFold(u.Host, page.Host) || u.Path == "" {
+		return "", false
+	}
+	return u.Path, true
+}
@@ -0,0 +1,235 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionCookie はセッションファイルに保存する1つのCookie
+type sessionCookie struct {
+	Domain   string    `json:"domain"`
+	HostOnly bool      `json:"host_only,omitempty"` // Domain属性がなく、設定したホストにのみ送るCookieか
+	Path     string    `json:"path"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Expires  time.Time `json:"expires,omitempty"` // ゼロ値はブラウザを閉じるまで有効なCookie
+	Secure   bool      `json:"secure,omitempty"`
+	HTTPOnly bool      `json:"http_only,omitempty"`
+}
+
+// key はCookieを区別するキー
+func (sc sessionCookie) key() string {
+	return sc.Domain + ";" + sc.Path + ";" + sc.Name
+}
+
+// expired はCookieが期限切れかどうかを判定する
+func (sc sessionCookie) expired(now time.Time) bool {
+	return !sc.Expires.IsZero() && !sc.Expires.After(now)
+}
+
+// recordingJar は設定されたCookieを記録してセッションファイルに保存できるようにするCookieジャー
+// net/http/cookiejar は保存しているCookieを列挙できないため、SetCookies の内容を別に保持する
+type recordingJar struct {
+	http.CookieJar
+	mu      sync.Mutex
+	cookies map[string]sessionCookie
+}
+
+// newRecordingJar は jar への設定を記録するCookieジャーを作成する
+func newRecordingJar(jar http.CookieJar) *recordingJar {
+	return &recordingJar{CookieJar: jar, cookies: make(map[string]sessionCookie)}
+}
+
+// SetCookies はCookieを設定し、保存用に記録する
+func (j *recordingJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.CookieJar.SetCookies(u, cookies)
+
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, cookie := range cookies {
+		sc := sessionCookie{
+			Domain:   strings.TrimPrefix(strings.ToLower(cookie.Domain), "."),
+			Path:     cookie.Path,
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Secure:   cookie.Secure,
+			HTTPOnly: cookie.HttpOnly,
+		}
+		if sc.Domain == "" {
+			sc.Domain, sc.HostOnly = u.Hostname(), true
+		}
+		if sc.Path == "" || !strings.HasPrefix(sc.Path, "/") {
+			sc.Path = defaultCookiePath(u.Path)
+		}
+		switch {
+		case cookie.MaxAge < 0:
+			sc.Expires = now
+		case cookie.MaxAge > 0:
+			sc.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case !cookie.Expires.IsZero():
+			sc.Expires = cookie.Expires
+		}
+		// 削除や期限切れのCookieは記録からも取り除く
+		if sc.expired(now) {
+			delete(j.cookies, sc.key())
+			continue
+		}
+		j.cookies[sc.key()] = sc
+	}
+}
+
+// snapshot は記録している有効なCookieを返す
+func (j *recordingJar) snapshot() []sessionCookie {
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cookies := make([]sessionCookie, 0, len(j.cookies))
+	for _, sc := range j.cookies {
+		if !sc.expired(now) {
+			cookies = append(cookies, sc)
+		}
+	}
+	return cookies
+}
+
+// defaultCookiePath はPath属性のないCookieのパス（RFC 6265 の default-path）を返す
+func defaultCookiePath(urlPath string) string {
+	if !strings.HasPrefix(urlPath, "/") || strings.Count(urlPath, "/") == 1 {
+		return "/"
+	}
+	return path.Dir(urlPath)
+}
+
+// loadSession はセッションファイルのCookieをCookieジャーに設定する（期限切れのCookieは読み込まない）
+// ファイルがない場合は false を返す
+func (c *Crawler) loadSession() (bool, error) {
+	data, err := os.ReadFile(c.sessionFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("セッションファイルの読み込みに失敗しました: %w", err)
+	}
+	var cookies []sessionCookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return false, fmt.Errorf("セッションファイルの解析に失敗しました: %w", err)
+	}
+
+	now, loaded := time.Now(), 0
+	for _, sc := range cookies {
+		if sc.expired(now) {
+			continue
+		}
+		cookie := &http.Cookie{
+			Name:     sc.Name,
+			Value:    sc.Value,
+			Path:     sc.Path,
+			Expires:  sc.Expires,
+			Secure:   sc.Secure,
+			HttpOnly: sc.HTTPOnly,
+		}
+		if !sc.HostOnly {
+			cookie.Domain = sc.Domain
+		}
+		c.jar.SetCookies(&url.URL{Scheme: "https", Host: sc.Domain, Path: sc.Path}, []*http.Cookie{cookie})
+		loaded++
+	}
+	c.logger.Info("セッションファイルからCookieを読み込みました", "path", c.sessionFile, "cookies", loaded)
+	return loaded > 0, nil
+}
+
+// saveSession はCookieジャーのCookieをセッションファイルに保存する（本人のみ読み書きできる権限にする）
+func (c *Crawler) saveSession() error {
+	jar, ok := c.jar.(*recordingJar)
+	if c.sessionFile == "" || !ok {
+		return nil
+	}
+	data, err := json.MarshalIndent(jar.snapshot(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("セッションファイルの作成に失敗しました: %w", err)
+	}
+
+	// 書き込み途中のファイルを読まないよう一時ファイルからリネームする
+	tmp, err := os.CreateTemp(filepath.Dir(c.sessionFile), filepath.Base(c.sessionFile)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("セッションファイルの保存に失敗しました: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("セッションファイルの保存に失敗しました: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("セッションファイルの保存に失敗しました: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("セッションファイルの保存に失敗しました: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.sessionFile); err != nil {
+		return fmt.Errorf("セッションファイルの保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// sessionValid は読み込んだセッションで開始URLにアクセスできるかを確認する
+// 401・403が返された場合やログインページへリダイレクトされた場合は無効とみなす
+func (c *Crawler) sessionValid(ctx context.Context) (bool, error) {
+	if err := c.wait(ctx, c.baseURL); err != nil {
+		return false, err
+	}
+	req, err := c.newRequest(ctx, c.baseURL)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("セッションの確認に失敗しました: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return false, nil
+	}
+	if login, err := url.Parse(c.login.URL); err == nil && resp.Request.URL.Host == login.Host && resp.Request.URL.Path == login.Path {
+		return false, nil
+	}
+	return true, nil
+}
+
+// restoreSessionOrLogin はセッションファイルを読み込み、セッションが無効な場合のみフォームログインを行う
+func (c *Crawler) restoreSessionOrLogin(ctx context.Context) error {
+	loaded := false
+	if c.sessionFile != "" {
+		var err error
+		if loaded, err = c.loadSession(); err != nil {
+			return err
+		}
+	}
+	if c.login == nil {
+		return nil
+	}
+	if loaded {
+		valid, err := c.sessionValid(ctx)
+		if err != nil {
+			return err
+		}
+		if valid {
+			c.logger.Info("保存したセッションが有効なためログインを省略します")
+			return nil
+		}
+		c.logger.Info("保存したセッションが無効なため再ログインします")
+	}
+	return c.doLogin(ctx)
+}
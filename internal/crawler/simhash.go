@@ -0,0 +1,113 @@
+package crawler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// DefaultNearDupThreshold はほぼ同じ内容とみなすsimhashのハミング距離のデフォルト値（この値未満を同じとみなす）
+// タイムスタンプやフッターだけが異なる数百語のページの距離は10ビット未満に収まる一方、
+// 内容の異なるページの距離は平均32ビット（標準偏差4ビット）のため、別の内容のページを落とすことはまずない
+const DefaultNearDupThreshold = 10
+
+// nearDupMinTokens はほぼ同じ内容かを判定するのに必要な語の数
+// 語の少ないページではわずかな違いでも内容が大きく異なるため判定しない
+const nearDupMinTokens = 50
+
+// simhashShingle は指紋の計算に使う連続した語の数
+const simhashShingle = 3
+
+// NearDupAction はほぼ同じ内容のページを見つけたときの扱い
+type NearDupAction string
+
+const (
+	NearDupAlias NearDupAction = "alias" // 既存のページの別URLとして記録する
+	NearDupSkip  NearDupAction = "skip"  // 収集せずにスキップする
+)
+
+// ParseNearDupAction は --near-dup-action の値を解析する
+func ParseNearDupAction(s string) (NearDupAction, error) {
+	switch action := NearDupAction(strings.ToLower(s)); action {
+	case NearDupAlias, NearDupSkip:
+		return action, nil
+	}
+	return "", fmt.Errorf("--near-dup-action には alias または skip を指定してください: %s", s)
+}
+
+// pageFingerprint は収集済みページのsimhashとpagesでの位置
+type pageFingerprint struct {
+	hash  uint64
+	index int
+}
+
+// textTokens は本文を語に分割する（空白で区切らない日本語などは1文字を1語とする）
+func textTokens(text string) []string {
+	var tokens []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// simhash は本文の連続した語の組から64ビットのsimhashを計算する（語が少ない場合は false）
+func simhash(text string) (uint64, bool) {
+	tokens := textTokens(text)
+	if len(tokens) < nearDupMinTokens {
+		return 0, false
+	}
+
+	var weights [64]int
+	h := fnv.New64a()
+	for i := 0; i+simhashShingle <= len(tokens); i++ {
+		h.Reset()
+		for _, token := range tokens[i : i+simhashShingle] {
+			h.Write([]byte(token))
+			h.Write([]byte{0})
+		}
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, w := range weights {
+		if w > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint, true
+}
+
+// findNearDuplicate はsimhashのハミング距離がしきい値未満の収集済みページの位置を返す（pagesのミューテックスで保護）
+func (c *Crawler) findNearDuplicate(hash uint64) (int, bool) {
+	for _, fp := range c.fingerprints {
+		if bits.OnesCount64(fp.hash^hash) < c.nearDupThreshold {
+			return fp.index, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"context"
+	"math/bits"
+	"strings"
+	"testing"
+)
+
+// simhashArticle は指紋の確認に使う本文（語の数は nearDupMinTokens より十分に多い）
+const simhashArticle = `Installing the command line tool is the fastest way to get started. Download the archive for your
+platform from the releases page, extract it into a directory on your PATH and run the version command to confirm that the
+binary works. The tool reads its configuration from a file in your home directory, and every option in that file can also
+be passed as a flag. When both are present the flag wins, which makes it easy to experiment without editing the file.
+Projects are created with the init command, which writes a skeleton configuration, a sample content directory and a
+gitignore file. Run the build command to render the site into the output directory, or the serve command to start a local
+preview server that rebuilds pages whenever a source file changes. Deploying is a matter of copying the output directory
+to any static host; the documentation lists recipes for the most common providers.`
+
+// simhashOtherArticle は simhashArticle と内容の異なる本文
+const simhashOtherArticle = `Authentication is configured per environment. Create an API key in the dashboard, store it in a secret
+manager and expose it to the application through an environment variable. Keys can be scoped to read only access, and
+every request made with a key is recorded in the audit log together with the address it came from. Rotate keys regularly:
+the dashboard lets you create a second key, switch your deployments over and revoke the old one without downtime. If a key
+leaks, revoke it immediately and review the audit log for requests you do not recognise. Service accounts are available on
+team plans and behave like users that can only authenticate with keys, which keeps automation separate from people.`
+
+// simhashPage は本文に定型のヘッダーとフッターを付けたページのテキストを返す
+func simhashPage(body, footer string) string {
+	return "Docs Home Guide API Reference Blog\n\n" + body + "\n\n" + footer
+}
+
+// simhashDistance は2つの本文のsimhashのハミング距離を返す
+func simhashDistance(t *testing.T, a, b string) int {
+	t.Helper()
+	ha, ok := simhash(a)
+	if !ok {
+		t.Fatalf("simhash が計算されませんでした: %q", a)
+	}
+	hb, ok := simhash(b)
+	if !ok {
+		t.Fatalf("simhash が計算されませんでした: %q", b)
+	}
+	return bits.OnesCount64(ha ^ hb)
+}
+
+func TestSimhashBoilerplateOnly(t *testing.T) {
+	a := simhashPage(simhashArticle, "Last updated on 2024-01-15 09:30. Copyright 2024 Example Inc.")
+	for _, footer := range []string{
+		"Last updated on 2025-06-02 17:45. Copyright 2025 Example Inc.",
+		"Tip: join the community forum to ask questions. Copyright 2024 Example Inc.",
+	} {
+		b := simhashPage(simhashArticle, footer)
+		if d := simhashDistance(t, a, b); d >= DefaultNearDupThreshold {
+			t.Errorf("フッター %q だけが異なるページのハミング距離 = %d, want < %d", footer, d, DefaultNearDupThreshold)
+		}
+	}
+	if d := simhashDistance(t, a, a); d != 0 {
+		t.Errorf("同じ本文のハミング距離 = %d, want 0", d)
+	}
+}
+
+func TestSimhashDifferentContent(t *testing.T) {
+	footer := "Last updated on 2024-01-15 09:30. Copyright 2024 Example Inc."
+	if d := simhashDistance(t, simhashPage(simhashArticle, footer), simhashPage(simhashOtherArticle, footer)); d < DefaultNearDupThreshold {
+		t.Errorf("本文が異なるページのハミング距離 = %d, want >= %d", d, DefaultNearDupThreshold)
+	}
+}
+
+func TestSimhashShortText(t *testing.T) {
+	if _, ok := simhash("Page not found. Return to the home page."); ok {
+		t.Error("語の少ない本文で simhash が計算されました")
+	}
+}
+
+func TestTextTokens(t *testing.T) {
+	got := strings.Join(textTokens("Hello, World! v2.0 の手順"), "|")
+	if want := "hello|world|v2|0|の|手|順"; got != want {
+		t.Errorf("textTokens = %s, want %s", got, want)
+	}
+}
+
+func TestCrawlNearDuplicates(t *testing.T) {
+	// タイムスタンプだけが異なる /copy は /guide のほぼ同じ内容のページとして扱い、本文の異なる /auth は収集する
+	body := strings.ReplaceAll(simhashArticle, "\n", " ")
+	site := newHTMLSite(t, map[string]string{
+		"/":      htmlPage("Top", "top page", "/guide", "/copy", "/auth"),
+		"/guide": htmlPage("Guide", body+" Last updated on 2024-01-15 09:30."),
+		"/copy":  htmlPage("Guide", body+" Last updated on 2025-06-02 17:45."),
+		"/auth":  htmlPage("Auth", strings.ReplaceAll(simhashOtherArticle, "\n", " ")+" Last updated on 2024-01-15 09:30."),
+	})
+
+	for _, action := range []NearDupAction{NearDupAlias, NearDupSkip} {
+		t.Run(string(action), func(t *testing.T) {
+			c := New(site.URL+"/", 3, 10, 0, 0, WithLogger(discardLogger()), WithNearDuplicates(DefaultNearDupThreshold, action))
+			result, err := c.Crawl(context.Background())
+			if err != nil {
+				t.Fatalf("Crawl: %v", err)
+			}
+			if got := strings.Join(pagePaths(site, result.Pages), ","); got != "/,/guide,/auth" {
+				t.Fatalf("取得したページ = %s, want /,/guide,/auth", got)
+			}
+			aliases := result.Pages[1].AliasURLs
+			if action == NearDupAlias && (len(aliases) != 1 || aliases[0] != site.URL+"/copy") {
+				t.Errorf("別URL = %v, want [%s/copy]", aliases, site.URL)
+			}
+			if action == NearDupSkip && len(aliases) != 0 {
+				t.Errorf("スキップしたページが別URLとして記録されました: %v", aliases)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxSitemapFiles はサイトマップインデックスをたどって取得するサイトマップの上限
+const maxSitemapFiles = 100
+
+// sitemapDocument はサイトマップ（urlset）とサイトマップインデックス（sitemapindex）の共通の形式
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+// sitemapLoc はサイトマップの1エントリ
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// discoverSitemapURLs は開始URLのサイトマップを取得し、クロール範囲内のページのURLを返す
+// robots.txtのSitemap行を優先し、ない場合は /sitemap.xml を参照する
+func (c *Crawler) discoverSitemapURLs(ctx context.Context, seed string) ([]string, error) {
+	baseURL, err := parseBaseURL(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	queue, err := c.robotsSitemaps(ctx, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(queue) == 0 {
+		queue = []string{baseURL + "/sitemap.xml"}
+	}
+
+	var pages []string
+	seenSitemaps := make(map[string]bool)
+	found := false
+	for len(queue) > 0 && len(seenSitemaps) < maxSitemapFiles {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if seenSitemaps[sitemapURL] {
+			continue
+		}
+		seenSitemaps[sitemapURL] = true
+
+		doc, err := c.fetchSitemap(ctx, sitemapURL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			c.logger.Warn("サイトマップを取得できませんでした", "url", sitemapURL, "error", err)
+			continue
+		}
+		found = true
+		c.logger.Info("サイトマップを取得しました", "url", sitemapURL)
+
+		// サイトマップインデックスの場合は子のサイトマップをたどる
+		for _, sm := range doc.Sitemaps {
+			if loc := strings.TrimSpace(sm.Loc); loc != "" {
+				queue = append(queue, loc)
+			}
+		}
+		for _, u := range doc.URLs {
+			loc := strings.TrimSpace(u.Loc)
+			if loc == "" || !c.inScope(loc) {
+				continue
+			}
+			pages = append(pages, loc)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("サイトマップが見つかりません (%s): robots.txtのSitemap行または /sitemap.xml が必要です", baseURL)
+	}
+	return pages, nil
+}
+
+// robotsSitemaps はrobots.txtに記載されたサイトマップのURLを返す（robots.txtがない場合は空）
+func (c *Crawler) robotsSitemaps(ctx context.Context, baseURL string) ([]string, error) {
+	if err := c.wait(ctx, baseURL); err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, baseURL+"/robots.txt")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+	body, _, err := readResponseBody(resp, c.maxBodySize)
+	if err != nil {
+		return nil, nil
+	}
+
+	var sitemaps []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "sitemap") {
+			if value = strings.TrimSpace(value); value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		}
+	}
+	return sitemaps, nil
+}
+
+// fetchSitemap はサイトマップを取得して解析する（gzip圧縮されたサイトマップにも対応する）
+func (c *Crawler) fetchSitemap(ctx context.Context, sitemapURL string) (*sitemapDocument, error) {
+	if err := c.wait(ctx, sitemapURL); err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, c.wrapProxyError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ステータスコード %d", resp.StatusCode)
+	}
+	body, _, err := readResponseBody(resp, c.maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("サイトマップの解析に失敗しました: %w", err)
+	}
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return nil, fmt.Errorf("サイトマップの形式ではありません (%s)", doc.XMLName.Local)
+	}
+	return &doc, nil
+}
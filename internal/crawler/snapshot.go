@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/yugo-ibuki/docrawl/internal/pathutil"
+)
+
+// snapshotManifestName は保存したHTMLの一覧を書き出すファイル名
+const snapshotManifestName = "manifest.json"
+
+// snapshotEntry は保存したHTMLファイルと元のページの対応
+type snapshotEntry struct {
+	Path       string    `json:"path"` // 保存先ディレクトリからの相対パス
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status_code"`
+	FetchedAt  time.Time `json:"fetched_at"`
+}
+
+// saveSnapshot はレスポンスのボディをURLのパスに対応するファイルに保存する
+func (c *Crawler) saveSnapshot(url string, statusCode int, fetchedAt time.Time, body []byte) {
+	if c.htmlDir == "" {
+		return
+	}
+
+	// 拡張子のないURL（/guide など）は同名のディレクトリと衝突しないよう .html を付ける
+	rel := pathutil.FromURL(url)
+	if path.Ext(rel) == "" {
+		rel += ".html"
+	}
+	dest := filepath.Join(c.htmlDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		c.logger.Warn("HTMLの保存に失敗しました", "url", url, "error", err)
+		return
+	}
+	if err := os.WriteFile(dest, body, 0o644); err != nil {
+		c.logger.Warn("HTMLの保存に失敗しました", "url", url, "error", err)
+		return
+	}
+
+	c.mu.Lock()
+	c.snapshots = append(c.snapshots, snapshotEntry{
+		Path:       rel,
+		URL:        url,
+		StatusCode: statusCode,
+		FetchedAt:  fetchedAt,
+	})
+	c.mu.Unlock()
+}
+
+// writeSnapshotManifest は保存したHTMLファイルとURLの対応を書き出す
+func (c *Crawler) writeSnapshotManifest() error {
+	if c.htmlDir == "" {
+		return nil
+	}
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.snapshots, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("HTMLの一覧の作成に失敗しました: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(c.htmlDir, snapshotManifestName), data, 0o644); err != nil {
+		return fmt.Errorf("HTMLの一覧の保存に失敗しました: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultSoft404MaxChars はソフト404と判定する本文の最大文字数のデフォルト値
+const DefaultSoft404MaxChars = 300
+
+// defaultSoft404Patterns はソフト404のタイトルや本文に一致するデフォルトのパターン
+var defaultSoft404Patterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b404\b`),
+	regexp.MustCompile(`(?i)\bpage not found\b`),
+	regexp.MustCompile(`(?i)\bnot found\b`),
+	regexp.MustCompile(`ページが見つかりません`),
+}
+
+// errSoft404 はステータスコード200で「ページが見つかりません」を返したページを表す
+var errSoft404 = withKind(ErrorSoft404, fmt.Errorf("ページが見つからないことを示す内容のため収集しません"))
+
+// isSoft404 はページがソフト404（200で返された「ページが見つかりません」のページ）かどうかを判定する
+// 本文で404に触れているだけのページを除外するため、パターンの一致に加えて本文が短いことを条件とする
+// タイトルはサイト名を除いた部分、本文は最初のブロック（見出しなど）の大半をパターンが占める場合のみ一致とみなす
+func (c *Crawler) isSoft404(title, content string) bool {
+	if c.soft404MaxChars <= 0 || utf8.RuneCountInString(strings.TrimSpace(content)) > c.soft404MaxChars {
+		return false
+	}
+	patterns := c.soft404Patterns
+	if patterns == nil {
+		patterns = defaultSoft404Patterns
+	}
+	name := pageTitleName(title)
+	lead := leadingBlock(content)
+	for _, re := range patterns {
+		if dominates(re, name) || dominates(re, lead) {
+			return true
+		}
+	}
+	return false
+}
+
+// titleSeparators はタイトルのページ名とサイト名を区切る記号
+var titleSeparators = []string{" | ", " – ", " — ", " - ", " · ", " :: "}
+
+// pageTitleName はタイトルから「 | Example Docs」のようなサイト名の部分を除いたページ名を返す
+func pageTitleName(title string) string {
+	title = strings.TrimSpace(title)
+	for _, sep := range titleSeparators {
+		if i := strings.Index(title, sep); i > 0 {
+			title = title[:i]
+		}
+	}
+	return strings.TrimSpace(title)
+}
+
+// leadingBlock は本文の最初の空でない行から見出しや引用などの記号を除いたテキストを返す
+func leadingBlock(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#>*-+ "))
+		if line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// dominates はパターンに一致した部分がテキストの半分以上を占めるかを判定する
+func dominates(re *regexp.Regexp, text string) bool {
+	total := utf8.RuneCountInString(text)
+	if total == 0 {
+		return false
+	}
+	matched := 0
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		matched += utf8.RuneCountInString(text[loc[0]:loc[1]])
+	}
+	return matched*2 >= total
+}
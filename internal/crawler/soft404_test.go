@@ -0,0 +1,76 @@
+package crawler
+
+import "testing"
+
+func TestIsSoft404(t *testing.T) {
+	c := &Crawler{soft404MaxChars: DefaultSoft404MaxChars}
+	tests := []struct {
+		name    string
+		title   string
+		content string
+		want    bool
+	}{
+		{
+			name:    "見出しがPage not found",
+			title:   "Example Docs",
+			content: "# Page not found\n\nThe page you are looking for does not exist. Go back to the home page.",
+			want:    true,
+		},
+		{
+			name:    "見出しが404",
+			title:   "Example Docs",
+			content: "## 404\n\nSorry, we couldn't find that page.",
+			want:    true,
+		},
+		{
+			name:    "タイトルが404",
+			title:   "404 | Example Docs",
+			content: "Sorry, we couldn't find that page.",
+			want:    true,
+		},
+		{
+			name:    "タイトルがサイト名付きのPage not found",
+			title:   "Page not found – Example Docs",
+			content: "Sorry, we couldn't find that page.",
+			want:    true,
+		},
+		{
+			name:    "タイトルで not found に触れているだけ",
+			title:   "Troubleshooting: module not found",
+			content: "Run the install command again and check that the module path is spelled correctly.",
+			want:    false,
+		},
+		{
+			name:    "タイトルで404に触れているだけ",
+			title:   "HTTP 404 responses",
+			content: "The API answers with 404 when the resource does not exist.",
+			want:    false,
+		},
+		{
+			name:    "本文で404に触れているだけ",
+			title:   "Get",
+			content: "# Get\n\nReturns ErrNotFound if the key is not found. The HTTP API answers with 404 in that case.",
+			want:    false,
+		},
+		{
+			name:    "最初の段落で not found に触れているだけ",
+			title:   "Get",
+			content: "Returns ErrNotFound if the key is not found.",
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.isSoft404(tt.title, tt.content); got != tt.want {
+				t.Errorf("isSoft404(%q, %q) = %v, want %v", tt.title, tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSoft404LongContent(t *testing.T) {
+	c := &Crawler{soft404MaxChars: 20}
+	if c.isSoft404("Page not found", "# Page not found\n\nThis page is longer than the limit.") {
+		t.Error("上限より長い本文のページがソフト404と判定されました")
+	}
+}
@@ -0,0 +1,147 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// editLinkSelectors は「このページを編集」リンクを探すためのセレクタ
+// Docusaurus、MkDocs（Material）などの代表的なマークアップに対応する
+var editLinkSelectors = []string{
+	"a.theme-edit-this-page",
+	`a.md-content__button[rel="edit"]`,
+	`a.md-icon[title="Edit this page"]`,
+	`a[rel="edit"]`,
+}
+
+// extractEditURL はページから「このページを編集」リンクを絶対URLとして取得する
+func extractEditURL(doc *goquery.Document, pageURL string) string {
+	for _, selector := range editLinkSelectors {
+		href, exists := doc.Find(selector).First().Attr("href")
+		if !exists || strings.TrimSpace(href) == "" {
+			continue
+		}
+		resolved, err := resolveURL(pageURL, strings.TrimSpace(href))
+		if err == nil {
+			return resolved
+		}
+	}
+	return ""
+}
+
+// sourceMarkdownCandidates はページに対応する元のMarkdownソースの候補URLを返す
+func sourceMarkdownCandidates(pageURL, editURL string) []string {
+	var candidates []string
+
+	// 編集リンクからRAWファイルのURLを組み立てる
+	if rawURL := rawURLFromEditURL(editURL); rawURL != "" {
+		candidates = append(candidates, rawURL)
+	}
+
+	// ページURLに.mdを付与したURL（Docusaurusなど）
+	u, err := url.Parse(stripFragment(pageURL))
+	if err == nil && u.Path != "" && u.Path != "/" && !strings.HasSuffix(u.Path, ".md") {
+		u.RawQuery = ""
+		u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".html") + ".md"
+		candidates = append(candidates, u.String())
+	}
+
+	return candidates
+}
+
+// rawURLFromEditURL はGitHub/GitLabの編集URLをRAWファイルのURLに変換する
+func rawURLFromEditURL(editURL string) string {
+	if editURL == "" {
+		return ""
+	}
+	u, err := url.Parse(editURL)
+	if err != nil {
+		return ""
+	}
+
+	switch u.Host {
+	case "github.com":
+		// /{owner}/{repo}/(edit|blob)/{branch}/{path...}
+		parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 4)
+		if len(parts) < 4 || (parts[2] != "edit" && parts[2] != "blob") {
+			return ""
+		}
+		return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s", parts[0], parts[1], parts[3])
+	case "gitlab.com":
+		// /{namespace...}/-/(edit|blob)/{branch}/{path...}
+		for _, marker := range []string{"/-/edit/", "/-/blob/"} {
+			if strings.Contains(u.Path, marker) {
+				u.Path = strings.Replace(u.Path, marker, "/-/raw/", 1)
+				u.RawQuery = ""
+				return u.String()
+			}
+		}
+	}
+	return ""
+}
+
+// fetchSourceMarkdown は元のMarkdownソースを取得し、フロントマターを除いた内容を返す
+// 取得できなかった場合は false を返し、呼び出し側はHTML抽出にフォールバックする
+func (c *Crawler) fetchSourceMarkdown(ctx context.Context, pageURL, editURL string) (string, bool) {
+	for _, candidate := range sourceMarkdownCandidates(pageURL, editURL) {
+		// 遅延を入れる
+		if err := c.wait(ctx, candidate); err != nil {
+			return "", false
+		}
+
+		req, err := c.newRequest(ctx, candidate)
+		if err != nil {
+			continue
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			continue
+		}
+		body, truncated, err := readResponseBody(resp, c.maxBodySize)
+		resp.Body.Close()
+		c.stats.bytesDownloaded.Add(int64(len(body)))
+		if err != nil || truncated || resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		// HTMLが返ってきた場合はMarkdownソースではない
+		if strings.Contains(resp.Header.Get("Content-Type"), "html") {
+			continue
+		}
+		content := stripFrontMatter(string(body))
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+
+		c.logger.Info("Markdownソースを使用", "url", candidate)
+		return content, true
+	}
+	return "", false
+}
+
+// stripFrontMatter はMarkdown先頭のYAMLフロントマター（---の行で囲まれた部分）を取り除く
+// 改行コードは変換せず、元の文字列の閉じる---の行より後をそのまま返す（閉じる---が最後の行で改行がない場合も含む）
+func stripFrontMatter(markdown string) string {
+	body, ok := strings.CutPrefix(markdown, "---\n")
+	if !ok {
+		if body, ok = strings.CutPrefix(markdown, "---\r\n"); !ok {
+			return markdown
+		}
+	}
+	for pos := 0; pos < len(body); {
+		line, next := body[pos:], len(body)
+		if end := strings.IndexByte(line, '\n'); end >= 0 {
+			line, next = line[:end], pos+end+1
+		}
+		if strings.TrimSuffix(line, "\r") == "---" {
+			return strings.TrimLeft(body[next:], "\r\n")
+		}
+		pos = next
+	}
+	return markdown
+}
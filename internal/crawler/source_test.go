@@ -0,0 +1,149 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sourceSiteHTML はMarkdownソースを持つサイトのHTMLページ
+var sourceSiteHTML = map[string]string{
+	"/docs/": `<html><head><title>Docs</title></head><body><main>
+		<h1 id="docs">Docs</h1>
+		<a href="/docs/install">Install</a> <a href="/docs/usage">Usage</a> <a href="/docs/faq">FAQ</a>
+	</main></body></html>`,
+	"/docs/install": `<html><head><title>Install</title></head><body><main>
+		<h1 id="install">Install</h1><p>Install the CLI with npm:</p>
+		<pre><code>npm install -g docrawl</code></pre>
+		<h2 id="config">Configuration</h2><p>Set <code>DOCRAWL_TOKEN</code> before running.</p>
+		<a href="/docs/faq">FAQ</a>
+	</main></body></html>`,
+	"/docs/usage": `<html><head><title>Usage</title></head><body><main>
+		<h1 id="usage">Usage</h1><p>Run <code>docrawl -u &lt;url&gt;</code>.</p>
+	</main></body></html>`,
+	"/docs/faq": `<html><head><title>FAQ</title></head><body><main>
+		<h1 id="faq">FAQ</h1><h2 id="why">Why?</h2><p>Because.</p>
+	</main></body></html>`,
+}
+
+func TestPreferSourceMarkdownMixedSite(t *testing.T) {
+	sources := map[string]string{}
+	for _, name := range []string{"install", "usage"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "source", name+".md"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources["/docs/"+name+".md"] = string(data)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if body, ok := sources[r.URL.Path]; ok {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			w.Write([]byte(body))
+			return
+		}
+		if body, ok := sourceSiteHTML[r.URL.Path]; ok {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(body))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/docs/", 3, 5, 0, 0, WithLogger(discardLogger()), WithPreferSourceMarkdown(true))
+	result, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := make(map[string]Page)
+	for _, page := range result.Pages {
+		pages[strings.TrimPrefix(page.URL, server.URL)] = page
+	}
+	if len(pages) != 4 {
+		t.Fatalf("取得したページ = %d 件, want 4: %v", len(pages), pages)
+	}
+
+	// フロントマターのあるソースは、フロントマターを除いた内容をそのまま使い、タイトルを加えない
+	install := pages["/docs/install"]
+	if !install.FromSource {
+		t.Fatal("/docs/install でMarkdownソースが使われていません")
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "source", "install.want.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if install.Content != string(want) {
+		t.Errorf("/docs/install の内容がソースと異なります\ngot:\n%q\nwant:\n%q", install.Content, want)
+	}
+	if install.Title != "Install" {
+		t.Errorf("/docs/install のタイトル = %q, want %q", install.Title, "Install")
+	}
+
+	// フロントマターのないソースはそのまま使う
+	usage := pages["/docs/usage"]
+	if !usage.FromSource || usage.Content != sources["/docs/usage.md"] {
+		t.Errorf("/docs/usage の内容がソースと異なります\ngot:\n%q\nwant:\n%q", usage.Content, sources["/docs/usage.md"])
+	}
+
+	// ソースのないページはHTMLから抽出し、「# タイトル」で始まる
+	for _, path := range []string{"/docs/", "/docs/faq"} {
+		page := pages[path]
+		if page.FromSource {
+			t.Errorf("%s でMarkdownソースが使われました", path)
+		}
+		if !strings.HasPrefix(page.Content, "# "+page.Title+"\n\n") {
+			t.Errorf("%s の先頭がタイトルではありません:\n%s", path, page.Content)
+		}
+	}
+
+	// 見出しの一覧はどちらの場合もHTMLのアンカーとともに抽出する
+	wantHeadings := map[string][]Heading{
+		"/docs/install": {{Level: 1, Text: "Install", Anchor: "install"}, {Level: 2, Text: "Configuration", Anchor: "config"}},
+		"/docs/usage":   {{Level: 1, Text: "Usage", Anchor: "usage"}},
+		"/docs/faq":     {{Level: 1, Text: "FAQ", Anchor: "faq"}, {Level: 2, Text: "Why?", Anchor: "why"}},
+	}
+	for path, want := range wantHeadings {
+		got := pages[path].Headings
+		if len(got) != len(want) {
+			t.Errorf("%s の見出し = %v, want %v", path, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s の見出し = %v, want %v", path, got, want)
+				break
+			}
+		}
+		if len(pages[path].Outline) == 0 {
+			t.Errorf("%s の目次がありません", path)
+		}
+	}
+}
+
+func TestStripFrontMatter(t *testing.T) {
+	tests := []struct {
+		name, markdown, want string
+	}{
+		{"フロントマターなし", "# Title\n\nBody\n", "# Title\n\nBody\n"},
+		{"フロントマターなし（CRLF）", "# Title\r\n\r\nBody\r\n", "# Title\r\n\r\nBody\r\n"},
+		{"フロントマター", "---\ntitle: Install\n---\n\n# Install\n", "# Install\n"},
+		{"フロントマター（CRLF）", "---\r\ntitle: Install\r\n---\r\n\r\n# Install\r\nBody\r\n", "# Install\r\nBody\r\n"},
+		{"空のフロントマター", "---\n---\nBody\n", "Body\n"},
+		{"閉じる---で終わる", "---\ntitle: Draft\n---", ""},
+		{"閉じる---で終わる（CRLF）", "---\r\ntitle: Draft\r\n---\r\n", ""},
+		{"閉じる---がない", "---\ntitle: Broken\n\nBody\n", "---\ntitle: Broken\n\nBody\n"},
+		{"区切り線は閉じる---とみなさない", "---\ntitle: Rule\n---\nAbove\n\n---\n\nBelow\n", "Above\n\n---\n\nBelow\n"},
+		{"先頭の行が---ではない", "Intro\n---\ntitle: x\n---\n", "Intro\n---\ntitle: x\n---\n"},
+		{"行頭の---の後に文字が続く", "----\ntitle: x\n---\n", "----\ntitle: x\n---\n"},
+	}
+	for _, tt := range tests {
+		if got := stripFrontMatter(tt.markdown); got != tt.want {
+			t.Errorf("%s: stripFrontMatter(%q) = %q, want %q", tt.name, tt.markdown, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package crawler
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SkipReason はページを収集しなかった理由
+type SkipReason string
+
+const (
+	SkipNotHTML            SkipReason = "not_html"            // HTMLではない
+	SkipOversized          SkipReason = "oversized"           // レスポンスが上限を超えた
+	SkipOffsiteRedirect    SkipReason = "offsite_redirect"    // 別のホストへリダイレクトされた
+	SkipVisitedRedirect    SkipReason = "visited_redirect"    // リダイレクト先が訪問済み
+	SkipDuplicateCanonical SkipReason = "duplicate_canonical" // 正規URLが同じページを収集済み
+	SkipRefreshStub        SkipReason = "refresh_stub"        // meta refreshによる転送ページ
+	SkipDuplicateContent   SkipReason = "duplicate_content"   // 同じ内容のページを収集済み
+	SkipNearDuplicate      SkipReason = "near_duplicate"      // ほぼ同じ内容のページを収集済み
+	SkipLanguage           SkipReason = "language"            // 指定された言語ではない
+	SkipSoft404            SkipReason = "soft_404"            // 200で返された「ページが見つかりません」のページ
+	SkipTitle              SkipReason = "title"               // タイトルが除外するパターンに一致した
+	SkipAuthDenied         SkipReason = "auth_denied"         // 401・403で拒否された
+)
+
+// skipReasons は集計するスキップ理由の一覧（表示順）
+var skipReasons = []SkipReason{
+	SkipNotHTML,
+	SkipOversized,
+	SkipOffsiteRedirect,
+	SkipVisitedRedirect,
+	SkipDuplicateCanonical,
+	SkipRefreshStub,
+	SkipDuplicateContent,
+	SkipNearDuplicate,
+	SkipLanguage,
+	SkipSoft404,
+	SkipTitle,
+	SkipAuthDenied,
+}
+
+// slowestURLCount は記録する応答の遅いURLの数
+const slowestURLCount = 5
+
+// URLTiming はURLとその応答時間
+type URLTiming struct {
+	URL      string
+	Duration time.Duration
+}
+
+// CrawlStats はクロール中の統計情報
+// クロール中にも参照できるよう、カウンタはアトミックに更新する
+type CrawlStats struct {
+	startedAt       atomic.Int64 // 開始時刻（UnixNano）
+	finishedAt      atomic.Int64 // 終了時刻（UnixNano、クロール中は0）
+	pagesFetched    atomic.Int64
+	bytesDownloaded atomic.Int64 // 展開後のボディのバイト数
+	bytesReceived   atomic.Int64 // 転送されたボディのバイト数（展開前、--max-bandwidth で制限する量）
+	responses       atomic.Int64
+	responseTime    atomic.Int64 // 応答時間の合計（ナノ秒）
+	headRequests    atomic.Int64
+	cacheHits       atomic.Int64
+	authDenied      atomic.Int64                 // 401・403が返されたリクエストの数
+	retries         atomic.Int64                 // 一時的なネットワークエラーで再試行した回数
+	waitForMissing  atomic.Int64                 // 描画の待機中に --wait-for の要素が現れなかったページの数
+	skipped         map[SkipReason]*atomic.Int64 // キーは作成時に固定するため読み書きにロックは不要
+
+	mu             sync.Mutex
+	slowest        []URLTiming     // 応答の遅い順
+	fetchDurations []time.Duration // ページごとの取得にかかった時間（ボディの読み込みまで）
+}
+
+// StatsSnapshot はある時点の統計情報
+type StatsSnapshot struct {
+	PagesFetched    int64
+	Skipped         map[SkipReason]int64
+	BytesDownloaded int64 // 展開後のボディのバイト数
+	BytesReceived   int64 // 転送されたボディのバイト数（展開前）
+	Elapsed         time.Duration
+	Responses       int64
+	AverageResponse time.Duration
+	HeadRequests    int64
+	CacheHits       int64
+	AuthDenied      int64
+	Retries         int64
+	WaitForMissing  int64 // --wait-for の要素が現れなかったページの数
+	Slowest         []URLTiming
+	FetchP50        time.Duration // ページの取得にかかった時間の中央値
+	FetchP95        time.Duration // ページの取得にかかった時間の95パーセンタイル
+}
+
+// newCrawlStats は空の統計情報を作成する
+func newCrawlStats() *CrawlStats {
+	s := &CrawlStats{skipped: make(map[SkipReason]*atomic.Int64, len(skipReasons))}
+	for _, reason := range skipReasons {
+		s.skipped[reason] = new(atomic.Int64)
+	}
+	return s
+}
+
+// start は開始時刻を記録する
+func (s *CrawlStats) start() {
+	s.startedAt.Store(time.Now().UnixNano())
+	s.finishedAt.Store(0)
+}
+
+// finish は終了時刻を記録する（記録済みの場合は何もしない）
+func (s *CrawlStats) finish() {
+	s.finishedAt.CompareAndSwap(0, time.Now().UnixNano())
+}
+
+// skip はページを収集しなかった理由を記録する
+func (s *CrawlStats) skip(reason SkipReason) {
+	s.skipped[reason].Add(1)
+}
+
+// observeResponse はレスポンスの応答時間を記録する
+func (s *CrawlStats) observeResponse(url string, d time.Duration) {
+	s.responses.Add(1)
+	s.responseTime.Add(int64(d))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.slowest) == slowestURLCount && d <= s.slowest[len(s.slowest)-1].Duration {
+		return
+	}
+	s.slowest = append(s.slowest, URLTiming{URL: url, Duration: d})
+	sort.SliceStable(s.slowest, func(i, j int) bool { return s.slowest[i].Duration > s.slowest[j].Duration })
+	if len(s.slowest) > slowestURLCount {
+		s.slowest = s.slowest[:slowestURLCount]
+	}
+}
+
+// observeFetch はページの取得（ボディの読み込みまで）にかかった時間を記録する
+func (s *CrawlStats) observeFetch(d time.Duration) {
+	s.mu.Lock()
+	s.fetchDurations = append(s.fetchDurations, d)
+	s.mu.Unlock()
+}
+
+// percentile はソート済みの時間の p パーセンタイルを返す（最近傍法）
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// Snapshot は現時点の統計情報を返す
+func (s *CrawlStats) Snapshot() StatsSnapshot {
+	snap := StatsSnapshot{
+		PagesFetched:    s.pagesFetched.Load(),
+		Skipped:         make(map[SkipReason]int64, len(s.skipped)),
+		BytesDownloaded: s.bytesDownloaded.Load(),
+		BytesReceived:   s.bytesReceived.Load(),
+		Responses:       s.responses.Load(),
+		HeadRequests:    s.headRequests.Load(),
+		CacheHits:       s.cacheHits.Load(),
+		AuthDenied:      s.authDenied.Load(),
+		Retries:         s.retries.Load(),
+		WaitForMissing:  s.waitForMissing.Load(),
+	}
+	for reason, n := range s.skipped {
+		snap.Skipped[reason] = n.Load()
+	}
+	if snap.Responses > 0 {
+		snap.AverageResponse = time.Duration(s.responseTime.Load() / snap.Responses)
+	}
+	if started := s.startedAt.Load(); started != 0 {
+		end := s.finishedAt.Load()
+		if end == 0 {
+			end = time.Now().UnixNano()
+		}
+		snap.Elapsed = time.Duration(end - started)
+	}
+
+	s.mu.Lock()
+	snap.Slowest = append([]URLTiming(nil), s.slowest...)
+	durations := append([]time.Duration(nil), s.fetchDurations...)
+	s.mu.Unlock()
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	snap.FetchP50 = percentile(durations, 50)
+	snap.FetchP95 = percentile(durations, 95)
+	return snap
+}
+
+// SkippedTotal はスキップしたページの合計を返す
+func (s StatsSnapshot) SkippedTotal() int64 {
+	var total int64
+	for _, n := range s.Skipped {
+		total += n
+	}
+	return total
+}
+
+// SkipReasons は集計するスキップ理由を表示順に返す
+func SkipReasons() []SkipReason {
+	return append([]SkipReason(nil), skipReasons...)
+}
+
+// Stats はクロール中または終了後の統計情報を返す
+func (c *Crawler) Stats() StatsSnapshot {
+	return c.stats.Snapshot()
+}
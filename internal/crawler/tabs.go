@@ -0,0 +1,158 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// TabsMode はタブで切り替える内容（npm / yarn のコード例など）の書き出し方
+type TabsMode string
+
+const (
+	TabsAll   TabsMode = "all"   // すべてのタブをタブ名の見出しに続けて書き出す
+	TabsFirst TabsMode = "first" // 選択されているタブ（なければ最初のタブ）のみを書き出す
+)
+
+// ParseTabsMode は --tabs の値を解析する
+func ParseTabsMode(s string) (TabsMode, error) {
+	switch mode := TabsMode(strings.ToLower(s)); mode {
+	case TabsAll, TabsFirst:
+		return mode, nil
+	}
+	return "", fmt.Errorf("--tabs には all または first を指定してください: %s", s)
+}
+
+// tabGroup はタブの切り替えの要素であれば、タブごとに「**タブ名**」と内容のブロックに変換する
+// Docusaurus・sphinx-tabsなどの role="tablist" / role="tabpanel" と、MkDocsの tabbed-set に対応する
+func (m *markdownConverter) tabGroup(n *html.Node) ([]mdBlock, bool) {
+	if !isTabGroup(n) {
+		return nil, false
+	}
+	var labels, panels []*html.Node
+	collectNodes(n, isTabPanel, &panels)
+	collectNodes(n, isTabLabel, &labels)
+	if len(panels) == 0 {
+		return nil, false
+	}
+
+	selected := 0
+	for i, label := range labels {
+		if isSelectedTab(n, label) {
+			selected = i
+			break
+		}
+	}
+
+	var blocks []mdBlock
+	for i, panel := range panels {
+		if m.tabsMode == TabsFirst && i != selected {
+			continue
+		}
+		name := fmt.Sprintf("Tab %d", i+1)
+		if label := tabLabelFor(panel, labels, i); label != nil {
+			if text := strings.ReplaceAll(m.inlineText(label), "\\\n", " "); text != "" {
+				name = text
+			}
+		}
+		blocks = append(blocks, mdBlock{text: "**" + name + "**"})
+		blocks = append(blocks, m.blocks(panel)...)
+	}
+	return blocks, true
+}
+
+// isTabGroup は要素がタブの切り替えの囲みかを判定する（子に role="tablist" がある要素か、MkDocsの tabbed-set）
+func isTabGroup(n *html.Node) bool {
+	if hasClass(n, "tabbed-set") {
+		return true
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && attr(c, "role") == "tablist" {
+			return true
+		}
+	}
+	return false
+}
+
+// isTabPanel はタブの内容の要素かを判定する
+// MkDocsの旧形式では tabbed-content が、新形式ではその中の tabbed-block が1つのタブの内容になる
+func isTabPanel(n *html.Node) bool {
+	if attr(n, "role") == "tabpanel" || hasClass(n, "tabbed-block") {
+		return true
+	}
+	if !hasClass(n, "tabbed-content") {
+		return false
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && hasClass(c, "tabbed-block") {
+			return false
+		}
+	}
+	return true
+}
+
+// isTabLabel はタブ名の要素かを判定する（MkDocsではlabel要素）
+func isTabLabel(n *html.Node) bool {
+	return attr(n, "role") == "tab" || (n.Data == "label" && attr(n, "for") != "")
+}
+
+// collectNodes は条件に一致する要素を文書の順に集める（一致した要素とタブの内容の中はたどらない）
+func collectNodes(n *html.Node, match func(*html.Node) bool, found *[]*html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if match(c) {
+			*found = append(*found, c)
+			continue
+		}
+		if isTabPanel(c) {
+			continue
+		}
+		collectNodes(c, match, found)
+	}
+}
+
+// isSelectedTab はタブ名の要素が選択されているタブかを判定する
+func isSelectedTab(group, label *html.Node) bool {
+	if attr(label, "aria-selected") == "true" {
+		return true
+	}
+	for _, class := range strings.Fields(attr(label, "class")) {
+		if class == "active" || strings.HasSuffix(class, "--active") || strings.HasSuffix(class, "-active") {
+			return true
+		}
+	}
+	// MkDocsはlabel要素に対応するラジオボタンの checked で選択されているタブを表す
+	if id := attr(label, "for"); id != "" {
+		var inputs []*html.Node
+		collectNodes(group, func(c *html.Node) bool { return c.Data == "input" && attr(c, "id") == id }, &inputs)
+		return len(inputs) > 0 && hasAttr(inputs[0], "checked")
+	}
+	return false
+}
+
+// tabLabelFor はタブの内容に対応するタブ名の要素を返す（aria-controls・aria-labelledby で対応付け、なければ同じ順番のもの）
+func tabLabelFor(panel *html.Node, labels []*html.Node, i int) *html.Node {
+	id, labelledBy := attr(panel, "id"), attr(panel, "aria-labelledby")
+	for _, label := range labels {
+		if (id != "" && attr(label, "aria-controls") == id) || (labelledBy != "" && attr(label, "id") == labelledBy) {
+			return label
+		}
+	}
+	if i < len(labels) {
+		return labels[i]
+	}
+	return nil
+}
+
+// hasClass は要素のクラスに name が含まれるかを判定する
+func hasClass(n *html.Node, name string) bool {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if class == name {
+			return true
+		}
+	}
+	return false
+}
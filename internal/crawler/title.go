@@ -0,0 +1,14 @@
+package crawler
+
+import "strings"
+
+// skipsTitle はタイトルが --skip-title-regex のいずれかのパターンに一致するかを判定する
+func (c *Crawler) skipsTitle(title string) bool {
+	title = strings.TrimSpace(title)
+	for _, re := range c.skipTitlePatterns {
+		if re.MatchString(title) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// newHTTPClient はクロール全体で共有するHTTPクライアントを作成する
+// custom が指定されている場合はそのコピーを使い、Cookieジャーが未設定であれば補う
+func (c *Crawler) newHTTPClient(custom *http.Client) *http.Client {
+	// Set-Cookieをクロール全体で引き継ぐためのCookieジャー
+	var jar http.CookieJar
+	jar, _ = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+
+	if custom != nil {
+		client := *custom
+		if client.Jar == nil {
+			client.Jar = jar
+		}
+		if c.sessionFile != "" {
+			client.Jar = newRecordingJar(client.Jar)
+		}
+		c.jar = client.Jar
+		return &client
+	}
+
+	// セッションファイルに保存するため、設定されたCookieを記録する
+	if c.sessionFile != "" {
+		jar = newRecordingJar(jar)
+	}
+	c.jar = jar
+	var transport http.RoundTripper = &throttledTransport{limiter: c.bandwidth, received: &c.stats.bytesReceived, next: c.newTransport()}
+	if c.local != nil {
+		transport = &localTransport{site: c.local, next: transport}
+	}
+	return &http.Client{
+		Timeout:   time.Duration(c.timeout) * time.Second,
+		Jar:       jar,
+		Transport: transport,
+	}
+}
+
+// newTransport はプロキシ設定を反映したTransportを作成する
+// プロキシが指定されていない場合は HTTP_PROXY / HTTPS_PROXY / NO_PROXY 環境変数に従う
+func (c *Crawler) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	switch {
+	case c.proxyURL != nil:
+		transport.Proxy = http.ProxyURL(c.proxyURL)
+	case c.noEnvProxy:
+		transport.Proxy = nil
+	default:
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	return transport
+}
+
+// wrapProxyError はプロキシへの接続で発生したエラーを対象サイトのエラーと区別できるようにする
+func (c *Crawler) wrapProxyError(err error) error {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return err
+	}
+	if opErr.Op != "proxyconnect" && !strings.HasPrefix(opErr.Op, "socks") {
+		return err
+	}
+
+	proxy := "環境変数のプロキシ"
+	if c.proxyURL != nil {
+		proxy = c.proxyURL.Redacted()
+	}
+	return fmt.Errorf("プロキシ (%s) への接続に失敗しました: %w", proxy, err)
+}
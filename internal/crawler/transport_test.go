@@ -0,0 +1,51 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCrawlReusesConnections(t *testing.T) {
+	const n = 50
+	var conns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/p/"))
+		if err != nil || i >= n {
+			http.NotFound(w, r)
+			return
+		}
+		var links []string
+		if i+1 < n {
+			links = append(links, fmt.Sprintf("/p/%d", i+1))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, htmlPage(fmt.Sprintf("Page %d", i), fmt.Sprintf("page number %d", i), links...))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	c := New(server.URL+"/p/0", n, 10, 0, 0, WithLogger(discardLogger()))
+	result, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if len(result.Pages) != n {
+		t.Fatalf("取得したページ数 = %d, want %d", len(result.Pages), n)
+	}
+	// 接続を使い回していれば、ページ数に関係なく接続はほとんど増えない
+	if got := conns.Load(); got > 2 {
+		t.Errorf("%d ページの取得で %d 回接続しました", n, got)
+	}
+}
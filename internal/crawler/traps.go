@@ -0,0 +1,132 @@
+package crawler
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultTrapMaxRepeats は1つのURLで同じパスの要素が繰り返してよい回数のデフォルト値
+	DefaultTrapMaxRepeats = 3
+	// DefaultTrapMaxVariants は数字やクエリの値だけが異なるURLが伸び続けてよい回数のデフォルト値
+	DefaultTrapMaxVariants = 200
+)
+
+// trapDetector はファセット検索やカレンダーのように際限なくURLを生成するクローラートラップを検出する
+type trapDetector struct {
+	maxRepeats  int // 同じパスの要素の繰り返しの上限（0以下は判定しない）
+	maxVariants int // 同じパターンのURLが伸び続けてよい回数の上限（0以下は判定しない）
+
+	mu         sync.Mutex
+	longest    map[string]int  // パターンごとのURLの最大の長さ
+	growth     map[string]int  // パターンごとのURLが最大の長さを更新した回数
+	suppressed map[string]bool // 追加を止めたパターン
+}
+
+// newTrapDetector は新しいtrapDetectorを作成する
+func newTrapDetector(maxRepeats, maxVariants int) *trapDetector {
+	return &trapDetector{
+		maxRepeats:  maxRepeats,
+		maxVariants: maxVariants,
+		longest:     make(map[string]int),
+		growth:      make(map[string]int),
+		suppressed:  make(map[string]bool),
+	}
+}
+
+// check は未訪問のURLがトラップに該当するかを判定し、該当する場合は抑止したパターンを返す
+// 初めて抑止したパターンの場合は first が true になる
+func (d *trapDetector) check(rawURL string) (pattern string, first bool, trapped bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false, false
+	}
+
+	// /tag/a/tag/b/tag/c/... のように同じ要素が繰り返すURL
+	if d.maxRepeats > 0 {
+		counts := make(map[string]int)
+		for _, s := range pathSegments(u.Path) {
+			counts[s]++
+			if counts[s] > d.maxRepeats {
+				pattern = u.Host + ": /" + s + "/ の繰り返し"
+				return pattern, d.suppress(pattern), true
+			}
+		}
+	}
+
+	// 数字の要素やクエリの値だけが異なり、際限なく伸び続けるURL（ファセット検索など）のパターン
+	// 同じ長さの兄弟ページ（/errors/1001 や ?title=Foo）はいくつあっても伸びたとは数えないため、
+	// 通常のドキュメントの階層では該当しない
+	if d.maxVariants > 0 {
+		pattern = urlPattern(u)
+		length := len(u.EscapedPath()) + len(u.RawQuery)
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.suppressed[pattern] {
+			return pattern, false, true
+		}
+		prev, seen := d.longest[pattern]
+		if seen && length <= prev {
+			return "", false, false
+		}
+		d.longest[pattern] = length
+		if !seen {
+			return "", false, false
+		}
+		d.growth[pattern]++
+		if d.growth[pattern] > d.maxVariants {
+			d.suppressed[pattern] = true
+			return pattern, true, true
+		}
+	}
+	return "", false, false
+}
+
+// suppress はパターンを抑止済みにし、初めて抑止した場合は true を返す
+func (d *trapDetector) suppress(pattern string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.suppressed[pattern] {
+		return false
+	}
+	d.suppressed[pattern] = true
+	return true
+}
+
+// urlPattern は数字だけのパスの要素とクエリの値を * に置き換えたURLのパターンを返す
+// 同じ名前のクエリは1つにまとめるため、?tag=a&tag=b のように値が増えていくURLも同じパターンになる
+func urlPattern(u *url.URL) string {
+	segments := pathSegments(u.Path)
+	for i, s := range segments {
+		if isDigits(s) {
+			segments[i] = "*"
+		}
+	}
+	pattern := u.Host + "/" + strings.Join(segments, "/")
+	if u.RawQuery != "" {
+		var names []string
+		for name := range u.Query() {
+			names = append(names, name+"=*")
+		}
+		sort.Strings(names)
+		pattern += "?" + strings.Join(names, "&")
+	}
+	return pattern
+}
+
+// isDigits は文字列が数字（と日付の区切りの - や _）だけからなるかを判定する
+func isDigits(s string) bool {
+	hasDigit := false
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r == '-' || r == '_':
+		default:
+			return false
+		}
+	}
+	return hasDigit
+}
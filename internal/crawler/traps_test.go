@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTrapDetectorRepeatedSegment(t *testing.T) {
+	d := newTrapDetector(DefaultTrapMaxRepeats, DefaultTrapMaxVariants)
+	if _, _, trapped := d.check("https://example.com/docs/tag/a/tag/b/tag/c"); trapped {
+		t.Error("tag が3回のURLがトラップと判定されました")
+	}
+	pattern, first, trapped := d.check("https://example.com/docs/tag/a/tag/b/tag/c/tag/d")
+	if !trapped || !first {
+		t.Fatalf("tag が4回のURL: trapped = %v, first = %v, want true, true", trapped, first)
+	}
+	if _, first, _ := d.check("https://example.com/docs/tag/x/tag/y/tag/z/tag/w"); first {
+		t.Errorf("抑止済みのパターン %q で first = true", pattern)
+	}
+}
+
+func TestTrapDetectorGrowingQuery(t *testing.T) {
+	d := newTrapDetector(DefaultTrapMaxRepeats, 5)
+	var tags []string
+	for i := 0; i < 20; i++ {
+		tags = append(tags, fmt.Sprintf("tag=t%d", i))
+		rawURL := "https://example.com/search?" + strings.Join(tags, "&")
+		_, _, trapped := d.check(rawURL)
+		if want := i > 5; trapped != want {
+			t.Fatalf("%d個目のURL %s: trapped = %v, want %v", i+1, rawURL, trapped, want)
+		}
+	}
+}
+
+func TestTrapDetectorSiblingPages(t *testing.T) {
+	d := newTrapDetector(DefaultTrapMaxRepeats, DefaultTrapMaxVariants)
+	for i := 0; i < 500; i++ {
+		for _, rawURL := range []string{
+			fmt.Sprintf("https://example.com/docs/guide/page-%d", i),
+			fmt.Sprintf("https://example.com/errors/%d", 1000+i),
+			fmt.Sprintf("https://example.com/index.php?title=Page_%d", i),
+			fmt.Sprintf("https://example.com/docs?page=%d&lang=en", i),
+		} {
+			if pattern, _, trapped := d.check(rawURL); trapped {
+				t.Fatalf("兄弟ページ %s がトラップと判定されました（パターン %s）", rawURL, pattern)
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package crawler
+
+// ListedURL はURLリストファイルの1行
+type ListedURL struct {
+	URL  string
+	Line int // ファイル内の行番号（1始まり）
+}
+
+// enqueueURLList はURLリストのURLをファイルの順に深度0としてキューに追加する
+// 開始URLの範囲外のURLは行番号とともに警告してスキップする
+func (c *Crawler) enqueueURLList() {
+	for _, entry := range c.urlList {
+		seed := c.seedFor(entry.URL)
+		if seed == "" {
+			c.logger.Warn("開始URLの範囲外のためスキップします", "line", entry.Line, "url", entry.URL)
+			continue
+		}
+		c.enqueueNew(crawlItem{url: entry.URL, depth: 0, seed: seed})
+	}
+}
+
+// discoversLinks はページ内のリンクをたどるかどうかを返す
+// サイトマップのみのモード、URLリストのモード、失敗したURLの再取得ではリンクをたどらない
+func (c *Crawler) discoversLinks() bool {
+	return !c.sitemapOnly && c.urlList == nil && c.retryFailed == nil
+}
@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+)
+
+// variantQueryParams は同じページの印刷用・AMP・モバイル版を示すクエリパラメータ
+var variantQueryParams = []string{"print", "printable", "amp", "mobile", "output"}
+
+// variantPathSegments は同じページの印刷用・AMP版を示すパス要素
+var variantPathSegments = []string{"amp", "print", "printable"}
+
+// variantQueryValues は output= などの値のうち別形式を示すもの
+var variantQueryValues = map[string][]string{
+	"output": {"amp", "print"},
+}
+
+// isVariantURL はURLが同じページの印刷用・AMP・モバイル版かどうかを判定する
+func isVariantURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	query := u.Query()
+	for _, param := range variantQueryParams {
+		if !query.Has(param) {
+			continue
+		}
+		values, restricted := variantQueryValues[param]
+		if !restricted {
+			return true
+		}
+		for _, v := range values {
+			if strings.EqualFold(query.Get(param), v) {
+				return true
+			}
+		}
+	}
+
+	for _, segment := range strings.Split(u.Path, "/") {
+		for _, variant := range variantPathSegments {
+			if strings.EqualFold(segment, variant) {
+				return true
+			}
+		}
+	}
+	return false
+}
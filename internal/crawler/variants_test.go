@@ -0,0 +1,33 @@
+package crawler
+
+import "testing"
+
+func TestIsVariantURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://docs.example.com/guide/intro", want: false},
+		{url: "https://docs.example.com/guide/intro?print=1", want: true},
+		{url: "https://docs.example.com/guide/intro?printable", want: true},
+		{url: "https://docs.example.com/guide/intro?amp", want: true},
+		{url: "https://docs.example.com/guide/intro?mobile=true", want: true},
+		{url: "https://docs.example.com/guide/intro?output=amp", want: true},
+		{url: "https://docs.example.com/guide/intro?output=PRINT", want: true},
+		{url: "https://docs.example.com/guide/intro?output=json", want: false},
+		{url: "https://docs.example.com/amp/guide/intro", want: true},
+		{url: "https://docs.example.com/guide/intro/print", want: true},
+		{url: "https://docs.example.com/guide/intro/Printable/", want: true},
+		// パス要素の一部に含まれるだけの場合やフラグメントは別形式ではない
+		{url: "https://docs.example.com/guide/amplifier", want: false},
+		{url: "https://docs.example.com/guide/printing-options", want: false},
+		{url: "https://docs.example.com/guide/intro?page=print", want: false},
+		{url: "https://docs.example.com/guide/intro#print", want: false},
+		{url: "://invalid", want: false},
+	}
+	for _, tt := range tests {
+		if got := isVariantURL(tt.url); got != tt.want {
+			t.Errorf("isVariantURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
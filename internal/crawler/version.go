@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// versionPattern はバージョン付きドキュメントのURLのバージョンを表すパスの要素（v2、v1.4、2.3.x、latest など）
+var versionPattern = regexp.MustCompile(`^(?i:v\d+(\.\d+)*(\.x)?|\d+\.\d+(\.\d+)*(\.x)?|latest|stable|next)$`)
+
+// versionSearchSegments はバージョンを探すパスの先頭からの要素数（/en/v2.3/ のように言語の後に置かれることが多い）
+const versionSearchSegments = 3
+
+// latestVersionNames はバージョンの切り替えで最新版を指す名前（優先する順）
+var latestVersionNames = []string{"latest", "stable"}
+
+// versionSegment はURLのパスに含まれるバージョンの要素とその位置を返す（含まれない場合は空と-1）
+func versionSegment(rawURL string) (string, int) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", -1
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, segment := range segments {
+		if i >= versionSearchSegments {
+			break
+		}
+		if versionPattern.MatchString(segment) {
+			return segment, i
+		}
+	}
+	return "", -1
+}
+
+// replaceVersion はURLのバージョンの要素を version に置き換える（バージョンを含まないURLはそのまま）
+func replaceVersion(rawURL, version string) string {
+	_, index := versionSegment(rawURL)
+	if index < 0 {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	segments := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	segments[index] = version
+	u.Path = "/" + strings.Join(segments, "/")
+	u.RawPath = ""
+	return u.String()
+}
+
+// matchesVersion はURLが対象のバージョンのページかどうかを判定する
+// バージョンを含まないURL（検索ページなど）とバージョンが未確定の場合は対象とする
+func (c *Crawler) matchesVersion(rawURL string) bool {
+	c.mu.Lock()
+	version := c.docVersion
+	c.mu.Unlock()
+	if version == "" {
+		return true
+	}
+	segment, _ := versionSegment(rawURL)
+	return segment == "" || strings.EqualFold(segment, version)
+}
+
+// DocVersion は対象としたドキュメントのバージョンを返す（バージョンで絞り込まない場合は空）
+func (c *Crawler) DocVersion() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.docVersion
+}
+
+// detectVersion は最初に取得した開始ページから対象のバージョンを決める
+// リダイレクト後のURL、正規URL、バージョンの切り替えのlatest・stableへのリンクの順に探す
+func (c *Crawler) detectVersion(doc *goquery.Document, pageURL, canonicalURL string) {
+	if !c.latestOnly {
+		return
+	}
+	c.mu.Lock()
+	decided := c.versionDecided
+	c.versionDecided = true
+	c.mu.Unlock()
+	if decided {
+		return
+	}
+
+	version, _ := versionSegment(pageURL)
+	if version == "" && canonicalURL != "" {
+		version, _ = versionSegment(canonicalURL)
+	}
+	if version == "" {
+		found := make(map[string]bool)
+		doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+			href, _ := s.Attr("href")
+			link, err := resolveURL(pageURL, href)
+			if err != nil || !sameSite(pageURL, link) {
+				return
+			}
+			if segment, _ := versionSegment(link); segment != "" {
+				found[strings.ToLower(segment)] = true
+			}
+		})
+		for _, name := range latestVersionNames {
+			if found[name] {
+				version = name
+				break
+			}
+		}
+	}
+	if version == "" {
+		c.logger.Warn("ドキュメントのバージョンを判定できないため、すべてのバージョンをクロールします", "url", pageURL)
+		return
+	}
+
+	c.mu.Lock()
+	c.docVersion = version
+	c.mu.Unlock()
+	c.logger.Info("ドキュメントのバージョンを絞り込みます", "version", version)
+}
@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// isHidden は要素が画面に表示されない要素（hidden 属性（until-found を除く）、aria-hidden="true"、style の display: none）かを判定する
+// 折りたたまれた details の中身とタブの内容は閉じていても本文として書き出すため、表示されない要素とみなさない
+func isHidden(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Data == "details" || isTabPanel(n) {
+		return false
+	}
+	hidden := hasAttr(n, "hidden") && attr(n, "hidden") != "until-found"
+	if !hidden && attr(n, "aria-hidden") != "true" && !hasDisplayNone(attr(n, "style")) {
+		return false
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "details" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasDisplayNone はインラインのスタイルに display: none（!important 付きを含む）の指定があるかを判定する
+func hasDisplayNone(style string) bool {
+	for _, decl := range strings.Split(style, ";") {
+		prop, value, ok := strings.Cut(decl, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(prop), "display") {
+			continue
+		}
+		value = strings.TrimSpace(strings.ToLower(value))
+		value = strings.TrimSpace(strings.TrimSuffix(value, "!important"))
+		if value == "none" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractTextSkipsHiddenElements(t *testing.T) {
+	c := New("https://docs.example.com/", 3, 10, 0, 0, WithLogger(discardLogger()))
+	got := convertFixture(t, c, filepath.Join("testdata", "content", "hidden-nav.html"))
+
+	// display: none（!important、空白、大文字を含む）・hidden 属性・aria-hidden="true" の要素は書き出さない
+	for _, unwanted := range []string{"Mobile menu", "Screen reader trail", "Prefetched search dialog", "Collapsed table of contents"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("表示されない要素の %q が出力されています:\n%s", unwanted, got)
+		}
+	}
+	// タブの内容・details の中身・hidden="until-found" の要素は閉じていても書き出す
+	for _, want := range []string{
+		"Install the agent on every host",
+		"Run the Linux installer.",
+		"Run the Windows installer.",
+		"Restart the agent service.",
+		"Found-in-page appendix.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q が出力に含まれていません:\n%s", want, got)
+		}
+	}
+}
+
+func TestHasDisplayNone(t *testing.T) {
+	for style, want := range map[string]bool{
+		"display:none":                      true,
+		"display : none !important":         true,
+		"color: red; DISPLAY: NONE":         true,
+		"display: none!important; width: 0": true,
+		"display: block":                    false,
+		"visibility: hidden":                false,
+		"":                                  false,
+	} {
+		if got := hasDisplayNone(style); got != want {
+			t.Errorf("hasDisplayNone(%q) = %v, want %v", style, got, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package crawler
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// visitedShardCount は訪問済みURLの集合を分割する数
+const visitedShardCount = 64
+
+// visitedSet は訪問済みURLの集合
+// URL全体ではなく64ビットのハッシュを保持してメモリを節約し、分割した集合ごとのロックで並行に参照できるようにする
+// 異なるURLのハッシュが衝突する確率は100万URLでも約 2.7×10^-8 と実用上無視できるが、
+// exact の場合はURL全体も保持して厳密に判定する
+type visitedSet struct {
+	exact  bool
+	shards [visitedShardCount]visitedShard
+}
+
+// visitedShard は分割した集合の1つ
+type visitedShard struct {
+	mu     sync.Mutex
+	hashes map[uint64]struct{} // ハッシュのみで記録したURL（exact の場合はチェックポイントから復元したもののみ）
+	urls   map[string]struct{} // exact の場合に記録したURL
+}
+
+// newVisitedSet は空の訪問済みURLの集合を作成する
+func newVisitedSet(exact bool) *visitedSet {
+	s := &visitedSet{exact: exact}
+	for i := range s.shards {
+		s.shards[i].hashes = make(map[uint64]struct{})
+		if exact {
+			s.shards[i].urls = make(map[string]struct{})
+		}
+	}
+	return s
+}
+
+// hashURL はURLの64ビットのハッシュを返す
+func hashURL(url string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(url))
+	return h.Sum64()
+}
+
+// shard はハッシュに対応する分割した集合を返す
+func (s *visitedSet) shard(h uint64) *visitedShard {
+	return &s.shards[h%visitedShardCount]
+}
+
+// contains はURLが訪問済みかを返す
+func (s *visitedSet) contains(url string) bool {
+	h := hashURL(url)
+	sh := s.shard(h)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.has(url, h)
+}
+
+// add はURLを訪問済みにする（既に訪問済みだった場合は false を返す）
+func (s *visitedSet) add(url string) bool {
+	h := hashURL(url)
+	sh := s.shard(h)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if sh.has(url, h) {
+		return false
+	}
+	if s.exact {
+		sh.urls[url] = struct{}{}
+	} else {
+		sh.hashes[h] = struct{}{}
+	}
+	return true
+}
+
+// addHash はハッシュのみで訪問済みのURLを記録する（チェックポイントからの復元用）
+func (s *visitedSet) addHash(h uint64) {
+	sh := s.shard(h)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.hashes[h] = struct{}{}
+}
+
+// has はURLが記録済みかを返す（呼び出し側でロックを取得すること）
+func (sh *visitedShard) has(url string, h uint64) bool {
+	if _, ok := sh.urls[url]; ok {
+		return true
+	}
+	_, ok := sh.hashes[h]
+	return ok
+}
+
+// entries は記録済みのURLとハッシュのみで記録したURLをすべて返す
+func (s *visitedSet) entries() (urls []string, hashes []uint64) {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		for u := range sh.urls {
+			urls = append(urls, u)
+		}
+		for h := range sh.hashes {
+			hashes = append(hashes, h)
+		}
+		sh.mu.Unlock()
+	}
+	return urls, hashes
+}
@@ -0,0 +1,89 @@
+package crawler
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVisitedSetAdd(t *testing.T) {
+	for _, exact := range []bool{false, true} {
+		s := newVisitedSet(exact)
+		if !s.add("https://example.com/a") {
+			t.Fatalf("exact=%v: 初回の追加が false", exact)
+		}
+		if s.add("https://example.com/a") {
+			t.Errorf("exact=%v: 2回目の追加が true", exact)
+		}
+		if !s.contains("https://example.com/a") || s.contains("https://example.com/b") {
+			t.Errorf("exact=%v: contains の結果が不正", exact)
+		}
+	}
+}
+
+func BenchmarkVisitedAdd(b *testing.B) {
+	s := newVisitedSet(false)
+	urls := benchmarkURLs(b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.add(urls[i])
+	}
+}
+
+func BenchmarkVisitedAddExact(b *testing.B) {
+	s := newVisitedSet(true)
+	urls := benchmarkURLs(b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.add(urls[i])
+	}
+}
+
+func BenchmarkVisitedAddParallel(b *testing.B) {
+	s := newVisitedSet(false)
+	urls := benchmarkURLs(b.N)
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.add(urls[int(next.Add(1)-1)%len(urls)])
+		}
+	})
+}
+
+func BenchmarkVisitedContainsParallel(b *testing.B) {
+	s := newVisitedSet(false)
+	urls := benchmarkURLs(10000)
+	for _, u := range urls {
+		s.add(u)
+	}
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.contains(urls[int(next.Add(1)-1)%len(urls)])
+		}
+	})
+}
+
+// BenchmarkVisitedEnqueueParallel はキューへの追加（上限の確認と訪問済みの判定）を並行に行う
+func BenchmarkVisitedEnqueueParallel(b *testing.B) {
+	c := New("https://example.com/", 100, 1, 0, 0)
+	urls := benchmarkURLs(b.N)
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.enqueueNew(crawlItem{url: urls[int(next.Add(1)-1)%len(urls)]})
+		}
+	})
+}
+
+// benchmarkURLs はベンチマーク用の異なるURLを n 個作成する
+func benchmarkURLs(n int) []string {
+	urls := make([]string, max(n, 1))
+	for i := range urls {
+		urls[i] = "https://example.com/docs/page-" + strconv.Itoa(i)
+	}
+	return urls
+}
@@ -0,0 +1,60 @@
+// Package document はクロール結果のページを出力形式の間で共有する型を提供する
+// output パッケージと内部のPDFジェネレーターが同じ型を使うことで、ページの変換を1か所にまとめる
+package document
+
+import "time"
+
+// Page はクロールされたページの情報
+type Page struct {
+	URL           string    `json:"url"`
+	RequestedURL  string    `json:"requested_url,omitempty"` // リダイレクトされた場合のリクエスト時のURL
+	Title         string    `json:"title"`
+	Content       string    `json:"content"` // 本文をMarkdownに変換したテキスト
+	Depth         int       `json:"depth"`
+	Seed          string    `json:"seed,omitempty"` // このページに到達した開始URL
+	StatusCode    int       `json:"status_code"`
+	FetchedAt     time.Time `json:"fetched_at"`                // レスポンスを受信した日時
+	LastModified  time.Time `json:"last_modified,omitempty"`   // Last-Modifiedヘッダーの日時（ない場合はゼロ値）
+	CanonicalURL  string    `json:"canonical_url,omitempty"`   // <link rel="canonical">で宣言された正規URL（自身と同じ場合は空）
+	SourceEditURL string    `json:"source_edit_url,omitempty"` // 「このページを編集」リンクのURL
+	FromSource    bool      `json:"from_source,omitempty"`     // 元のMarkdownソースをそのままコンテンツとして使用したか
+	AliasURLs     []string  `json:"alias_urls,omitempty"`      // 同じ内容で提供されている別のURL
+	ChangeStatus  string    `json:"change_status,omitempty"`   // 前回のクロールからの変化（added / changed / unchanged、比較しない場合は空）
+	FromPDF       bool      `json:"from_pdf,omitempty"`        // リンク先のPDFから抽出したページか
+	AssetPath     string    `json:"asset_path,omitempty"`      // 保存したPDFのパス
+	Headings      []Heading `json:"headings,omitempty"`        // 本文の見出しとアンカー（文書の順）
+	Description   string    `json:"description,omitempty"`     // meta description（なければ og:description）
+	OGTitle       string    `json:"og_title,omitempty"`        // og:title
+	ModifiedAt    time.Time `json:"modified_at,omitempty"`     // article:modified_time の日時（ない場合はゼロ値）
+	Breadcrumbs   []string  `json:"breadcrumbs,omitempty"`     // パンくずリストの項目名（上位の階層から順）
+
+	Outline []*HeadingNode `json:"outline,omitempty"` // Headings を階層にした目次
+	Section []string       `json:"section,omitempty"` // セクションごとにまとめた場合の属するセクションの項目名（ルートからの順、output.Section.AllPages で設定する）
+}
+
+// Heading は本文の見出し
+type Heading struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`
+}
+
+// HeadingNode は見出しの階層（目次）の1項目
+type HeadingNode struct {
+	Heading
+	Depth    int            `json:"depth"` // 階層の深さ（最上位は1）
+	Children []*HeadingNode `json:"children,omitempty"`
+}
+
+// CrawlMeta はクロール全体に関するメタ情報
+type CrawlMeta struct {
+	BaseURL    string    // クローリング開始URL
+	SeedURLs   []string  // 開始URLが複数ある場合のすべての開始URL
+	StartedAt  time.Time // クローリング開始日時
+	FinishedAt time.Time // クローリング終了日時
+	PageCount  int       // 取得ページ数
+	DocVersion string    // 対象としたドキュメントのバージョン（絞り込まない場合は空）
+	AcceptLang string    // リクエストに設定したAccept-Language（取得した言語の版を示す）
+
+	RemovedURLs []string // 前回のクロールから削除されたページのURL（比較しない場合は空）
+}
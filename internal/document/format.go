@@ -0,0 +1,105 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yugo-ibuki/docrawl/internal/crawler"
+)
+
+// CleanupText はテキストコンテンツを整形する
+// 改行コードの統一、行末の空白の削除、連続する空行の削除を行う（コードブロックの中の行はそのまま残す）
+func CleanupText(content string) string {
+	// 改行を統一（Windowsの CRLF を LF に変換）
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	lines := strings.Split(content, "\n")
+	code := crawler.CodeFenceLines(lines)
+
+	// 行末の空白を削除し、連続する空行を最大1行に制限
+	var result []string
+	prevEmpty := false
+
+	for i, line := range lines {
+		if code[i] {
+			result = append(result, line)
+			prevEmpty = false
+			continue
+		}
+		line = strings.TrimRight(line, " \t")
+		isEmpty := line == ""
+
+		if isEmpty && (prevEmpty || len(result) == 0) {
+			// 連続する空行と先頭の空行をスキップ
+			continue
+		}
+
+		result = append(result, line)
+		prevEmpty = isEmpty
+	}
+
+	// 末尾の空行を削除
+	for len(result) > 0 && strings.TrimSpace(result[len(result)-1]) == "" {
+		result = result[:len(result)-1]
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// OutlineLines は見出しの階層を深さに応じて字下げした「- 見出し (#アンカー)」の行にする（最上位の見出しは字下げしない）
+func OutlineLines(nodes []*HeadingNode) []string {
+	var lines []string
+	for _, node := range nodes {
+		lines = append(lines, fmt.Sprintf("%s- %s (#%s)", strings.Repeat("  ", node.Depth-1), node.Text, node.Anchor))
+		lines = append(lines, OutlineLines(node.Children)...)
+	}
+	return lines
+}
+
+// FormatTime は日時を表示用に整形する（utc が false の場合はローカルタイムゾーン）
+func FormatTime(t time.Time, utc bool) string {
+	if utc {
+		t = t.UTC()
+	} else {
+		t = t.Local()
+	}
+	return t.Format("2006-01-02 15:04:05 MST")
+}
+
+// PDFSource はPDFから抽出したページの元のファイル（保存先、保存していない場合はURL）を返す
+func PDFSource(page Page) string {
+	if page.AssetPath != "" {
+		return page.AssetPath
+	}
+	return page.URL
+}
+
+// ProvenanceBlock はクロール結果の出所（開始URL、取得日時、ページ数）を示すヘッダーを返す
+// utc が true の場合は取得日時をUTCで表示する
+func ProvenanceBlock(meta CrawlMeta, utc bool) string {
+	fetchedAt := meta.FinishedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# クロール結果\n")
+	if len(meta.SeedURLs) > 1 {
+		fmt.Fprintf(&sb, "# 開始URL: %s\n", strings.Join(meta.SeedURLs, ", "))
+	} else {
+		fmt.Fprintf(&sb, "# 開始URL: %s\n", meta.BaseURL)
+	}
+	fmt.Fprintf(&sb, "# 取得日時: %s\n", FormatTime(fetchedAt, utc))
+	fmt.Fprintf(&sb, "# 取得ページ数: %d\n", meta.PageCount)
+	if meta.AcceptLang != "" {
+		fmt.Fprintf(&sb, "# Accept-Language: %s\n", meta.AcceptLang)
+	}
+	if meta.DocVersion != "" {
+		fmt.Fprintf(&sb, "# バージョン: %s\n", meta.DocVersion)
+	}
+	for _, u := range meta.RemovedURLs {
+		fmt.Fprintf(&sb, "# 削除されたページ: %s\n", u)
+	}
+	return sb.String()
+}
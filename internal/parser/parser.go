@@ -2,156 +2,125 @@ package parser
 
 import (
 	"strings"
+	"unicode"
 
 	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
 )
 
-// ExtractMainContent はHTMLドキュメントからメインコンテンツを抽出する
-func ExtractMainContent(doc *goquery.Document) string {
-	// メインコンテンツの可能性が高い要素のセレクタ
-	// これはサイトによって調整が必要
-	selectors := []string{
-		"main", "article", ".content", ".documentation", ".docs-content",
-		"#content", "#main-content", ".main-content", ".article-content",
-	}
+// mainContentSelectors はメインコンテンツの可能性が高い要素のセレクタ
+// これはサイトによって調整が必要
+var mainContentSelectors = []string{
+	"main", "article", ".content", ".documentation", ".docs-content",
+	"#content", "#main-content", ".main-content", ".article-content",
+}
 
-	for _, selector := range selectors {
-		selection := doc.Find(selector)
-		if selection.Length() > 0 {
-			// 見つかった最初の要素を使用
-			html, err := selection.First().Html()
-			if err == nil && len(html) > 0 {
-				return cleanHTML(html)
-			}
+// MainContent はHTMLドキュメントのメインコンテンツの要素を推定する
+// 候補のセレクタに一致する内容のある最初の要素、なければbody、それもなければドキュメント全体を返す
+// score が true の場合は、bodyの代わりに本文らしさの点数が最も高い要素を使う（見つからなければbody）
+func MainContent(doc *goquery.Document, score bool) *goquery.Selection {
+	for _, selector := range mainContentSelectors {
+		// 見つかった最初の要素を使用
+		selection := doc.Find(selector).First()
+		if selection.Length() > 0 && strings.TrimSpace(selection.Text()) != "" {
+			return selection
 		}
 	}
 
-	// 特定のセレクタが見つからない場合は、bodyコンテンツを使用
-	body := doc.Find("body")
-	if body.Length() > 0 {
-		html, err := body.Html()
-		if err == nil {
-			return cleanHTML(html)
+	if score {
+		if best := scoreContent(doc); best != nil {
+			return best
 		}
 	}
 
-	// 最終手段としてHTMLドキュメント全体を返す
-	html, err := doc.Html()
-	if err == nil {
-		return cleanHTML(html)
+	// 特定のセレクタが見つからない場合は、bodyコンテンツを使用
+	if body := doc.Find("body"); body.Length() > 0 {
+		return body
 	}
 
-	return ""
+	// 最終手段としてHTMLドキュメント全体を返す
+	return doc.Selection
 }
 
-// cleanHTML はHTMLをクリーンアップする
-func cleanHTML(html string) string {
-	// JavaScript部分を削除
-	html = removeElement(html, "<script", "</script>")
-
-	// CSSスタイルを削除
-	html = removeElement(html, "<style", "</style>")
-
-	// インラインスタイルを削除
-	html = removeAttribute(html, "style=\"", "\"")
-
-	// クラス属性を削除
-	html = removeAttribute(html, "class=\"", "\"")
-
-	// IDを削除
-	html = removeAttribute(html, "id=\"", "\"")
+// ExtractMainContent はHTMLドキュメントからメインコンテンツを抽出する
+func ExtractMainContent(doc *goquery.Document) string {
+	return cleanHTML(MainContent(doc, false))
+}
 
-	// データ属性を削除
-	html = removeDataAttributes(html)
+// removedElements はクリーンアップで取り除く要素
+const removedElements = "script, style, noscript"
 
-	// 連続する空白を単一の空白に置換
-	html = strings.Join(strings.Fields(html), " ")
+// removedAttributes はクリーンアップで取り除く属性（data-* 属性も取り除く）
+var removedAttributes = []string{"style", "class", "id"}
 
-	return html
-}
+// cleanHTML は要素の内容からスクリプトやスタイル、装飾用の属性を取り除いたHTMLを返す
+// 元のドキュメントを変更しないよう複製したDOMを操作する
+func cleanHTML(s *goquery.Selection) string {
+	clone := s.Clone()
+	clone.Find(removedElements).Remove()
 
-// removeElement はHTMLから特定の要素を削除する
-func removeElement(html, startTag, endTag string) string {
-	result := html
-	for {
-		startIdx := strings.Index(strings.ToLower(result), strings.ToLower(startTag))
-		if startIdx == -1 {
-			break
+	clone.Find("*").AddSelection(clone).Each(func(i int, el *goquery.Selection) {
+		for _, name := range removedAttributes {
+			el.RemoveAttr(name)
 		}
-
-		endIdx := strings.Index(strings.ToLower(result[startIdx:]), strings.ToLower(endTag))
-		if endIdx == -1 {
-			break
+		for _, node := range el.Nodes {
+			var dataAttrs []string
+			for _, a := range node.Attr {
+				if strings.HasPrefix(a.Key, "data-") {
+					dataAttrs = append(dataAttrs, a.Key)
+				}
+			}
+			for _, name := range dataAttrs {
+				el.RemoveAttr(name)
+			}
 		}
+	})
 
-		endIdx += startIdx + len(endTag)
-		if endIdx <= len(result) {
-			result = result[:startIdx] + result[endIdx:]
-		} else {
-			break
-		}
+	// 連続する空白を単一の空白に置換（コードなど整形済みの要素の中は改行や字下げを残す）
+	for _, node := range clone.Nodes {
+		collapseWhitespace(node)
 	}
-	return result
-}
 
-// removeAttribute はHTML要素から特定の属性を削除する
-func removeAttribute(html, attrStart, attrEnd string) string {
-	result := html
-	for {
-		startIdx := strings.Index(strings.ToLower(result), strings.ToLower(attrStart))
-		if startIdx == -1 {
-			break
-		}
-
-		endIdx := strings.Index(result[startIdx:], attrEnd)
-		if endIdx == -1 {
-			break
-		}
-
-		endIdx += startIdx + len(attrEnd)
-		if endIdx <= len(result) {
-			result = result[:startIdx] + result[endIdx:]
-		} else {
-			break
-		}
+	content, err := clone.Html()
+	if err != nil {
+		return ""
 	}
-	return result
+	return strings.TrimSpace(content)
 }
 
-// removeDataAttributes はHTML要素からdata-*属性を削除する
-func removeDataAttributes(html string) string {
-	result := html
-	for {
-		startIdx := strings.Index(strings.ToLower(result), "data-")
-		if startIdx == -1 {
-			break
+// preformattedElements は空白をそのまま表示する要素
+var preformattedElements = map[string]bool{"pre": true, "textarea": true, "listing": true, "plaintext": true, "xmp": true}
+
+// collapseWhitespace はテキストノードの連続する空白を単一の空白に置換する（整形済みの要素の中はたどらない）
+func collapseWhitespace(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode:
+			c.Data = collapseSpaces(c.Data)
+		case c.Type == html.ElementNode && preformattedElements[c.Data]:
+		default:
+			collapseWhitespace(c)
 		}
+	}
+}
 
-		// data-属性の前にスペースがあるか確認
-		if startIdx > 0 && result[startIdx-1] != ' ' {
-			// 実際のdata-属性でない場合は次の候補を探す
-			result = result[startIdx+5:]
+// collapseSpaces は文字列の連続する空白を単一の空白に置換する（前後の空白も1つ残す）
+func collapseSpaces(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = true
 			continue
 		}
-
-		// 属性値の終わりを見つける
-		endIdx := strings.Index(result[startIdx:], "\"")
-		if endIdx == -1 {
-			break
-		}
-
-		// 属性値を含む引用符の後の位置
-		valueEndIdx := strings.Index(result[startIdx+endIdx+1:], "\"")
-		if valueEndIdx == -1 {
-			break
-		}
-
-		endIdx = startIdx + endIdx + valueEndIdx + 2
-		if endIdx <= len(result) {
-			result = result[:startIdx] + result[endIdx:]
-		} else {
-			break
+		if space {
+			sb.WriteByte(' ')
+			space = false
 		}
+		sb.WriteRune(r)
+	}
+	if space {
+		sb.WriteByte(' ')
 	}
-	return result
+	return sb.String()
 }
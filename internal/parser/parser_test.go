@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// loadFixture は testdata のHTMLを読み込む
+func loadFixture(t *testing.T, name string) *goquery.Document {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestExtractMainContentCodeSamples(t *testing.T) {
+	doc := loadFixture(t, "code-samples.html")
+	got := ExtractMainContent(doc)
+
+	// コードの中の <script> や data- はそのまま残す
+	for _, want := range []string{
+		"<code>&lt;script&gt;</code>",
+		"<code>data-</code>",
+		"&lt;script src=&#34;https://cdn.example.com/widget.js&#34;&gt;&lt;/script&gt;\n&lt;div data-widget=&#34;chat&#34; data-theme=&#34;dark&#34;&gt;",
+		`<a href="/docs/options">single-quoted attributes</a>`,
+		"def greet():\n    print(&#34;hello&#34;)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("抽出したHTMLに %q が含まれていません:\n%s", want, got)
+		}
+	}
+	// 本物のスクリプト・スタイル・noscriptと、装飾用の属性は取り除く
+	for _, unwanted := range []string{"<script", "docs.js", "<noscript", "Enable JavaScript", "class=", "id=", `data-lang=`, `data-track=`, `data-section=`} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("抽出したHTMLに %q が含まれています:\n%s", unwanted, got)
+		}
+	}
+
+	// 元のドキュメントは変更しない
+	if doc.Find("main script").Length() != 1 || doc.Find("main").AttrOr("data-section", "") != "guides" {
+		t.Error("元のドキュメントが変更されました")
+	}
+}
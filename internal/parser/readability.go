@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// contentCandidates は本文の要素の候補とする要素
+var contentCandidates = map[string]bool{
+	"div": true, "section": true, "article": true, "main": true, "td": true,
+}
+
+// minParagraphChars は本文の段落とみなす文字数の下限
+const minParagraphChars = 25
+
+// classWeight はclass・idの名前による点数の加減
+const classWeight = 25
+
+// positiveNames・negativeNames は本文らしい、または本文らしくない要素のclass・idの名前
+var (
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|docs?|entry|main|markdown|page|post|text`)
+	negativeNames = regexp.MustCompile(`(?i)nav|footer|header|comment|sidebar|menu|banner|breadcrumb|cookie|consent|share|social|related|sponsor|\bads?\b|promo|\btoc\b`)
+)
+
+// scoreContent は本文らしさの点数が最も高い要素を返す（候補がなければ nil）
+// 段落（p・pre）の文字数と読点の数を親（と半分を祖父母）の点数とし、class・idの名前で加減してからリンクの文字の割合で割り引く
+func scoreContent(doc *goquery.Document) *goquery.Selection {
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+	add := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode || !contentCandidates[n.Data] {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = nameWeight(n)
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	doc.Find("p, pre").Each(func(i int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphChars {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "、")) + min(float64(length)/100, 3)
+		parent := s.Nodes[0].Parent
+		add(parent, score)
+		if parent != nil {
+			add(parent.Parent, score/2)
+		}
+	})
+
+	var best *html.Node
+	var bestScore float64
+	for _, n := range order {
+		score := scores[n] * (1 - linkDensity(doc.FindNodes(n)))
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return doc.FindNodes(best)
+}
+
+// nameWeight は要素のclass・idの名前による点数を返す
+func nameWeight(n *html.Node) float64 {
+	var weight float64
+	for _, a := range n.Attr {
+		if a.Key != "class" && a.Key != "id" {
+			continue
+		}
+		if negativeNames.MatchString(a.Val) {
+			weight -= classWeight
+		}
+		if positiveNames.MatchString(a.Val) {
+			weight += classWeight
+		}
+	}
+	return weight
+}
+
+// linkDensity は要素の文字数のうちリンクの文字の割合を返す
+func linkDensity(s *goquery.Selection) float64 {
+	length := utf8.RuneCountInString(strings.TrimSpace(s.Text()))
+	if length == 0 {
+		return 1
+	}
+	links := 0
+	s.Find("a").Each(func(i int, a *goquery.Selection) {
+		links += utf8.RuneCountInString(strings.TrimSpace(a.Text()))
+	})
+	return min(float64(links)/float64(length), 1)
+}
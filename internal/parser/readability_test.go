@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMainContentScoring(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		want     []string // 選ばれた要素に含まれるべき本文
+		unwanted []string // 選ばれた要素に含まれてはいけないナビゲーションなど
+	}{
+		{
+			fixture:  "table-layout.html",
+			want:     []string{"Configuring the mail gateway", "Relay hosts are listed", "reload the service"},
+			unwanted: []string{"Installation guide for all supported platforms", "without warranty"},
+		},
+		{
+			fixture:  "div-soup.html",
+			want:     []string{"Releases are cut from the main branch", "Hotfixes skip the schedule"},
+			unwanted: []string{"About this wiki", "Onboarding checklist", "Great write-up"},
+		},
+		{
+			fixture:  "cookie-banner.html",
+			want:     []string{"100 requests per minute", "X-RateLimit-Reset: 17"},
+			unwanted: []string{"We use cookies", "API overview and authentication", "How we scaled"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strings.TrimSuffix(tt.fixture, ".html"), func(t *testing.T) {
+			doc := loadFixture(t, "readability/"+tt.fixture)
+
+			// 点数で選ばない場合は body 全体になり、ナビゲーションも含まれる
+			if body := MainContent(doc, false); !strings.Contains(body.Text(), tt.unwanted[0]) {
+				t.Errorf("点数で選ばない場合に body 全体が選ばれていません")
+			}
+
+			text := MainContent(doc, true).Text()
+			for _, want := range tt.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("選ばれた要素に %q が含まれていません:\n%s", want, text)
+				}
+			}
+			for _, unwanted := range tt.unwanted {
+				if strings.Contains(text, unwanted) {
+					t.Errorf("選ばれた要素に %q が含まれています:\n%s", unwanted, text)
+				}
+			}
+		})
+	}
+}
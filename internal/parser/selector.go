@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/andybalholm/cascadia"
+)
+
+// ValidateSelector はユーザー指定のセレクタをcascadiaでコンパイルして検証する
+// source にはエラーメッセージに表示する指定元（フラグ名やプロファイル名）を渡す
+func ValidateSelector(source, selector string) error {
+	if _, err := cascadia.ParseGroup(selector); err != nil {
+		return fmt.Errorf("%s のセレクタ %q が不正です: %v", source, selector, err)
+	}
+	return nil
+}
+
+// selectorStat はセレクタ1つ分の集計値
+type selectorStat struct {
+	source   string
+	selector string
+	matches  int
+	pages    int // 評価されたページ数
+}
+
+// SelectorStats はユーザー指定セレクタごとの一致数を集計する
+type SelectorStats struct {
+	mu      sync.Mutex
+	entries map[string]*selectorStat
+	order   []string
+}
+
+// NewSelectorStats は新しいSelectorStatsインスタンスを作成する
+func NewSelectorStats() *SelectorStats {
+	return &SelectorStats{
+		entries: make(map[string]*selectorStat),
+	}
+}
+
+// Register はセレクタを集計対象として登録する
+func (s *SelectorStats) Register(source, selector string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entry(source, selector)
+}
+
+// Record はページ1つでのセレクタの一致数を記録する
+func (s *SelectorStats) Record(source, selector string, matches int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entry(source, selector)
+	e.matches += matches
+	e.pages++
+}
+
+// entry は集計エントリを取得する（存在しなければ作成する）
+func (s *SelectorStats) entry(source, selector string) *selectorStat {
+	key := source + "\x00" + selector
+	e, ok := s.entries[key]
+	if !ok {
+		e = &selectorStat{source: source, selector: selector}
+		s.entries[key] = e
+		s.order = append(s.order, key)
+	}
+	return e
+}
+
+// Empty は集計対象のセレクタが登録されていないかを返す
+func (s *SelectorStats) Empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.order) == 0
+}
+
+// Report はセレクタの有効性を表形式で書き出す
+// 一度も一致しなかったセレクタにはタイプミスの可能性を警告する
+func (s *SelectorStats) Report(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.order) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "セレクタの有効性:")
+	for _, key := range s.order {
+		e := s.entries[key]
+		fmt.Fprintf(w, "  %s '%s': %d 件一致 / %d ページ\n", e.source, e.selector, e.matches, e.pages)
+	}
+	for _, key := range s.order {
+		e := s.entries[key]
+		if e.pages > 0 && e.matches == 0 {
+			fmt.Fprintf(w, "警告: %s '%s' は %d ページで1件も一致しませんでした — タイプミスの可能性があります\n", e.source, e.selector, e.pages)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSelector(t *testing.T) {
+	for _, selector := range []string{"main", "article.content", "div > p:first-child", "nav, .sidebar", `[data-role="toc"]`} {
+		if err := ValidateSelector("--selector", selector); err != nil {
+			t.Errorf("ValidateSelector(%q) = %v", selector, err)
+		}
+	}
+}
+
+func TestValidateSelectorInvalid(t *testing.T) {
+	for _, selector := range []string{"div[", "..advert", "p:nth-child(", "> main", ""} {
+		err := ValidateSelector("--exclude-selector", selector)
+		if err == nil {
+			t.Errorf("ValidateSelector(%q) がエラーになりませんでした", selector)
+			continue
+		}
+		// エラーメッセージには指定元のフラグとセレクタを含める
+		if msg := err.Error(); !strings.Contains(msg, "--exclude-selector") || !strings.Contains(msg, selector) {
+			t.Errorf("エラーメッセージ = %q", msg)
+		}
+	}
+}
+
+func TestSelectorStatsReport(t *testing.T) {
+	s := NewSelectorStats()
+	if !s.Empty() {
+		t.Fatal("登録前の集計が空ではありません")
+	}
+	s.Register("--selector", "main")
+	s.Register("--exclude-selector", ".advert")
+	s.Register("--exclude-selector", ".unused")
+	for range 3 {
+		s.Record("--selector", "main", 1)
+		s.Record("--exclude-selector", ".advert", 0)
+	}
+
+	var sb strings.Builder
+	s.Report(&sb)
+	report := sb.String()
+	for _, want := range []string{
+		"--selector 'main': 3 件一致 / 3 ページ",
+		"--exclude-selector '.advert': 0 件一致 / 3 ページ",
+		"警告: --exclude-selector '.advert' は 3 ページで1件も一致しませんでした",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("レポートに %q が含まれていません:\n%s", want, report)
+		}
+	}
+	// 一致したセレクタと、評価されなかったセレクタには警告しない
+	for _, unwanted := range []string{"警告: --selector", "警告: --exclude-selector '.unused'"} {
+		if strings.Contains(report, unwanted) {
+			t.Errorf("レポートに %q が含まれています:\n%s", unwanted, report)
+		}
+	}
+}
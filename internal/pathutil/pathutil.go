@@ -0,0 +1,101 @@
+package pathutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// maxSegmentLength はパス要素1つあたりの最大バイト数
+// 多くのファイルシステムの上限（255バイト）に余裕を持たせた値
+const maxSegmentLength = 120
+
+// windowsReserved はWindowsで予約されているファイル名
+var windowsReserved = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+	"com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+	"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// SanitizeSegment はパス要素1つをローカルファイルシステムで安全な名前に変換する
+func SanitizeSegment(segment string) string {
+	var sb strings.Builder
+	for _, r := range segment {
+		switch {
+		case r < 0x20, strings.ContainsRune(`<>:"/\|?*`, r):
+			sb.WriteRune('_')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	name := strings.TrimRight(sb.String(), ". ")
+	if name == "" || name == "." || name == ".." {
+		name = "_"
+	}
+
+	// Windowsの予約名（拡張子付きを含む）を回避
+	base := strings.ToLower(strings.SplitN(name, ".", 2)[0])
+	if windowsReserved[base] {
+		name = "_" + name
+	}
+
+	// 長すぎる要素はハッシュで短縮する
+	if len(name) > maxSegmentLength {
+		sum := sha256.Sum256([]byte(name))
+		name = truncateUTF8(name, maxSegmentLength-17) + "-" + hex.EncodeToString(sum[:8])
+	}
+	return name
+}
+
+// FromURL はURLからローカルファイルシステム上の相対パスを生成する
+// ディレクトリを表すURL（末尾が/）には index.html を補い、クエリ文字列はハッシュとしてファイル名に含める
+func FromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return SanitizeSegment(rawURL)
+	}
+
+	p := u.Path
+	if p == "" || strings.HasSuffix(p, "/") {
+		p += "index.html"
+	}
+
+	var segments []string
+	if u.Host != "" {
+		segments = append(segments, SanitizeSegment(u.Host))
+	}
+	for _, segment := range strings.Split(path.Clean("/"+p), "/") {
+		if segment == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		segments = append(segments, SanitizeSegment(segment))
+	}
+
+	// クエリ文字列はファイル名の末尾に短いハッシュとして付与する
+	if u.RawQuery != "" && len(segments) > 0 {
+		sum := sha256.Sum256([]byte(u.RawQuery))
+		last := segments[len(segments)-1]
+		ext := path.Ext(last)
+		segments[len(segments)-1] = strings.TrimSuffix(last, ext) + "_" + hex.EncodeToString(sum[:4]) + ext
+	}
+
+	return path.Join(segments...)
+}
+
+// truncateUTF8 はUTF-8の文字境界を壊さないように文字列を切り詰める
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	for maxBytes > 0 && (s[maxBytes]&0xC0) == 0x80 {
+		maxBytes--
+	}
+	return s[:maxBytes]
+}
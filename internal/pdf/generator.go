@@ -7,22 +7,34 @@ import (
 	"strings"
 
 	"github.com/yugo-ibuki/docrawl/internal/crawler"
+	"github.com/yugo-ibuki/docrawl/internal/document"
 )
 
+// Options はページヘッダーの表示に関する設定
+type Options struct {
+	ShowStatus bool               // 各ページのヘッダーにHTTPステータスコードを表示するか
+	UTC        bool               // 日時をローカルタイムゾーンではなくUTCで表示するか
+	Meta       document.CrawlMeta // クロール全体のメタ情報（ファイルの先頭に出所として表示する）
+
+	SectionHeadings map[int]string // ページの番号（0から）とその前に表示するセクションの見出し（--group-by-section の場合のみ）
+}
+
 // Generator はPDFを生成する構造体
 type Generator struct {
 	outputPath string
+	opts       Options
 }
 
 // NewGenerator は新しいGeneratorインスタンスを作成する
-func NewGenerator(outputPath string) *Generator {
+func NewGenerator(outputPath string, opts Options) *Generator {
 	return &Generator{
 		outputPath: outputPath,
+		opts:       opts,
 	}
 }
 
 // GeneratePDF はクロールしたページからPDFを生成する（今回はテキストファイルに変更）
-func (g *Generator) GeneratePDF(pages []crawler.Page) error {
+func (g *Generator) GeneratePDF(pages []document.Page) error {
 	if len(pages) == 0 {
 		return fmt.Errorf("生成するページがありません")
 	}
@@ -31,7 +43,7 @@ func (g *Generator) GeneratePDF(pages []crawler.Page) error {
 	txtOutputPath := getTextPath(g.outputPath)
 
 	// テキストファイルを生成
-	err := generateTextFile(pages, txtOutputPath)
+	err := g.generateTextFile(pages, txtOutputPath)
 	if err != nil {
 		return err
 	}
@@ -51,7 +63,7 @@ func getTextPath(pdfPath string) string {
 }
 
 // generateTextFile はページの内容からテキストファイルを生成する
-func generateTextFile(pages []crawler.Page, outputPath string) error {
+func (g *Generator) generateTextFile(pages []document.Page, outputPath string) error {
 	// テキストファイルを作成
 	file, err := os.Create(outputPath)
 	if err != nil {
@@ -60,14 +72,54 @@ func generateTextFile(pages []crawler.Page, outputPath string) error {
 	defer file.Close()
 
 	// ヘッダー情報を書き込み
-	fmt.Fprintf(file, "# ドキュメント収集結果\n")
-	fmt.Fprintf(file, "# 取得ページ数: %d\n\n", len(pages))
+	fmt.Fprintln(file, document.ProvenanceBlock(g.opts.Meta, g.opts.UTC))
 
 	// 各ページの内容を書き込み
 	for i, page := range pages {
+		if heading, ok := g.opts.SectionHeadings[i]; ok {
+			fmt.Fprintf(file, "### セクション: %s ###\n\n", heading)
+		}
 		fmt.Fprintf(file, "=== ページ %d/%d ===\n", i+1, len(pages))
 		fmt.Fprintf(file, "URL: %s\n", page.URL)
-		fmt.Fprintf(file, "タイトル: %s\n\n", page.Title)
+		if page.CanonicalURL != "" {
+			fmt.Fprintf(file, "正規URL: %s\n", page.CanonicalURL)
+		}
+		for _, alias := range page.AliasURLs {
+			fmt.Fprintf(file, "別URL: %s\n", alias)
+		}
+		if page.FromPDF {
+			fmt.Fprintf(file, "元の形式: PDF（%s）\n", document.PDFSource(page))
+		}
+		if g.opts.ShowStatus {
+			fmt.Fprintf(file, "ステータス: %d\n", page.StatusCode)
+		}
+		if page.ChangeStatus != "" {
+			fmt.Fprintf(file, "変更: %s\n", page.ChangeStatus)
+		}
+		if !page.FetchedAt.IsZero() {
+			fmt.Fprintf(file, "取得日時: %s\n", document.FormatTime(page.FetchedAt, g.opts.UTC))
+		}
+		if !page.LastModified.IsZero() {
+			fmt.Fprintf(file, "最終更新日時: %s\n", document.FormatTime(page.LastModified, g.opts.UTC))
+		}
+		if !page.ModifiedAt.IsZero() {
+			fmt.Fprintf(file, "記事の更新日時: %s\n", document.FormatTime(page.ModifiedAt, g.opts.UTC))
+		}
+		fmt.Fprintf(file, "タイトル: %s\n", page.Title)
+		if page.Description != "" {
+			fmt.Fprintf(file, "説明: %s\n", page.Description)
+		}
+		if len(page.Breadcrumbs) > 0 {
+			fmt.Fprintf(file, "パンくずリスト: %s\n", strings.Join(page.Breadcrumbs, " > "))
+		}
+		// 本文の見出しの {#アンカー} を参照できるよう、ページごとに見出しの階層とアンカーを目次として書き込む
+		if len(page.Outline) > 0 {
+			fmt.Fprintln(file, "目次:")
+			for _, line := range document.OutlineLines(page.Outline) {
+				fmt.Fprintln(file, line)
+			}
+		}
+		fmt.Fprintln(file)
 
 		// コンテンツを整形して書き込み
 		content := cleanupContent(page.Content)
@@ -82,39 +134,29 @@ func generateTextFile(pages []crawler.Page, outputPath string) error {
 	return nil
 }
 
-// cleanupContent はコンテンツを整形する
+// cleanupContent はコンテンツを整形する（コードブロックの中の行は空白も含めてそのまま残す）
+// 共通の整形に加えて、連続するスペースを1つにまとめ、見出しの前に空行を入れる
 func cleanupContent(content string) string {
-	// 改行を統一（Windowsの CRLF を LF に変換）
-	content = strings.ReplaceAll(content, "\r\n", "\n")
-	
-	// 連続する空白行を削除（3行以上の空行を2行に）
-	for strings.Contains(content, "\n\n\n") {
-		content = strings.ReplaceAll(content, "\n\n\n", "\n\n")
-	}
-	
-	// 先頭と末尾の空白を削除
-	content = strings.TrimSpace(content)
-	
-	// 行末の空白を削除
-	regexTrailingSpaces := regexp.MustCompile(`[ \t]+\n`)
-	content = regexTrailingSpaces.ReplaceAllString(content, "\n")
-	
-	// 連続するスペースを1つに
 	regexMultipleSpaces := regexp.MustCompile(`[ \t]{2,}`)
-	content = regexMultipleSpaces.ReplaceAllString(content, " ")
-	
-	// 見出し周りの空行を整理（見出し前に空行を挿入し、見出し後の空行を削除）
-	// 文字列を一括で置換するのではなく、必要な調整を行う
-	lines := strings.Split(content, "\n")
-	for i := 0; i < len(lines); i++ {
-		if i > 0 && strings.HasPrefix(strings.TrimSpace(lines[i]), "#") {
-			// 見出しの前に空行がなければ追加（すでに前が空行の場合は追加しない）
-			if lines[i-1] != "" {
-				lines = append(lines[:i], append([]string{""}, lines[i:]...)...)
-				i++ // 挿入された行をスキップ
-			}
+
+	source := strings.Split(document.CleanupText(content), "\n")
+	code := crawler.CodeFenceLines(source)
+	var lines []string
+	for i, line := range source {
+		if code[i] {
+			lines = append(lines, line)
+			continue
 		}
+
+		// 連続するスペースを1つに（入れ子のリストなどの行頭の字下げは残す）
+		body := strings.TrimLeft(line, " \t")
+		line = line[:len(line)-len(body)] + regexMultipleSpaces.ReplaceAllString(body, " ")
+
+		// 見出しの前に空行がなければ追加（すでに前が空行の場合は追加しない）
+		if len(lines) > 0 && strings.HasPrefix(strings.TrimSpace(line), "#") && lines[len(lines)-1] != "" {
+			lines = append(lines, "")
+		}
+		lines = append(lines, line)
 	}
-	
 	return strings.Join(lines, "\n")
-}
\ No newline at end of file
+}
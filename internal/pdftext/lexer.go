@@ -0,0 +1,189 @@
+package pdftext
+
+import (
+	"bytes"
+	"encoding/hex"
+)
+
+// tokenKind はページの内容を構成する字句の種類
+type tokenKind int
+
+const (
+	tokenNumber   tokenKind = iota // 数値
+	tokenString                    // 文字列（リテラルと16進数）
+	tokenName                      // /Name
+	tokenOperator                  // Tj などの演算子
+	tokenDelim                     // 配列や辞書の区切り
+)
+
+// token はページの内容の字句
+type token struct {
+	kind  tokenKind
+	value []byte
+}
+
+// lexer はPDFのページの内容を字句に分割する
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+// isDelimiter はPDFの区切り文字かどうかを判定する
+func isDelimiter(b byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), b) >= 0
+}
+
+// isWhitespace はPDFの空白文字かどうかを判定する
+func isWhitespace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == '\f' || b == 0
+}
+
+// next は次の字句を返す（データの終わりでは false）
+func (l *lexer) next() (token, bool) {
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		switch {
+		case isWhitespace(b):
+			l.pos++
+		case b == '%':
+			// コメントは行末まで読み飛ばす
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case b == '(':
+			return token{kind: tokenString, value: l.literalString()}, true
+		case b == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<', b == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+			l.pos += 2
+			return token{kind: tokenDelim, value: l.data[l.pos-2 : l.pos]}, true
+		case b == '<':
+			return token{kind: tokenString, value: l.hexString()}, true
+		case b == '[' || b == ']' || b == '{' || b == '}' || b == ')' || b == '>':
+			l.pos++
+			return token{kind: tokenDelim, value: l.data[l.pos-1 : l.pos]}, true
+		case b == '/':
+			l.pos++
+			return token{kind: tokenName, value: l.regular()}, true
+		default:
+			word := l.regular()
+			if isNumber(word) {
+				return token{kind: tokenNumber, value: word}, true
+			}
+			return token{kind: tokenOperator, value: word}, true
+		}
+	}
+	return token{}, false
+}
+
+// regular は区切り文字か空白までの字句を読む
+func (l *lexer) regular() []byte {
+	start := l.pos
+	for l.pos < len(l.data) && !isWhitespace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	// 区切り文字が単独で現れた場合も先に進める
+	if l.pos == start {
+		l.pos++
+	}
+	return l.data[start:l.pos]
+}
+
+// isNumber は字句が数値かどうかを判定する
+func isNumber(word []byte) bool {
+	if len(word) == 0 {
+		return false
+	}
+	for i, b := range word {
+		if (b < '0' || b > '9') && b != '.' && !(i == 0 && (b == '-' || b == '+')) {
+			return false
+		}
+	}
+	return true
+}
+
+// literalString は括弧で囲まれた文字列を読み、エスケープを解釈した内容を返す
+func (l *lexer) literalString() []byte {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		l.pos++
+		switch b {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// 行末の \ は改行を無視する
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					// \ddd は8進数の文字コード
+					n := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+			continue
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// hexString は <...> で囲まれた16進数の文字列を読む
+func (l *lexer) hexString() []byte {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if !isWhitespace(l.data[l.pos]) {
+			digits = append(digits, l.data[l.pos])
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	// 桁数が奇数の場合は最後に0を補う
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, hex.DecodedLen(len(digits)))
+	n, _ := hex.Decode(out, digits)
+	return out[:n]
+}
+
+// skipInlineImage はインライン画像のデータを EI の直後まで読み飛ばす
+func (l *lexer) skipInlineImage() {
+	if i := bytes.Index(l.data[l.pos:], []byte("EI")); i >= 0 {
+		l.pos += i + 2
+		return
+	}
+	l.pos = len(l.data)
+}
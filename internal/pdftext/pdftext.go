@@ -0,0 +1,250 @@
+// Package pdftext はPDFファイルから本文のテキストとタイトルを抽出する
+// 標準フォントや1バイトの文字コードで書かれたテキストを対象とし、
+// CIDフォントなどで文字コードを解釈できないPDFはエラーを返す
+package pdftext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// ErrNoText はPDFから読み取れるテキストが見つからなかったことを示す
+var ErrNoText = errors.New("PDFから読み取れるテキストが見つかりません")
+
+// minPrintableRatio は抽出したテキストとして採用する表示可能な文字の割合の下限
+// 文字コードを解釈できないフォントの文字列は制御文字が多くなるため、これを下回る場合は抽出に失敗したとみなす
+const minPrintableRatio = 0.9
+
+// Document はPDFから抽出した内容
+type Document struct {
+	Title string // 文書情報のタイトル（ない場合は空）
+	Text  string // 本文のテキスト
+}
+
+// streamPattern はストリームの辞書の終わりと本文の開始位置を探すパターン
+var streamPattern = regexp.MustCompile(`>>\s*stream\r?\n`)
+
+// titlePattern は文書情報のタイトルを探すパターン
+var titlePattern = regexp.MustCompile(`/Title\s*(\(|<[0-9A-Fa-f\s]*>)`)
+
+// Extract はPDFのデータから本文のテキストとタイトルを抽出する
+func Extract(data []byte) (*Document, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\r\n\t "), []byte("%PDF-")) {
+		return nil, errors.New("PDFファイルではありません")
+	}
+
+	doc := &Document{Title: findTitle(data)}
+	var text strings.Builder
+	for pos := 0; pos < len(data); {
+		loc := streamPattern.FindIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		// ストリームの辞書はオブジェクトの開始（N 0 obj）からストリームの直前まで
+		dictStart := bytes.LastIndex(data[pos:pos+loc[0]], []byte("obj"))
+		if dictStart < 0 {
+			dictStart = 0
+		}
+		dict := string(data[pos+dictStart : pos+loc[0]])
+		start := pos + loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		pos = start + end
+		content, ok := decodeStream(dict, data[start:start+end])
+		if !ok {
+			continue
+		}
+		// 圧縮されたオブジェクトの中にある文書情報からもタイトルを探す
+		if doc.Title == "" && strings.Contains(dict, "/ObjStm") {
+			doc.Title = findTitle(content)
+		}
+		if isContentStream(dict) {
+			text.WriteString(extractContentText(content))
+		}
+	}
+
+	doc.Text = strings.TrimSpace(text.String())
+	if doc.Text == "" || printableRatio(doc.Text) < minPrintableRatio {
+		return nil, ErrNoText
+	}
+	return doc, nil
+}
+
+// decodeStream はストリームの本文を展開する（対応していない圧縮方式の場合は false）
+func decodeStream(dict string, raw []byte) ([]byte, bool) {
+	i := strings.Index(dict, "/Filter")
+	if i < 0 {
+		return raw, true
+	}
+	// FlateDecodeを1つだけ指定したストリームのみ対応する
+	filter := strings.TrimSpace(dict[i+len("/Filter"):])
+	if strings.HasPrefix(filter, "[") {
+		end := strings.IndexByte(filter, ']')
+		if end < 0 {
+			return nil, false
+		}
+		names := strings.Fields(filter[1:end])
+		if len(names) != 1 {
+			return nil, false
+		}
+		filter = names[0]
+	}
+	if !strings.HasPrefix(filter, "/FlateDecode") {
+		return nil, false
+	}
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	// 末尾が壊れている場合もそれまでに展開できた内容を使う
+	content, err := io.ReadAll(r)
+	if err != nil && len(content) == 0 {
+		return nil, false
+	}
+	return content, true
+}
+
+// isContentStream はストリームがページの内容（画像・フォント・メタデータ以外）かどうかを判定する
+func isContentStream(dict string) bool {
+	for _, excluded := range []string{"/Image", "/FontFile", "/Length1", "/Metadata", "/XRef", "/ObjStm", "/ICCBased", "/EmbeddedFile"} {
+		if strings.Contains(dict, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// findTitle は文書情報の /Title の値を返す（ない場合は空）
+func findTitle(data []byte) string {
+	loc := titlePattern.FindIndex(data)
+	if loc == nil {
+		return ""
+	}
+	l := &lexer{data: data, pos: loc[1] - 1}
+	if data[l.pos] != '(' {
+		l.pos = bytes.LastIndexByte(data[:loc[1]], '<')
+	}
+	tok, ok := l.next()
+	if !ok || tok.kind != tokenString {
+		return ""
+	}
+	return strings.TrimSpace(decodeText(tok.value))
+}
+
+// extractContentText はページの内容の演算子からテキストを取り出す
+func extractContentText(content []byte) string {
+	var sb strings.Builder
+	var operands []token
+	l := &lexer{data: content}
+	newline := func() {
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteByte('\n')
+		}
+	}
+	for {
+		tok, ok := l.next()
+		if !ok {
+			break
+		}
+		if tok.kind != tokenOperator {
+			operands = append(operands, tok)
+			continue
+		}
+		switch string(tok.value) {
+		case "Tj":
+			writeOperandText(&sb, operands)
+		case "'", "\"":
+			newline()
+			writeOperandText(&sb, operands)
+		case "TJ":
+			for _, op := range operands {
+				switch op.kind {
+				case tokenString:
+					sb.WriteString(decodeText(op.value))
+				case tokenNumber:
+					// 大きな字間の調整は単語の区切りとして扱う
+					if n, err := strconv.ParseFloat(string(op.value), 64); err == nil && n < -200 {
+						sb.WriteByte(' ')
+					}
+				}
+			}
+		case "Td", "TD":
+			// 縦方向に移動した場合は改行、横方向のみの場合は空白とする
+			if len(operands) >= 2 && !isZero(operands[len(operands)-1]) {
+				newline()
+			} else if sb.Len() > 0 && !strings.HasSuffix(sb.String(), " ") {
+				sb.WriteByte(' ')
+			}
+		case "T*", "Tm", "ET":
+			newline()
+		case "ID":
+			// インライン画像のバイナリデータは EI まで読み飛ばす
+			l.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+	newline()
+	return sb.String()
+}
+
+// isZero は数値のオペランドが0かどうかを判定する
+func isZero(tok token) bool {
+	n, err := strconv.ParseFloat(string(tok.value), 64)
+	return err == nil && n == 0
+}
+
+// writeOperandText はオペランドの最後の文字列を書き込む
+func writeOperandText(sb *strings.Builder, operands []token) {
+	for i := len(operands) - 1; i >= 0; i-- {
+		if operands[i].kind == tokenString {
+			sb.WriteString(decodeText(operands[i].value))
+			return
+		}
+	}
+}
+
+// decodeText はPDFの文字列をUTF-8に変換する（BOM付きはUTF-16、それ以外はWindows-1252として扱う）
+func decodeText(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	s, err := charmap.Windows1252.NewDecoder().Bytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return string(s)
+}
+
+// printableRatio は空白を除いた文字のうち表示可能な文字の割合を返す
+func printableRatio(s string) float64 {
+	total, printable := 0, 0
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if unicode.IsPrint(r) && r != unicode.ReplacementChar {
+			printable++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(printable) / float64(total)
+}
@@ -0,0 +1,144 @@
+package pdftext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF はオブジェクトを順に並べた最小限のPDFのデータを作る（相互参照表は省略する）
+func buildPDF(objects ...string) []byte {
+	var sb strings.Builder
+	sb.WriteString("%PDF-1.4\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&sb, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	sb.WriteString("%%EOF\n")
+	return []byte(sb.String())
+}
+
+// stream は辞書の項目と本文からストリームのオブジェクトを作る
+func stream(dict string, content []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(content), content)
+}
+
+// deflate はFlateDecodeのストリームの本文を作る
+func deflate(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractTitle(t *testing.T) {
+	page := stream("", []byte("BT /F1 12 Tf (Body text) Tj ET"))
+	for _, tt := range []struct {
+		name string
+		info string
+		want string
+	}{
+		{"literal", "<< /Title (Hello Spec) /Producer (test) >>", "Hello Spec"},
+		{"hex", "<< /Title <48656C6C6F> >>", "Hello"},
+		{"utf16 hex", "<< /Title <FEFF00480069> >>", "Hi"},
+		{"utf16 hex with spaces", "<< /Producer (test) /Title <FEFF 3053 3093 306B 3061 306F> >>", "こんにちは"},
+		{"escaped literal", `<< /Title (Spec \(draft\)) >>`, "Spec (draft)"},
+		{"none", "<< /Producer (test) >>", ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Extract(buildPDF(tt.info, page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Title != tt.want {
+				t.Errorf("Title = %q, want %q", doc.Title, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		objects func(t *testing.T) []string
+		want    string
+	}{
+		{"Tj", func(*testing.T) []string {
+			return []string{stream("", []byte("BT /F1 12 Tf 72 720 Td (Hello, PDF) Tj ET"))}
+		}, "Hello, PDF"},
+		{"TJ の字間", func(*testing.T) []string {
+			return []string{stream("", []byte("BT [(Hel) -20 (lo) -300 (world)] TJ ET"))}
+		}, "Hello world"},
+		{"' で改行", func(*testing.T) []string {
+			return []string{stream("", []byte("BT 14 TL (first line) Tj (second line) ' ET"))}
+		}, "first line\nsecond line"},
+		{"Td の移動", func(*testing.T) []string {
+			return []string{stream("", []byte("BT (left) Tj 120 0 Td (right) Tj 0 -14 Td (below) Tj ET"))}
+		}, "left right\nbelow"},
+		{"エスケープと16進数の文字列", func(*testing.T) []string {
+			return []string{stream("", []byte(`BT (caf\351 \(1\)) Tj <20414243> Tj ET`))}
+		}, "café (1) ABC"},
+		{"FlateDecode", func(t *testing.T) []string {
+			return []string{stream("/Filter /FlateDecode", deflate(t, "BT (compressed text) Tj ET"))}
+		}, "compressed text"},
+		{"配列で指定した FlateDecode", func(t *testing.T) []string {
+			return []string{stream("/Filter [/FlateDecode]", deflate(t, "BT (array filter) Tj ET"))}
+		}, "array filter"},
+		{"対応していない圧縮方式は読み飛ばす", func(t *testing.T) []string {
+			return []string{
+				stream("/Filter /LZWDecode", []byte("BT (lzw) Tj ET")),
+				stream("/Filter [/ASCII85Decode /FlateDecode]", []byte("BT (chained) Tj ET")),
+				stream("", []byte("BT (plain page) Tj ET")),
+			}
+		}, "plain page"},
+		{"フォントと画像のストリームは読み飛ばす", func(*testing.T) []string {
+			return []string{
+				stream("/Length1 10", []byte("BT (font program) Tj ET")),
+				stream("/Type /XObject /Subtype /Image", []byte("BT (image) Tj ET")),
+				stream("", []byte("BT (page text) Tj ET")),
+			}
+		}, "page text"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Extract(buildPDF(tt.objects(t)...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Text != tt.want {
+				t.Errorf("Text = %q, want %q", doc.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		data   []byte
+		noText bool
+	}{
+		{"PDFではない", []byte("<html><body>not a pdf</body></html>"), false},
+		{"テキストがない", buildPDF(stream("", []byte("0 0 m 100 100 l S"))), true},
+		{"圧縮方式に対応していない", buildPDF(stream("/Filter /DCTDecode", []byte("BT (jpeg) Tj ET"))), true},
+		// CIDフォントの2バイトの文字コードは制御文字として読めるため、表示可能な文字の割合で抽出の失敗とみなす
+		{"文字コードを解釈できない", buildPDF(stream("", []byte("BT <0012003400560078001A> Tj ET"))), true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Extract(tt.data)
+			if err == nil {
+				t.Fatalf("エラーになりませんでした: %+v", doc)
+			}
+			if got := errors.Is(err, ErrNoText); got != tt.noText {
+				t.Errorf("errors.Is(err, ErrNoText) = %v, want %v（err: %v）", got, tt.noText, err)
+			}
+		})
+	}
+}
@@ -12,4 +12,5 @@ func main() {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	os.Exit(cmd.ExitCode())
 }
@@ -0,0 +1,57 @@
+// Package output はクロール結果の出力形式（Writer）を提供する
+//
+// 組み込みの txt / pdf 形式に加えて、docrawlを組み込んだアプリケーションは
+// RegisterFormat で独自のWriterを名前付きで登録し、--format などの設定から選択できる。
+// 組み込み形式と同じ名前を登録しようとした場合はエラーになる。
+//
+// 次の例は、クロールしたページを1件ずつHTTP APIへ送信するWriterを登録する。
+//
+//	type apiWriter struct {
+//		endpoint string
+//		client   *http.Client
+//	}
+//
+//	func (w *apiWriter) Write(result *output.CrawlResult) error {
+//		return output.Write(w, result) // StreamWriterとして1ページずつ処理する
+//	}
+//
+//	func (w *apiWriter) Begin(meta output.CrawlMeta) error { return nil }
+//
+//	func (w *apiWriter) WritePage(page output.Page) error {
+//		body, err := json.Marshal(map[string]string{
+//			"url":     page.URL,
+//			"title":   page.Title,
+//			"content": output.CleanupText(page.Content),
+//		})
+//		if err != nil {
+//			return err
+//		}
+//		resp, err := w.client.Post(w.endpoint, "application/json", bytes.NewReader(body))
+//		if err != nil {
+//			return err
+//		}
+//		defer resp.Body.Close()
+//		if resp.StatusCode >= 300 {
+//			return fmt.Errorf("%s の送信に失敗しました: %s", page.URL, resp.Status)
+//		}
+//		return nil
+//	}
+//
+//	func (w *apiWriter) End() error { return nil }
+//
+//	func main() {
+//		err := output.RegisterFormat("docstore", func(opts output.Options) (output.Writer, error) {
+//			return &apiWriter{endpoint: os.Getenv("DOCSTORE_URL"), client: http.DefaultClient}, nil
+//		})
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		// docrawl -u https://example.com/docs -f docstore
+//		if err := cmd.Execute(); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// output.Write はWriterがStreamWriterを実装していれば Begin → WritePage → End の順に呼び出し、
+// そうでなければ Write を一度だけ呼び出す。上の例の Write は直接呼び出された場合のためのもの。
+package output
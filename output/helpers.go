@@ -0,0 +1,168 @@
+package output
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yugo-ibuki/docrawl/internal/document"
+	"github.com/yugo-ibuki/docrawl/internal/pathutil"
+)
+
+// SanitizePath はURLからローカルファイルシステムで安全な相対パスを生成する
+func SanitizePath(rawURL string) string {
+	return pathutil.FromURL(rawURL)
+}
+
+// CleanupText はテキストコンテンツを整形する
+// 改行コードの統一、行末の空白の削除、連続する空行の削除を行う（コードブロックの中の行はそのまま残す）
+func CleanupText(content string) string {
+	return document.CleanupText(content)
+}
+
+// OutlineLines は見出しの階層を深さに応じて字下げした「- 見出し (#アンカー)」の行にする（目次の出力に使う）
+func OutlineLines(nodes []*HeadingNode) []string {
+	return document.OutlineLines(nodes)
+}
+
+// FormatTime は日時を表示用に整形する（utc が false の場合はローカルタイムゾーン）
+func FormatTime(t time.Time, utc bool) string {
+	return document.FormatTime(t, utc)
+}
+
+// ProvenanceBlock はクロール結果の出所（開始URL、取得日時、ページ数）を示すヘッダーを返す
+// utc が true の場合は取得日時をUTCで表示する
+func ProvenanceBlock(meta CrawlMeta, utc bool) string {
+	return document.ProvenanceBlock(meta, utc)
+}
+
+// Section はパンくずリスト（なければURLパス）の階層に基づくページのまとまり
+type Section struct {
+	Name     string     // パンくずリストの項目名またはパス要素名（ルートは空）
+	Path     string     // ルートからのパス（末尾は/）
+	Pages    []Page     // このセクションに直接属するページ
+	Children []*Section // 子セクション（最初に現れた順）
+}
+
+// BuildSectionTree はページを階層ごとにまとめたツリーを構築する
+// パンくずリストのあるページは最後の項目（ページ自身）を除いた項目を、ないページはURLパスを階層とする
+// URLパスの階層は、同じディレクトリにあるパンくずリストのあるページの項目名に置き換える（guides と Guides を別のセクションにしない）
+// ページの順序は入力の順序を保つ
+func BuildSectionTree(pages []Page) *Section {
+	dirs := breadcrumbDirs(pages)
+	root := &Section{Path: "/"}
+	for _, page := range pages {
+		section := root
+		names := dirSections(sectionNames(page.URL), dirs)
+		if len(page.Breadcrumbs) > 0 {
+			names = page.Breadcrumbs[:len(page.Breadcrumbs)-1]
+		}
+		for _, name := range names {
+			section = section.child(name)
+		}
+		section.Pages = append(section.Pages, page)
+	}
+	return root
+}
+
+// AllPages はセクションとその子孫のページをツリーの順（各セクションのページ、続けて子セクション）に返す
+// 返すページの Section には、ページが属するセクションの項目名（ルートからの順）を設定する
+func (s *Section) AllPages() []Page {
+	return s.appendPages(nil, nil)
+}
+
+// appendPages はセクションとその子孫のページを pages に追加する（names はこのセクションまでの項目名）
+func (s *Section) appendPages(pages []Page, names []string) []Page {
+	for _, page := range s.Pages {
+		page.Section = names
+		pages = append(pages, page)
+	}
+	for _, c := range s.Children {
+		pages = c.appendPages(pages, append(names[:len(names):len(names)], c.Name))
+	}
+	return pages
+}
+
+// child は名前に一致する子セクションを返す（存在しなければ作成する）
+// 大文字と小文字、区切りの - や _ と空白の違いは同じ名前とみなす
+func (s *Section) child(name string) *Section {
+	key := sectionKey(name)
+	for _, c := range s.Children {
+		if sectionKey(c.Name) == key {
+			return c
+		}
+	}
+	c := &Section{Name: name, Path: s.Path + name + "/"}
+	s.Children = append(s.Children, c)
+	return c
+}
+
+// sectionKey はセクションの名前を比較用に正規化する
+func sectionKey(name string) string {
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(strings.ToLower(name))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// SectionTitle はセクションの項目名を見出し用に「Guides > Deployment」の形にする
+func SectionTitle(names []string) string {
+	return strings.Join(names, " > ")
+}
+
+// sectionStarts はページの前にセクションの見出しを出力するかを判定する（直前のページとセクションが異なる場合）
+func sectionStarts(prev, page Page) bool {
+	return len(page.Section) > 0 && SectionTitle(prev.Section) != SectionTitle(page.Section)
+}
+
+// breadcrumbDirs はパンくずリストのあるページから、URLのディレクトリと対応するセクションの項目名を集める
+// /docs/guides/deploy/k8s のパンくずリストが Guides > Deployment > Kubernetes の場合、末尾からたどって
+// /docs/guides/deploy/ を Guides > Deployment に、/docs/guides/ を Guides に対応付ける（先に現れたページを優先する）
+// /docs/guides/ のようなディレクトリのページは、パンくずリストの最後の項目（ページ自身）がディレクトリに対応する
+func breadcrumbDirs(pages []Page) map[string][]string {
+	dirs := make(map[string][]string)
+	for _, page := range pages {
+		segments := sectionNames(page.URL)
+		names := page.Breadcrumbs
+		if u, err := url.Parse(page.URL); err == nil && !strings.HasSuffix(u.Path, "/") && len(names) > 0 {
+			names = names[:len(names)-1]
+		}
+		for i, j := len(segments), len(names); i > 0 && j > 0; i, j = i-1, j-1 {
+			dir := strings.Join(segments[:i], "/")
+			if _, exists := dirs[dir]; !exists {
+				dirs[dir] = names[:j]
+			}
+		}
+	}
+	return dirs
+}
+
+// dirSections はURLのディレクトリのパス要素を、対応付けのある最も深いディレクトリのセクションの項目名に置き換える
+// 対応付けのないディレクトリの分はパス要素のまま続ける
+func dirSections(segments []string, dirs map[string][]string) []string {
+	for i := len(segments); i > 0; i-- {
+		if names, ok := dirs[strings.Join(segments[:i], "/")]; ok {
+			return append(names[:len(names):len(names)], segments[i:]...)
+		}
+	}
+	return segments
+}
+
+// sectionNames はページURLが属するディレクトリのパス要素を返す
+// /docs/guide/intro は docs, guide、/docs/guide/ は docs, guide に属する
+func sectionNames(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if !strings.HasSuffix(u.Path, "/") {
+		segments = segments[:len(segments)-1]
+	}
+
+	var names []string
+	for _, segment := range segments {
+		if segment != "" {
+			names = append(names, segment)
+		}
+	}
+	return names
+}
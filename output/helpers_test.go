@@ -0,0 +1,171 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sectionOrder はページの順とそれぞれのセクションを「セクション: URLのパス」の行にする
+func sectionOrder(pages []Page) string {
+	var lines []string
+	for _, page := range pages {
+		lines = append(lines, SectionTitle(page.Section)+": "+strings.TrimPrefix(page.URL, "https://example.com"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestBuildSectionTreeMapsURLFallbacks(t *testing.T) {
+	pages := []Page{
+		{URL: "https://example.com/docs/"},
+		{URL: "https://example.com/docs/guides/intro"},
+		{URL: "https://example.com/docs/guides/deploy/k8s", Breadcrumbs: []string{"Guides", "Deployment", "Kubernetes"}},
+		{URL: "https://example.com/docs/guides/deploy/docker"},
+		{URL: "https://example.com/docs/api/"},
+		{URL: "https://example.com/docs/api/auth", Breadcrumbs: []string{"API Reference", "Auth"}},
+		{URL: "https://example.com/docs/getting-started/install"},
+		{URL: "https://example.com/docs/getting-started/faq", Breadcrumbs: []string{"Getting Started", "FAQ"}},
+	}
+	got := sectionOrder(BuildSectionTree(pages).AllPages())
+	want := strings.Join([]string{
+		"docs: /docs/",
+		"Guides: /docs/guides/intro",
+		"Guides > Deployment: /docs/guides/deploy/k8s",
+		"Guides > Deployment: /docs/guides/deploy/docker",
+		"API Reference: /docs/api/",
+		"API Reference: /docs/api/auth",
+		"Getting Started: /docs/getting-started/install",
+		"Getting Started: /docs/getting-started/faq",
+	}, "\n")
+	if got != want {
+		t.Errorf("セクションの順が異なります\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBuildSectionTreeMergesNames(t *testing.T) {
+	// 大文字と小文字、- と空白の違いは同じセクションにまとめ、最初に現れた名前を使う
+	pages := []Page{
+		{URL: "https://example.com/a", Breadcrumbs: []string{"Getting Started", "A"}},
+		{URL: "https://example.com/getting-started/b"},
+		{URL: "https://example.com/c", Breadcrumbs: []string{"getting started", "C"}},
+	}
+	root := BuildSectionTree(pages)
+	if len(root.Children) != 1 {
+		t.Fatalf("セクションが分かれています: %d 件", len(root.Children))
+	}
+	if section := root.Children[0]; section.Name != "Getting Started" || len(section.Pages) != 3 {
+		t.Errorf("セクション = %q（%d ページ）, want \"Getting Started\"（3 ページ）", section.Name, len(section.Pages))
+	}
+}
+
+func TestTXTWriterSectionHeadings(t *testing.T) {
+	pages := BuildSectionTree([]Page{
+		{URL: "https://example.com/guides/a", Title: "A", Breadcrumbs: []string{"Guides", "A"}},
+		{URL: "https://example.com/top", Title: "Top"},
+		{URL: "https://example.com/guides/b", Title: "B", Breadcrumbs: []string{"Guides", "B"}},
+		{URL: "https://example.com/guides/deploy/c", Title: "C", Breadcrumbs: []string{"Guides", "Deploy", "C"}},
+	}).AllPages()
+
+	path := filepath.Join(t.TempDir(), "out.txt")
+	w, _ := newTXTWriter(Options{OutputPath: path})
+	if err := w.Write(&CrawlResult{Meta: CrawlMeta{PageCount: len(pages)}, Pages: pages}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "# セクション: ") || strings.HasPrefix(line, "# URL: ") {
+			order = append(order, line)
+		}
+	}
+	want := []string{
+		"# URL: https://example.com/top",
+		"# セクション: Guides",
+		"# URL: https://example.com/guides/a",
+		"# URL: https://example.com/guides/b",
+		"# セクション: Guides > Deploy",
+		"# URL: https://example.com/guides/deploy/c",
+	}
+	if got := strings.Join(order, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("セクションの見出しとページの順が異なります\ngot:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestWritersOutline(t *testing.T) {
+	// h1 の直後の h3 は1段深い子として正規化済みの階層
+	page := Page{
+		URL:     "https://example.com/docs/install",
+		Title:   "Install",
+		Content: "# Install {#install}\n\n### npm {#npm}\n\n## Verify {#verify}",
+		Outline: []*HeadingNode{{
+			Heading: Heading{Level: 1, Text: "Install", Anchor: "install"},
+			Depth:   1,
+			Children: []*HeadingNode{
+				{Heading: Heading{Level: 3, Text: "npm", Anchor: "npm"}, Depth: 2},
+				{Heading: Heading{Level: 2, Text: "Verify", Anchor: "verify"}, Depth: 2},
+			},
+		}},
+	}
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name    string
+		factory WriterFactory
+		output  string
+		want    string
+	}{
+		{"pdf", newPDFWriter, "docs.pdf", "目次:\n- Install (#install)\n  - npm (#npm)\n  - Verify (#verify)\n"},
+		{"txt", newTXTWriter, "docs.txt", "# 目次:\n# - Install (#install)\n#   - npm (#npm)\n#   - Verify (#verify)\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.output)
+			w, _ := tt.factory(Options{OutputPath: path})
+			if err := w.Write(&CrawlResult{Meta: CrawlMeta{PageCount: 1}, Pages: []Page{page}}); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(strings.TrimSuffix(path, filepath.Ext(path)) + ".txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// ページのヘッダーに見出しの階層とアンカーを目次として書き出す
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("目次が出力されていません\nwant:\n%s\ngot:\n%s", tt.want, data)
+			}
+		})
+	}
+}
+
+func TestProvenanceBlockUTC(t *testing.T) {
+	finished := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("JST", 9*60*60))
+	block := ProvenanceBlock(CrawlMeta{BaseURL: "https://example.com/docs", FinishedAt: finished}, true)
+	// ページごとの取得日時と同じくUTCのタイムゾーン名付きで表示する
+	if want := "# 取得日時: 2024-03-01 03:30:00 UTC\n"; !strings.Contains(block, want) {
+		t.Errorf("取得日時がUTCで表示されていません\nwant: %s\ngot:\n%s", want, block)
+	}
+}
+
+func TestPDFWriterProvenance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docs.pdf")
+	w, _ := newPDFWriter(Options{OutputPath: path, UTC: true})
+	meta := CrawlMeta{
+		BaseURL:    "https://example.com/docs",
+		FinishedAt: time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("JST", 9*60*60)),
+		PageCount:  1,
+	}
+	if err := w.Write(&CrawlResult{Meta: meta, Pages: []Page{{URL: "https://example.com/docs", Title: "Docs"}}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(strings.TrimSuffix(path, ".pdf") + ".txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// txt形式と同じく開始URLと取得日時を先頭に書き出す
+	if want := ProvenanceBlock(meta, true); !strings.HasPrefix(string(data), want) {
+		t.Errorf("出所のヘッダーが出力されていません\nwant:\n%s\ngot:\n%s", want, data)
+	}
+}
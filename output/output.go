@@ -0,0 +1,129 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/yugo-ibuki/docrawl/internal/document"
+)
+
+// Page はクロールされたページの情報
+type Page = document.Page
+
+// Heading は本文の見出し
+type Heading = document.Heading
+
+// HeadingNode は見出しの階層（目次）の1項目
+type HeadingNode = document.HeadingNode
+
+// CrawlMeta はクロール全体に関するメタ情報
+type CrawlMeta = document.CrawlMeta
+
+// CrawlResult はクロール結果全体
+type CrawlResult struct {
+	Meta  CrawlMeta
+	Pages []Page
+}
+
+// Writer はクロール結果を出力する
+type Writer interface {
+	Write(result *CrawlResult) error
+}
+
+// StreamWriter はページを1つずつ受け取って出力するWriter
+// Write で一括出力する代わりに Begin → WritePage（ページ数分）→ End の順で呼び出される
+type StreamWriter interface {
+	Writer
+	Begin(meta CrawlMeta) error
+	WritePage(page Page) error
+	End() error
+}
+
+// Options はWriterの生成時に渡される設定
+type Options struct {
+	OutputPath string // 出力ファイルパス（ファイルに出力しないWriterは無視してよい）
+	ShowStatus bool   // 各ページのヘッダーにHTTPステータスコードを表示するか
+	UTC        bool   // 日時をローカルタイムゾーンではなくUTCで表示するか
+}
+
+// WriterFactory は設定からWriterを生成する関数
+type WriterFactory func(opts Options) (Writer, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]WriterFactory)
+)
+
+// RegisterFormat は出力形式を名前で登録する
+// 組み込みの形式を含め、既に登録済みの名前を指定した場合はエラーを返す
+func RegisterFormat(name string, factory WriterFactory) error {
+	if name == "" {
+		return fmt.Errorf("出力形式の名前を指定してください")
+	}
+	if factory == nil {
+		return fmt.Errorf("出力形式 %q のファクトリがnilです", name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[name]; exists {
+		return fmt.Errorf("出力形式 %q は既に登録されています", name)
+	}
+	registry[name] = factory
+	return nil
+}
+
+// Lookup は登録済みの出力形式のファクトリを名前で取得する
+func Lookup(name string) (WriterFactory, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("未対応の出力形式です: %s (利用可能: %v)", name, formatsLocked())
+	}
+	return factory, nil
+}
+
+// Formats は登録済みの出力形式の名前をソートして返す
+func Formats() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return formatsLocked()
+}
+
+// formatsLocked はロック取得済みの状態で登録済みの名前を返す
+func formatsLocked() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Write はWriterの種類に応じてクロール結果を出力する
+// StreamWriterの場合はページを1つずつ渡す
+func Write(w Writer, result *CrawlResult) error {
+	sw, ok := w.(StreamWriter)
+	if !ok {
+		return w.Write(result)
+	}
+
+	if err := sw.Begin(result.Meta); err != nil {
+		return err
+	}
+	for _, page := range result.Pages {
+		if err := sw.WritePage(page); err != nil {
+			return err
+		}
+	}
+	return sw.End()
+}
+
+// mustRegister は組み込みの出力形式を登録する
+func mustRegister(name string, factory WriterFactory) {
+	if err := RegisterFormat(name, factory); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,45 @@
+package output
+
+import "github.com/yugo-ibuki/docrawl/internal/pdf"
+
+func init() {
+	mustRegister("pdf", newPDFWriter)
+}
+
+// pdfWriter は内部のPDFジェネレーターでクロール結果を出力する
+type pdfWriter struct {
+	opts Options
+}
+
+// newPDFWriter は新しいpdfWriterを作成する
+func newPDFWriter(opts Options) (Writer, error) {
+	return &pdfWriter{opts: opts}, nil
+}
+
+// Write はクロールしたページからPDFを生成する
+func (w *pdfWriter) Write(result *CrawlResult) error {
+	generator := pdf.NewGenerator(w.opts.OutputPath, pdf.Options{
+		ShowStatus: w.opts.ShowStatus,
+		UTC:        w.opts.UTC,
+		Meta:       result.Meta,
+
+		SectionHeadings: sectionHeadings(result.Pages),
+	})
+	return generator.GeneratePDF(result.Pages)
+}
+
+// sectionHeadings はセクションの最初のページの番号とセクションの見出しを返す（セクションごとにまとめない場合は nil）
+func sectionHeadings(pages []Page) map[int]string {
+	var headings map[int]string
+	var prev Page
+	for i, page := range pages {
+		if sectionStarts(prev, page) {
+			if headings == nil {
+				headings = make(map[int]string)
+			}
+			headings[i] = SectionTitle(page.Section)
+		}
+		prev = page
+	}
+	return headings
+}
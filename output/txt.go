@@ -0,0 +1,119 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yugo-ibuki/docrawl/internal/document"
+)
+
+func init() {
+	mustRegister("txt", newTXTWriter)
+}
+
+// txtWriter はクロール結果をテキストファイルとして出力する
+type txtWriter struct {
+	outputPath string
+	showStatus bool
+	utc        bool
+}
+
+// newTXTWriter は新しいtxtWriterを作成する
+func newTXTWriter(opts Options) (Writer, error) {
+	return &txtWriter{outputPath: opts.OutputPath, showStatus: opts.ShowStatus, utc: opts.UTC}, nil
+}
+
+// Write はクロールしたページからTXTファイルを生成する
+func (w *txtWriter) Write(result *CrawlResult) error {
+	pages := result.Pages
+	if len(pages) == 0 {
+		return fmt.Errorf("生成するページがありません")
+	}
+
+	// 出力ディレクトリを作成
+	outputPath := w.outputPath
+	outputDir := filepath.Dir(outputPath)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+	}
+
+	// 拡張子が.txtでない場合は変更
+	if !strings.HasSuffix(strings.ToLower(outputPath), ".txt") {
+		outputPath = outputPath + ".txt"
+	}
+
+	// テキストファイルを作成
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("テキストファイルの作成に失敗しました: %w", err)
+	}
+	defer file.Close()
+
+	// ヘッダー情報を書き込み
+	fmt.Fprintln(file, ProvenanceBlock(result.Meta, w.utc))
+
+	// 各ページの内容を書き込み（セクションごとにまとめた場合は、セクションの最初のページの前に見出しを書き込む）
+	var prev Page
+	for i, page := range pages {
+		if sectionStarts(prev, page) {
+			fmt.Fprintf(file, "\n%s\n", strings.Repeat("#", 80))
+			fmt.Fprintf(file, "# セクション: %s\n", SectionTitle(page.Section))
+			fmt.Fprintf(file, "%s\n", strings.Repeat("#", 80))
+		}
+		prev = page
+
+		fmt.Fprintf(file, "\n%s\n", strings.Repeat("=", 80))
+		fmt.Fprintf(file, "# ページ %d/%d\n", i+1, len(pages))
+		fmt.Fprintf(file, "# URL: %s\n", page.URL)
+		fmt.Fprintf(file, "# 深度: %d\n", page.Depth)
+		if len(page.Breadcrumbs) > 0 {
+			fmt.Fprintf(file, "# パンくずリスト: %s\n", strings.Join(page.Breadcrumbs, " > "))
+		}
+		if page.Description != "" {
+			fmt.Fprintf(file, "# 説明: %s\n", page.Description)
+		}
+		if page.CanonicalURL != "" {
+			fmt.Fprintf(file, "# 正規URL: %s\n", page.CanonicalURL)
+		}
+		for _, alias := range page.AliasURLs {
+			fmt.Fprintf(file, "# 別URL: %s\n", alias)
+		}
+		if page.FromPDF {
+			fmt.Fprintf(file, "# 元の形式: PDF（%s）\n", document.PDFSource(page))
+		}
+		if w.showStatus {
+			fmt.Fprintf(file, "# ステータス: %d\n", page.StatusCode)
+		}
+		if page.ChangeStatus != "" {
+			fmt.Fprintf(file, "# 変更: %s\n", page.ChangeStatus)
+		}
+		if !page.FetchedAt.IsZero() {
+			fmt.Fprintf(file, "# 取得日時: %s\n", FormatTime(page.FetchedAt, w.utc))
+		}
+		if !page.LastModified.IsZero() {
+			fmt.Fprintf(file, "# 最終更新日時: %s\n", FormatTime(page.LastModified, w.utc))
+		}
+		if !page.ModifiedAt.IsZero() {
+			fmt.Fprintf(file, "# 記事の更新日時: %s\n", FormatTime(page.ModifiedAt, w.utc))
+		}
+		if len(page.Outline) > 0 {
+			fmt.Fprintln(file, "# 目次:")
+			for _, line := range OutlineLines(page.Outline) {
+				fmt.Fprintf(file, "# %s\n", line)
+			}
+		}
+		fmt.Fprintf(file, "%s\n", strings.Repeat("=", 80))
+		fmt.Fprintln(file)
+
+		// コンテンツをクリーンアップして書き込み
+		cleanedContent := CleanupText(page.Content)
+		fmt.Fprintln(file, cleanedContent)
+		fmt.Fprintln(file)
+	}
+
+	// 成功メッセージを表示
+	fmt.Printf("テキストファイルが生成されました: %s\n", outputPath)
+	return nil
+}